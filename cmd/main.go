@@ -13,9 +13,15 @@ import (
 	"github.com/pkg/errors"
 
 	"streaming-service/internal/api"
+	"streaming-service/internal/auth"
+	"streaming-service/internal/cache"
 	"streaming-service/internal/config"
+	"streaming-service/internal/hls"
 	customLogger "streaming-service/internal/logger"
 	"streaming-service/internal/service"
+	"streaming-service/internal/storage"
+	"streaming-service/internal/upload"
+	"streaming-service/internal/worker"
 )
 
 func main() {
@@ -27,7 +33,10 @@ func main() {
 	if err := envconfig.Process("", &cfg); err != nil {
 		log.Fatal(errors.Wrap(err, "failed to process configuration"))
 	}
-	
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(errors.Wrap(err, "invalid configuration"))
+	}
+
 	logger, err := customLogger.NewLogger(cfg.LogLevel)
 	if err != nil {
 		log.Fatal(errors.Wrap(err, "failed to initialize logger"))
@@ -38,12 +47,80 @@ func main() {
 		log.Fatal(errors.Wrap(err, "failed to initialize repository"))
 	}
 
-	serviceInstance := service.NewService(repository.MovieRepo, repository.OwnerRepo, logger)
+	tokens := auth.NewTokenManager(cfg.Auth.JWTSecret, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	playback, err := auth.NewPlaybackSigner(cfg.Playback.Keys, cfg.Playback.TokenTTL)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to initialize playback signer"))
+	}
+
+	suggestions := cache.NewLRU[string, []*repo.Suggestion](cfg.Suggest.CacheSize)
+
+	uploads, err := upload.NewDisk(cfg.Upload.Dir, cfg.Upload.MaxSize)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to initialize upload storage"))
+	}
+
+	blobs, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal(errors.Wrap(err, "failed to initialize media storage"))
+	}
+
+	var packager *hls.Packager
+	if cfg.HLS.Enabled {
+		ladder, err := hls.ParseLadder(cfg.HLS.Ladder)
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "failed to parse HLS rendition ladder"))
+		}
+
+		packager, err = hls.NewPackager(hls.NewFFmpeg(cfg.HLS.FFmpegPath, cfg.HLS.FFprobePath), blobs, hls.Options{
+			Ladder:  ladder,
+			Segment: hls.SegmentOptions{Format: cfg.HLS.SegmentFormat, Duration: cfg.HLS.SegmentDuration},
+			WorkDir: cfg.HLS.WorkDir,
+		})
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "failed to initialize HLS packager"))
+		}
+	}
+
+	serviceInstance := service.NewService(
+		repository, repository, repository, repository, repository, repository, repository, repository, repository, repository,
+		repository, repository, repository, repository, repository, repository,
+		suggestions, uploads, blobs, packager, cfg.Jobs.MaxAttempts, tokens, playback, logger,
+	)
 
 	app := api.NewRouters(&api.Routers{
-		MovieService: serviceInstance,
-		OwnerService: serviceInstance,
-	}, cfg.Rest.Token)
+		MovieService:    serviceInstance,
+		OwnerService:    serviceInstance,
+		AuthService:     serviceInstance,
+		MemberService:   serviceInstance,
+		APIKeyService:   serviceInstance,
+		SearchService:   serviceInstance,
+		TrashService:    serviceInstance,
+		AuditService:    serviceInstance,
+		TaxonomyService: serviceInstance,
+		PeopleService:   serviceInstance,
+		SeriesService:   serviceInstance,
+		TitleService:    serviceInstance,
+		AssetService:    serviceInstance,
+		StreamService:   serviceInstance,
+		HLSService:      serviceInstance,
+		JobService:      serviceInstance,
+		PlaybackService: serviceInstance,
+	}, cfg.Rest)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go serviceInstance.RunTrashPurge(ctx, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+
+	pool := worker.NewPool(repository, cfg.Jobs, logger)
+	pool.Handle(repo.JobCompleteAsset, serviceInstance.RunCompleteJob)
+	pool.Handle(repo.JobPackageAsset, serviceInstance.RunPackageJob)
+	poolDone := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(poolDone)
+	}()
 
 	go func() {
 		logger.Infof("Starting server on %s", cfg.Rest.ListenAddress)
@@ -57,4 +134,8 @@ func main() {
 	<-sigChan
 
 	logger.Infof("Shutting down server...")
+
+	// Выполняемые задачи прерываются и возвращаются в очередь
+	cancel()
+	<-poolDone
 }
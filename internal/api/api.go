@@ -3,30 +3,71 @@ package api
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"streaming-service/internal/config"
+	"streaming-service/internal/repo"
 	"streaming-service/internal/service"
 )
 
 type Routers struct {
-	MovieService service.MovieService
-	OwnerService service.OwnerService
+	MovieService    service.MovieService
+	OwnerService    service.OwnerService
+	AuthService     service.AuthService
+	MemberService   service.MemberService
+	APIKeyService   service.APIKeyService
+	SearchService   service.SearchService
+	TrashService    service.TrashService
+	AuditService    service.AuditService
+	TaxonomyService service.TaxonomyService
+	PeopleService   service.PeopleService
+	SeriesService   service.SeriesService
+	TitleService    service.TitleService
+	AssetService    service.AssetService
+	StreamService   service.StreamService
+	HLSService      service.HLSService
+	JobService      service.JobService
+	PlaybackService service.PlaybackService
 }
 
-func NewRouters(r *Routers, token string) *fiber.App {
-	app := fiber.New()
+func NewRouters(r *Routers, cfg config.Rest) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: errorHandler,
+		BodyLimit:    cfg.BodyLimit,
+	})
 
 	app.Use(cors.New(cors.Config{
-		AllowMethods:  "GET,POST,PUT,DELETE",
-		AllowHeaders:  "Accept, Authorization, Content-Type, X-CSRF-Token, X-REQUEST-ID",
-		ExposeHeaders: "Link",
-		MaxAge:        300,
+		AllowMethods: "GET,HEAD,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders: "Accept, Authorization, Content-Type, X-CSRF-Token, X-REQUEST-ID, X-API-Key, If-Match, If-None-Match, Range, If-Range, " +
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Checksum",
+		ExposeHeaders: "Link, ETag, X-Request-ID, Location, Accept-Ranges, Content-Range, Content-Length, " +
+			"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, Upload-Offset, Upload-Length",
+		MaxAge: 300,
 	}))
 
-	apiGroup := app.Group("/v1")
+	// Принимает X-Request-ID клиента или генерирует новый и возвращает его в ответе
+	app.Use(requestid.New())
+
+	// Маршруты auth регистрируются до middleware и остаются публичными
+	authGroup := app.Group("/v1/auth")
+	authGroup.Post("/register", r.AuthService.Register)
+	authGroup.Post("/login", r.AuthService.Login)
+	authGroup.Post("/refresh", r.AuthService.Refresh)
+	authGroup.Post("/logout", r.AuthService.Logout)
+
+	// OPTIONS в tus сообщает возможности сервера и по протоколу не требует авторизации
+	app.Options("/v1/movies/:id/assets", r.AssetService.TusOptions)
+	app.Options("/v1/episodes/:id/assets", r.AssetService.TusOptions)
+
+	// Подписанные ссылки воспроизведения сами служат авторизацией, поэтому тоже идут до middleware
+	playbackRoutes(app.Group("/v1/play/:token"), r)
+
+	apiGroup := app.Group("/v1", authMiddleware(cfg.Token, cfg.PublicRead, r.AuthService, r.APIKeyService), auditMiddleware())
 
 	apiGroup.Post("/movies", r.MovieService.CreateMovie)
 	apiGroup.Get("/movies/:id", r.MovieService.GetMovie)
 	apiGroup.Get("/movies", r.MovieService.GetAllMovies)
 	apiGroup.Put("/movies/", r.MovieService.UpdateMovie)
+	apiGroup.Patch("/movies/:id", r.MovieService.PatchMovie)
 	apiGroup.Delete("/movies/:id", r.MovieService.DeleteMovie)
 
 	apiGroup.Post("/owners", r.OwnerService.CreateOwner)
@@ -34,7 +75,103 @@ func NewRouters(r *Routers, token string) *fiber.App {
 	apiGroup.Get("/owners/name/:name", r.OwnerService.GetOwnerByName)
 	apiGroup.Get("/owners", r.OwnerService.GetAllOwners)
 	apiGroup.Put("/owners/", r.OwnerService.UpdateOwner)
+	apiGroup.Patch("/owners/:id", r.OwnerService.PatchOwner)
 	apiGroup.Delete("/owners/:id", r.OwnerService.DeleteOwner)
 
+	apiGroup.Post("/owners/:id/members", r.MemberService.AddOwnerMember)
+	apiGroup.Get("/owners/:id/members", r.MemberService.GetOwnerMembers)
+	apiGroup.Delete("/owners/:id/members/:userId", r.MemberService.RemoveOwnerMember)
+	apiGroup.Put("/users/:id/role", r.MemberService.SetUserRole)
+
+	apiGroup.Post("/owners/:id/api-keys", r.APIKeyService.CreateAPIKey)
+	apiGroup.Get("/owners/:id/api-keys", r.APIKeyService.GetAPIKeys)
+	apiGroup.Post("/owners/:id/api-keys/:keyId/rotate", r.APIKeyService.RotateAPIKey)
+	apiGroup.Delete("/owners/:id/api-keys/:keyId", r.APIKeyService.RevokeAPIKey)
+
+	apiGroup.Post("/genres", r.TaxonomyService.CreateGenre)
+	apiGroup.Get("/genres", r.TaxonomyService.GetGenres)
+	apiGroup.Get("/genres/:slug", r.TaxonomyService.GetGenre)
+	apiGroup.Put("/genres/:slug", r.TaxonomyService.UpdateGenre)
+	apiGroup.Delete("/genres/:slug", r.TaxonomyService.DeleteGenre)
+
+	apiGroup.Post("/tags", r.TaxonomyService.CreateTag)
+	apiGroup.Get("/tags", r.TaxonomyService.GetTags)
+	apiGroup.Get("/tags/:slug", r.TaxonomyService.GetTag)
+	apiGroup.Put("/tags/:slug", r.TaxonomyService.UpdateTag)
+	apiGroup.Delete("/tags/:slug", r.TaxonomyService.DeleteTag)
+
+	apiGroup.Post("/people", r.PeopleService.CreatePerson)
+	apiGroup.Get("/people", r.PeopleService.GetPeople)
+	apiGroup.Get("/people/:id", r.PeopleService.GetPerson)
+	apiGroup.Put("/people/:id", r.PeopleService.UpdatePerson)
+	apiGroup.Delete("/people/:id", r.PeopleService.DeletePerson)
+	apiGroup.Get("/people/:id/filmography", r.PeopleService.GetFilmography)
+
+	apiGroup.Post("/series", r.SeriesService.CreateSeries)
+	apiGroup.Get("/series", r.SeriesService.GetAllSeries)
+	apiGroup.Get("/series/:id", r.SeriesService.GetSeries)
+	apiGroup.Put("/series/:id", r.SeriesService.UpdateSeries)
+	apiGroup.Delete("/series/:id", r.SeriesService.DeleteSeries)
+	apiGroup.Post("/series/:id/seasons", r.SeriesService.CreateSeason)
+	apiGroup.Get("/series/:id/seasons", r.SeriesService.GetSeasons)
+	apiGroup.Get("/series/:id/seasons/:n", r.SeriesService.GetSeason)
+	apiGroup.Put("/series/:id/seasons/:n", r.SeriesService.UpdateSeason)
+	apiGroup.Delete("/series/:id/seasons/:n", r.SeriesService.DeleteSeason)
+	apiGroup.Post("/series/:id/seasons/:n/episodes", r.SeriesService.CreateEpisode)
+	apiGroup.Get("/series/:id/seasons/:n/episodes", r.SeriesService.GetEpisodes)
+	apiGroup.Get("/series/:id/seasons/:n/episodes/:e", r.SeriesService.GetEpisode)
+	apiGroup.Put("/series/:id/seasons/:n/episodes/:e", r.SeriesService.UpdateEpisode)
+	apiGroup.Delete("/series/:id/seasons/:n/episodes/:e", r.SeriesService.DeleteEpisode)
+	apiGroup.Get("/episodes/:id", r.SeriesService.GetEpisodeByID)
+
+	// HEAD регистрируется раньше GET того же пути, иначе fiber ответит на него обработчиком GET
+	apiGroup.Post("/movies/:id/assets", r.AssetService.CreateMovieAsset)
+	apiGroup.Get("/movies/:id/assets", r.AssetService.GetMovieAssets)
+	apiGroup.Head("/movies/:id/assets/:assetId", r.AssetService.HeadMovieAsset)
+	apiGroup.Get("/movies/:id/assets/:assetId", r.AssetService.GetMovieAsset)
+	apiGroup.Patch("/movies/:id/assets/:assetId", r.AssetService.PatchMovieAsset)
+	apiGroup.Delete("/movies/:id/assets/:assetId", r.AssetService.DeleteMovieAsset)
+
+	apiGroup.Post("/episodes/:id/assets", r.AssetService.CreateEpisodeAsset)
+	apiGroup.Get("/episodes/:id/assets", r.AssetService.GetEpisodeAssets)
+	apiGroup.Head("/episodes/:id/assets/:assetId", r.AssetService.HeadEpisodeAsset)
+	apiGroup.Get("/episodes/:id/assets/:assetId", r.AssetService.GetEpisodeAsset)
+	apiGroup.Patch("/episodes/:id/assets/:assetId", r.AssetService.PatchEpisodeAsset)
+	apiGroup.Delete("/episodes/:id/assets/:assetId", r.AssetService.DeleteEpisodeAsset)
+
+	apiGroup.Post("/movies/:id/playback", r.PlaybackService.CreateMoviePlayback)
+	apiGroup.Post("/episodes/:id/playback", r.PlaybackService.CreateEpisodePlayback)
+
+	playbackRoutes(apiGroup, r)
+
+	apiGroup.Get("/jobs/:id", r.JobService.GetJob)
+	apiGroup.Get("/movies/:id/jobs", r.JobService.GetMovieJobs)
+	apiGroup.Get("/episodes/:id/jobs", r.JobService.GetEpisodeJobs)
+
+	apiGroup.Get("/titles/:id", r.TitleService.GetTitle)
+
+	apiGroup.Get("/trash", r.TrashService.GetTrash)
+	apiGroup.Post("/movies/:id/restore", r.TrashService.RestoreMovie)
+	apiGroup.Post("/owners/:id/restore", r.TrashService.RestoreOwner)
+
+	apiGroup.Get("/audit", r.AuditService.GetAuditEvents)
+
+	apiGroup.Get("/search", r.SearchService.Search)
+	apiGroup.Get("/suggest", r.SearchService.Suggest)
+
 	return app
 }
+
+// playbackRoutes регистрирует маршруты воспроизведения; GET в fiber отвечает и на HEAD,
+// и плееры так узнают размер файла перед перемоткой.
+func playbackRoutes(group fiber.Router, r *Routers) {
+	movie := playbackMiddleware(repo.TitleMovie, r.PlaybackService)
+	episode := playbackMiddleware(repo.TitleEpisode, r.PlaybackService)
+
+	group.Get("/movies/:id/stream", movie, r.StreamService.StreamMovie)
+	group.Get("/episodes/:id/stream", episode, r.StreamService.StreamEpisode)
+	group.Get("/movies/:id/hls/*", movie, r.HLSService.GetMovieHLS)
+	group.Get("/episodes/:id/hls/*", episode, r.HLSService.GetEpisodeHLS)
+	group.Get("/movies/:id/dash/*", movie, r.HLSService.GetMovieDASH)
+	group.Get("/episodes/:id/dash/*", episode, r.HLSService.GetEpisodeDASH)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/repo"
+)
+
+// auditMiddleware передает в репозиторий автора изменений и X-Request-ID для журнала изменений.
+// Должен стоять после authMiddleware.
+func auditMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		audit := repo.AuditContext{}
+		if requestID, ok := ctx.Locals(requestid.ConfigDefault.ContextKey).(string); ok {
+			audit.RequestID = requestID
+		}
+		if identity, ok := auth.FromContext(ctx); ok {
+			audit.Actor = identity.Actor()
+		}
+
+		ctx.SetUserContext(repo.WithAuditContext(ctx.UserContext(), audit))
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+	"streaming-service/internal/service"
+)
+
+// errorHandler - единая точка преобразования ошибок обработчиков в HTTP-ответы.
+func errorHandler(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repo.ErrInvalidUUID):
+		return dto.BadRequestError(ctx, dto.InvalidUUID, "Invalid UUID format")
+	case errors.Is(err, repo.ErrInvalidInput):
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid value format")
+	case errors.Is(err, repo.ErrInvalidCursor):
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid page cursor")
+	case errors.Is(err, repo.ErrNotFound):
+		return dto.NotFoundError(ctx, dto.NotFound, "Requested resource not found")
+	case errors.Is(err, repo.ErrConflict):
+		return dto.ConflictError(ctx, dto.Conflict, "Resource conflicts with an existing one")
+	case errors.Is(err, repo.ErrForeignKeyViolation):
+		return dto.UnprocessableEntityError(ctx, dto.ReferenceNotFound, "Referenced resource does not exist")
+	case errors.Is(err, repo.ErrCheckViolation):
+		return dto.UnprocessableEntityError(ctx, dto.ConstraintFailed, "Resource violates a data constraint")
+	case errors.Is(err, repo.ErrPreconditionFailed):
+		return dto.PreconditionFailedError(ctx, "Resource has been modified, refetch it and retry")
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return ctx.Status(fiberErr.Code).JSON(&dto.Response{
+			Status: "error",
+			Error: &dto.Error{
+				Code: statusCode(fiberErr.Code),
+				Desc: fiberErr.Message,
+			},
+		})
+	}
+
+	return dto.InternalServerError(ctx)
+}
+
+func statusCode(status int) string {
+	switch status {
+	case fiber.StatusNotFound:
+		return dto.NotFound
+	case fiber.StatusBadRequest:
+		return dto.FieldBadFormat
+	case fiber.StatusUnauthorized:
+		return dto.Unauthorized
+	case fiber.StatusForbidden:
+		return dto.Forbidden
+	case fiber.StatusUnsupportedMediaType:
+		return dto.UnsupportedMediaType
+	case fiber.StatusConflict:
+		return dto.Conflict
+	case fiber.StatusPreconditionFailed:
+		return dto.PreconditionFailed
+	case fiber.StatusRequestEntityTooLarge:
+		return dto.PayloadTooLarge
+	case fiber.StatusRequestedRangeNotSatisfiable:
+		return dto.RangeNotSatisfiable
+	case service.StatusChecksumMismatch:
+		return dto.ChecksumMismatch
+	}
+	if status >= fiber.StatusInternalServerError {
+		return dto.ServiceUnavailable
+	}
+	return "HTTP_ERROR"
+}
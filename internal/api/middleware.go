@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/service"
+)
+
+const (
+	bearerPrefix = "Bearer "
+	apiKeyHeader = "X-API-Key"
+)
+
+// authMiddleware принимает статический Rest.Token, access-токен пользователя
+// или API-ключ владельца и кладет вызывающую сторону в контекст.
+// При publicRead запросы только на чтение пропускаются без токена.
+func authMiddleware(token string, publicRead bool, users service.AuthService, keys service.APIKeyService) fiber.Handler {
+	expected := []byte(token)
+
+	return func(ctx *fiber.Ctx) error {
+		provided, ok := bearerToken(ctx.Get(fiber.HeaderAuthorization))
+		if !ok {
+			provided = strings.TrimSpace(ctx.Get(apiKeyHeader))
+			ok = provided != ""
+		}
+		if !ok {
+			if publicRead && isReadOnly(ctx.Method()) {
+				return ctx.Next()
+			}
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="streaming-service"`)
+			return dto.UnauthorizedError(ctx, "Missing or malformed bearer token")
+		}
+
+		// Пустой токен в конфиге не должен открывать доступ
+		if len(expected) > 0 && subtle.ConstantTimeCompare([]byte(provided), expected) == 1 {
+			auth.SetIdentity(ctx, &auth.Identity{Static: true})
+			return ctx.Next()
+		}
+
+		verify := users.VerifyAccessToken
+		if auth.IsAPIKey(provided) {
+			verify = keys.VerifyAPIKey
+		}
+
+		identity, err := verify(ctx.Context(), provided)
+		if err != nil {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="streaming-service", error="invalid_token"`)
+			return dto.UnauthorizedError(ctx, "Invalid bearer token")
+		}
+
+		auth.SetIdentity(ctx, identity)
+		return ctx.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	return token, token != ""
+}
+
+func isReadOnly(method string) bool {
+	return method == fiber.MethodGet || method == fiber.MethodHead
+}
+
+// playbackMiddleware защищает маршруты воспроизведения тайтла kind. На /v1/play/:token/... токен
+// должен быть выдан на этот тайтл и, если привязан к адресу, на адрес клиента; он заменяет авторизацию.
+// Без токена нужна обычная авторизация: анонимное чтение при PUBLIC_READ воспроизведение не открывает.
+func playbackMiddleware(kind string, playback service.PlaybackService) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token := ctx.Params("token")
+		if token == "" {
+			if _, ok := auth.FromContext(ctx); !ok {
+				ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="streaming-service"`)
+				return dto.UnauthorizedError(ctx, "Playback requires a bearer token or a signed playback URL")
+			}
+			return ctx.Next()
+		}
+
+		claims, err := playback.VerifyPlaybackToken(token)
+		if err != nil {
+			return dto.UnauthorizedError(ctx, "Invalid or expired playback token")
+		}
+		if claims.Kind != kind || claims.TitleID != ctx.Params("id") {
+			return dto.ForbiddenError(ctx, "Playback token is issued for another title")
+		}
+		if claims.IP != "" && claims.IP != ctx.IP() {
+			return dto.ForbiddenError(ctx, "Playback token is bound to another client address")
+		}
+
+		auth.SetIdentity(ctx, &auth.Identity{Playback: claims.Subject})
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/repo"
+	"streaming-service/internal/service"
+)
+
+const (
+	movieID   = "0b7e4c1a-2f3d-4e5a-8b9c-0d1e2f3a4b5c"
+	episodeID = "6f1c2b8e-3d4a-4f5b-9c6d-7e8f9a0b1c2d"
+)
+
+// fakePlayback проверяет токены настоящим PlaybackSigner, остальное сервису воспроизведения здесь не нужно.
+type fakePlayback struct {
+	service.PlaybackService
+	signer *auth.PlaybackSigner
+}
+
+func (f fakePlayback) VerifyPlaybackToken(token string) (*auth.PlaybackClaims, error) {
+	return f.signer.Verify(token)
+}
+
+func newPlaybackApp(t *testing.T, signer *auth.PlaybackSigner) *fiber.App {
+	t.Helper()
+
+	playback := fakePlayback{signer: signer}
+	movie := playbackMiddleware(repo.TitleMovie, playback)
+	episode := playbackMiddleware(repo.TitleEpisode, playback)
+	// Отвечает тем, от чьего имени пропущен запрос
+	subject := func(ctx *fiber.Ctx) error {
+		identity, _ := auth.FromContext(ctx)
+		return ctx.SendString(identity.Playback)
+	}
+
+	app := fiber.New()
+	app.Get("/v1/play/:token/movies/:id/stream", movie, subject)
+	app.Get("/v1/play/:token/episodes/:id/stream", episode, subject)
+	app.Get("/v1/movies/:id/stream", movie, subject)
+	return app
+}
+
+func TestPlaybackMiddleware(t *testing.T) {
+	signer, err := auth.NewPlaybackSigner("k1:0123456789abcdef0123456789abcdef", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newPlaybackApp(t, signer)
+
+	// Адрес клиента, который видит fiber в app.Test
+	probe := httptest.NewRequest(http.MethodGet, "/", nil)
+	probeApp := fiber.New()
+	probeApp.Get("/", func(ctx *fiber.Ctx) error { return ctx.SendString(ctx.IP()) })
+	resp, err := probeApp.Test(probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	clientIP := string(raw)
+	if clientIP == "" {
+		t.Fatal("client address is empty, the bound token would not be bound")
+	}
+
+	sign := func(kind, titleID, ip string) string {
+		token, err := signer.Sign(&auth.PlaybackClaims{Kind: kind, TitleID: titleID, Subject: "user:1", IP: ip})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	rotated, err := auth.NewPlaybackSigner("k2:fedcba9876543210fedcba9876543210", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := rotated.Sign(&auth.PlaybackClaims{Kind: repo.TitleMovie, TitleID: movieID, Subject: "user:1"})
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		status int
+	}{
+		{name: "valid", path: "/v1/play/" + sign(repo.TitleMovie, movieID, "") + "/movies/" + movieID + "/stream",
+			status: http.StatusOK},
+		{name: "bound to the client address", path: "/v1/play/" + sign(repo.TitleMovie, movieID, clientIP) + "/movies/" + movieID + "/stream",
+			status: http.StatusOK},
+		{name: "bound to another address", path: "/v1/play/" + sign(repo.TitleMovie, movieID, "203.0.113.7") + "/movies/" + movieID + "/stream",
+			status: http.StatusForbidden},
+		{name: "forwarded address is not trusted", path: "/v1/play/" + sign(repo.TitleMovie, movieID, "203.0.113.7") + "/movies/" + movieID + "/stream",
+			header: map[string]string{fiber.HeaderXForwardedFor: "203.0.113.7"}, status: http.StatusForbidden},
+		{name: "another title", path: "/v1/play/" + sign(repo.TitleMovie, episodeID, "") + "/movies/" + movieID + "/stream",
+			status: http.StatusForbidden},
+		{name: "another kind", path: "/v1/play/" + sign(repo.TitleMovie, episodeID, "") + "/episodes/" + episodeID + "/stream",
+			status: http.StatusForbidden},
+		{name: "unknown key", path: "/v1/play/" + foreign + "/movies/" + movieID + "/stream", status: http.StatusUnauthorized},
+		{name: "garbage token", path: "/v1/play/garbage/movies/" + movieID + "/stream", status: http.StatusUnauthorized},
+		{name: "no token and no identity", path: "/v1/movies/" + movieID + "/stream", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if body, _ := io.ReadAll(resp.Body); tt.status == http.StatusOK && string(body) != "user:1" {
+				t.Errorf("identity = %q, want the token subject", body)
+			}
+		})
+	}
+}
+
+func TestPlaybackMiddlewareExpired(t *testing.T) {
+	signer, err := auth.NewPlaybackSigner("k1:0123456789abcdef0123456789abcdef", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newPlaybackApp(t, signer)
+
+	// ExpiresAt округляется до секунды вниз, так что через 1 с токен гарантированно истек
+	token, err := signer.Sign(&auth.PlaybackClaims{Kind: repo.TitleMovie, TitleID: movieID, Subject: "user:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/v1/play/"+token+"/movies/"+movieID+"/stream", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", resp.StatusCode)
+	}
+}
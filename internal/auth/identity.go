@@ -0,0 +1,56 @@
+package auth
+
+import "github.com/gofiber/fiber/v2"
+
+const identityKey = "identity"
+
+// Identity описывает вызывающую сторону текущего запроса.
+type Identity struct {
+	UserID    string
+	SessionID string
+	Role      Role
+	// Для запросов по API-ключу владельца
+	APIKeyID string
+	OwnerID  string
+	Scopes   []string
+	// Static выставляется для запросов по статическому Rest.Token
+	Static bool
+	// Playback - кому выдана подписанная ссылка воспроизведения, по которой пришел запрос
+	Playback string
+}
+
+func (i *Identity) IsAdmin() bool {
+	return i.Static || i.Role == RoleAdmin
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func SetIdentity(ctx *fiber.Ctx, identity *Identity) {
+	ctx.Locals(identityKey, identity)
+}
+
+func FromContext(ctx *fiber.Ctx) (*Identity, bool) {
+	identity, ok := ctx.Locals(identityKey).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// Actor - идентификатор вызывающей стороны для журнала изменений.
+func (i *Identity) Actor() string {
+	switch {
+	case i.Static:
+		return "static-token"
+	case i.APIKeyID != "":
+		return "api-key:" + i.APIKeyID
+	case i.Playback != "":
+		return i.Playback
+	default:
+		return "user:" + i.UserID
+	}
+}
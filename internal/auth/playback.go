@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PlaybackClaims - на что выдана ссылка воспроизведения. Пустой IP - ссылка не привязана к адресу клиента.
+// Subject - кому выдана ссылка, в формате Identity.Actor.
+type PlaybackClaims struct {
+	Kind      string
+	TitleID   string
+	Subject   string
+	IP        string
+	ExpiresAt time.Time
+}
+
+// playbackPayload - claims в теле токена; короткие имена полей укорачивают ссылку.
+type playbackPayload struct {
+	Kind    string `json:"k"`
+	TitleID string `json:"t"`
+	Subject string `json:"s"`
+	IP      string `json:"ip,omitempty"`
+	Expiry  int64  `json:"e"`
+}
+
+// PlaybackSigner подписывает и проверяет токены воспроизведения: kid.payload.signature в base64url,
+// подпись - HMAC-SHA256 от kid.payload. Новые токены подписываются активным ключом, а проверяются
+// всеми ключами из списка: при ротации старый ключ остается в конфиге, пока не истекут его токены.
+type PlaybackSigner struct {
+	active string
+	keys   map[string][]byte
+	ttl    time.Duration
+}
+
+// NewPlaybackSigner разбирает ключи в формате kid:secret через запятую; первый ключ - активный.
+func NewPlaybackSigner(spec string, ttl time.Duration) (*PlaybackSigner, error) {
+	if ttl <= 0 {
+		return nil, errors.New("playback token TTL must be positive")
+	}
+
+	signer := &PlaybackSigner{keys: make(map[string][]byte), ttl: ttl}
+	for _, entry := range strings.Split(spec, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || kid == "" || strings.Contains(kid, ".") {
+			return nil, errors.Errorf("invalid playback key %q: expected kid:secret", kid)
+		}
+		if len(secret) < 32 {
+			return nil, errors.Errorf("playback key %q must be at least 32 characters", kid)
+		}
+		if _, ok := signer.keys[kid]; ok {
+			return nil, errors.Errorf("duplicate playback key %q", kid)
+		}
+
+		signer.keys[kid] = []byte(secret)
+		if signer.active == "" {
+			signer.active = kid
+		}
+	}
+
+	return signer, nil
+}
+
+// Sign выдает токен на claims; ExpiresAt выставляется через TTL от текущего момента.
+func (s *PlaybackSigner) Sign(claims *PlaybackClaims) (string, error) {
+	claims.ExpiresAt = time.Now().Add(s.ttl).Truncate(time.Second)
+
+	payload, err := json.Marshal(playbackPayload{
+		Kind:    claims.Kind,
+		TitleID: claims.TitleID,
+		Subject: claims.Subject,
+		IP:      claims.IP,
+		Expiry:  claims.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode playback claims")
+	}
+
+	unsigned := s.active + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.signature(s.keys[s.active], unsigned), nil
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает его claims.
+func (s *PlaybackSigner) Verify(token string) (*PlaybackClaims, error) {
+	unsigned, signature, ok := cutLast(token, ".")
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed playback token")
+	}
+
+	kid, payload, ok := strings.Cut(unsigned, ".")
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed playback token")
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidToken, "unknown playback key")
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(key, unsigned))) {
+		return nil, errors.Wrap(ErrInvalidToken, "invalid playback token signature")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed playback token payload")
+	}
+
+	var claims playbackPayload
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed playback token payload")
+	}
+
+	expiresAt := time.Unix(claims.Expiry, 0)
+	if !time.Now().Before(expiresAt) {
+		return nil, errors.Wrap(ErrInvalidToken, "playback token is expired")
+	}
+
+	return &PlaybackClaims{
+		Kind:      claims.Kind,
+		TitleID:   claims.TitleID,
+		Subject:   claims.Subject,
+		IP:        claims.IP,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *PlaybackSigner) signature(key []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
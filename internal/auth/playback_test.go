@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	oldKey = "old:0123456789abcdef0123456789abcdef"
+	newKey = "new:fedcba9876543210fedcba9876543210"
+)
+
+func newTestSigner(t *testing.T, spec string) *PlaybackSigner {
+	t.Helper()
+
+	signer, err := NewPlaybackSigner(spec, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// signPayload подписывает произвольное тело ключом kid, минуя Sign: так собираются истекшие и битые токены.
+func signPayload(t *testing.T, signer *PlaybackSigner, kid string, payload []byte) string {
+	t.Helper()
+
+	unsigned := kid + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signer.signature(signer.keys[kid], unsigned)
+}
+
+func encodeClaims(t *testing.T, payload playbackPayload) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func flipFirst(s string) string {
+	if s[0] == 'A' {
+		return "B" + s[1:]
+	}
+	return "A" + s[1:]
+}
+
+func TestPlaybackSignVerify(t *testing.T) {
+	signer := newTestSigner(t, newKey+","+oldKey)
+
+	claims := &PlaybackClaims{Kind: "movie", TitleID: "title", Subject: "user:1", IP: "203.0.113.7"}
+	token, err := signer.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "new.") {
+		t.Errorf("token %q is not signed with the active key", token)
+	}
+	if until := time.Until(claims.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("ExpiresAt is %v from now, want about an hour", until)
+	}
+
+	verified, err := signer.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *verified != *claims {
+		t.Errorf("Verify() = %+v, want %+v", verified, claims)
+	}
+}
+
+func TestPlaybackVerify(t *testing.T) {
+	signer := newTestSigner(t, newKey+","+oldKey)
+	valid := playbackPayload{Kind: "movie", TitleID: "title", Subject: "user:1", Expiry: time.Now().Add(time.Minute).Unix()}
+	token := signPayload(t, signer, "new", encodeClaims(t, valid))
+	kid, rest, _ := strings.Cut(token, ".")
+	payload, signature, _ := strings.Cut(rest, ".")
+
+	expired := valid
+	expired.Expiry = time.Now().Unix()
+	otherTitle := valid
+	otherTitle.TitleID = "other"
+
+	// Ключ, удаленный из конфига после ротации
+	retired := newTestSigner(t, "retired:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	retiredToken := signPayload(t, retired, "retired", encodeClaims(t, valid))
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "valid", token: token, valid: true},
+		{name: "signed with the previous key", token: signPayload(t, signer, "old", encodeClaims(t, valid)), valid: true},
+		{name: "expired", token: signPayload(t, signer, "new", encodeClaims(t, expired))},
+		{name: "tampered signature", token: kid + "." + payload + "." + flipFirst(signature)},
+		{name: "empty signature", token: kid + "." + payload + "."},
+		{name: "tampered payload", token: kid + "." + base64.RawURLEncoding.EncodeToString(encodeClaims(t, otherTitle)) + "." + signature},
+		{name: "signature of another key", token: "old." + payload + "." + signature},
+		{name: "unknown key", token: "missing." + payload + "." + signature},
+		{name: "retired key", token: retiredToken},
+		{name: "no separators", token: "garbage"},
+		{name: "no payload", token: kid + "." + signature},
+		{name: "payload is not base64", token: "new.!!!." + signer.signature(signer.keys["new"], "new.!!!")},
+		{name: "payload is not json", token: signPayload(t, signer, "new", []byte("not json"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := signer.Verify(tt.token)
+			if tt.valid {
+				if err != nil || claims.TitleID != valid.TitleID {
+					t.Fatalf("Verify() = %+v, %v, want valid claims", claims, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Verify() = %+v, %v, want ErrInvalidToken", claims, err)
+			}
+		})
+	}
+}
+
+func TestNewPlaybackSigner(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		ttl  time.Duration
+		ok   bool
+	}{
+		{name: "rotation", spec: newKey + ", " + oldKey, ttl: time.Hour, ok: true},
+		{name: "zero ttl", spec: newKey, ttl: 0},
+		{name: "no secret", spec: "new", ttl: time.Hour},
+		{name: "empty kid", spec: ":fedcba9876543210fedcba9876543210", ttl: time.Hour},
+		{name: "dot in kid", spec: "a.b:fedcba9876543210fedcba9876543210", ttl: time.Hour},
+		{name: "short secret", spec: "new:short", ttl: time.Hour},
+		{name: "duplicate kid", spec: newKey + "," + newKey, ttl: time.Hour},
+		{name: "empty spec", spec: "", ttl: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewPlaybackSigner(tt.spec, tt.ttl)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NewPlaybackSigner() error = %v, want ok = %v", err, tt.ok)
+			}
+			if tt.ok && signer.active != "new" {
+				t.Errorf("active key = %q, want the first one", signer.active)
+			}
+		})
+	}
+}
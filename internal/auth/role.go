@@ -0,0 +1,14 @@
+package auth
+
+type Role string
+
+const (
+	// RoleAdmin - глобальная роль, разрешено все
+	RoleAdmin Role = "admin"
+	// RoleOwnerMember - полный доступ к владельцу и его фильмам
+	RoleOwnerMember Role = "owner-member"
+	// RoleEditor - изменение фильмов владельца
+	RoleEditor Role = "editor"
+	// RoleViewer - только чтение
+	RoleViewer Role = "viewer"
+)
@@ -0,0 +1,44 @@
+package cache
+
+import "testing"
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a")
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used entry is not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUAddIfGeneration(t *testing.T) {
+	c := NewLRU[string, int](2)
+
+	generation := c.Generation()
+	if !c.AddIfGeneration("a", 1, generation) {
+		t.Fatal("AddIfGeneration rejected the current generation")
+	}
+
+	// Значение прочитано до Purge и не должно вернуться в кэш после него
+	stale := c.Generation()
+	c.Purge()
+	if c.AddIfGeneration("a", 1, stale) {
+		t.Error("AddIfGeneration accepted a generation from before Purge")
+	}
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("stale entry is cached after Purge")
+	}
+
+	if !c.AddIfGeneration("a", 2, c.Generation()) {
+		t.Error("AddIfGeneration rejected the generation after Purge")
+	}
+}
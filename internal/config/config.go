@@ -1,11 +1,34 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 type AppConfig struct {
 	LogLevel   string
 	Rest       Rest
 	PostgreSQL PostgreSQL
+	Auth       Auth
+	Suggest    Suggest
+	Trash      Trash
+	Upload     Upload
+	Storage    Storage
+	HLS        HLS
+	Jobs       Jobs
+	Playback   Playback
+}
+
+// Validate проверяет значения, которые envconfig принимает, но с которыми сервис работать не может.
+func (c *AppConfig) Validate() error {
+	if err := c.Trash.Validate(); err != nil {
+		return err
+	}
+	if err := c.Jobs.Validate(); err != nil {
+		return err
+	}
+	return nil
 }
 
 type Rest struct {
@@ -13,6 +36,9 @@ type Rest struct {
 	WriteTimeout  time.Duration `envconfig:"WRITE_TIMEOUT"`
 	ServerName    string        `envconfig:"SERVER_NAME"`
 	Token         string        `envconfig:"TOKEN"`
+	PublicRead    bool          `envconfig:"PUBLIC_READ" default:"false"`
+	// BodyLimit ограничивает тело запроса, в том числе размер фрагмента tus-загрузки
+	BodyLimit int `envconfig:"BODY_LIMIT" default:"67108864"`
 }
 
 type PostgreSQL struct {
@@ -26,3 +52,95 @@ type PostgreSQL struct {
 	PoolMaxConnLifetime time.Duration `envconfig:"DB_POOL_MAX_CONN_LIFETIME" default:"180s"`
 	PoolMaxConnIdleTime time.Duration `envconfig:"DB_POOL_MAX_CONN_IDLE_TIME" default:"100s"`
 }
+
+type Auth struct {
+	JWTSecret  string        `envconfig:"JWT_SECRET" required:"true"`
+	AccessTTL  time.Duration `envconfig:"JWT_ACCESS_TTL" default:"15m"`
+	RefreshTTL time.Duration `envconfig:"JWT_REFRESH_TTL" default:"720h"`
+}
+
+type Suggest struct {
+	CacheSize int `envconfig:"SUGGEST_CACHE_SIZE" default:"1024"`
+}
+
+type Trash struct {
+	Retention     time.Duration `envconfig:"TRASH_RETENTION" default:"720h"`
+	PurgeInterval time.Duration `envconfig:"TRASH_PURGE_INTERVAL" default:"1h"`
+}
+
+func (t Trash) Validate() error {
+	if t.Retention <= 0 {
+		return errors.New("TRASH_RETENTION must be positive")
+	}
+	if t.PurgeInterval <= 0 {
+		return errors.New("TRASH_PURGE_INTERVAL must be positive")
+	}
+	return nil
+}
+
+type Upload struct {
+	Dir     string `envconfig:"UPLOAD_DIR" default:"data/uploads"`
+	MaxSize int64  `envconfig:"UPLOAD_MAX_SIZE" default:"10737418240"`
+}
+
+// Storage выбирает хранилище готовых медиафайлов: fs - локальный каталог, s3 - S3-совместимый сервис
+type Storage struct {
+	Driver      string `envconfig:"STORAGE_DRIVER" default:"fs"`
+	Dir         string `envconfig:"STORAGE_DIR" default:"data/media"`
+	S3Endpoint  string `envconfig:"STORAGE_S3_ENDPOINT"`
+	S3Region    string `envconfig:"STORAGE_S3_REGION" default:"us-east-1"`
+	S3Bucket    string `envconfig:"STORAGE_S3_BUCKET"`
+	S3AccessKey string `envconfig:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey string `envconfig:"STORAGE_S3_SECRET_KEY"`
+	S3PathStyle bool   `envconfig:"STORAGE_S3_PATH_STYLE" default:"true"`
+}
+
+// HLS - нарезка готовых медиафайлов на варианты для адаптивного воспроизведения.
+// Манифест DASH собирается из тех же сегментов и поэтому есть только при HLS_SEGMENT_FORMAT=fmp4
+type HLS struct {
+	Enabled         bool          `envconfig:"HLS_ENABLED" default:"true"`
+	FFmpegPath      string        `envconfig:"HLS_FFMPEG_PATH" default:"ffmpeg"`
+	FFprobePath     string        `envconfig:"HLS_FFPROBE_PATH" default:"ffprobe"`
+	Ladder          string        `envconfig:"HLS_LADDER" default:"1080p:1920x1080:5000:192,720p:1280x720:2800:128,480p:854x480:1400:96,360p:640x360:800:96"`
+	SegmentDuration time.Duration `envconfig:"HLS_SEGMENT_DURATION" default:"6s"`
+	SegmentFormat   string        `envconfig:"HLS_SEGMENT_FORMAT" default:"fmp4"`
+	WorkDir         string        `envconfig:"HLS_WORK_DIR" default:"data/packaging"`
+}
+
+// Jobs - фоновые задачи из очереди в Postgres. Неудачная попытка повторяется через
+// JOBS_BACKOFF_BASE, и задержка удваивается до JOBS_BACKOFF_MAX
+type Jobs struct {
+	Workers      int           `envconfig:"JOBS_WORKERS" default:"2"`
+	PollInterval time.Duration `envconfig:"JOBS_POLL_INTERVAL" default:"2s"`
+	Lease        time.Duration `envconfig:"JOBS_LEASE" default:"5m"`
+	MaxAttempts  int           `envconfig:"JOBS_MAX_ATTEMPTS" default:"5"`
+	BackoffBase  time.Duration `envconfig:"JOBS_BACKOFF_BASE" default:"30s"`
+	BackoffMax   time.Duration `envconfig:"JOBS_BACKOFF_MAX" default:"1h"`
+}
+
+func (j Jobs) Validate() error {
+	switch {
+	case j.Workers <= 0:
+		return errors.New("JOBS_WORKERS must be positive")
+	case j.PollInterval <= 0:
+		return errors.New("JOBS_POLL_INTERVAL must be positive")
+	case j.Lease < 3*time.Second:
+		// Аренда продлевается каждую треть срока
+		return errors.New("JOBS_LEASE must be at least 3s")
+	case j.MaxAttempts <= 0:
+		return errors.New("JOBS_MAX_ATTEMPTS must be positive")
+	case j.BackoffBase <= 0:
+		return errors.New("JOBS_BACKOFF_BASE must be positive")
+	case j.BackoffMax < j.BackoffBase:
+		return errors.New("JOBS_BACKOFF_MAX must not be less than JOBS_BACKOFF_BASE")
+	}
+	return nil
+}
+
+// Playback - подписанные ссылки воспроизведения. PLAYBACK_KEYS - ключи kid:secret через запятую:
+// первым подписываются новые ссылки, остальные только проверяются. При ротации новый ключ ставится
+// первым, а старый остается в списке, пока не истекут выданные им ссылки (PLAYBACK_TOKEN_TTL)
+type Playback struct {
+	Keys     string        `envconfig:"PLAYBACK_KEYS" required:"true"`
+	TokenTTL time.Duration `envconfig:"PLAYBACK_TOKEN_TTL" default:"4h"`
+}
@@ -0,0 +1,280 @@
+package dash
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Файл манифеста в каталоге пакета; сегменты адресуются относительно него
+const ManifestName = "manifest.mpd"
+
+const (
+	mpdNamespace    = "urn:mpeg:dash:schema:mpd:2011"
+	profileLive     = "urn:mpeg:dash:profile:isoff-live:2011"
+	roleScheme      = "urn:mpeg:dash:role:2011"
+	channelScheme   = "urn:mpeg:dash:23003:3:audio_channel_configuration:2011"
+	timescale       = 1000
+	undetermined    = "und"
+	subtitleRole    = "subtitle"
+	mainRole        = "main"
+	textBandwidth   = 256
+	defaultChannels = 2
+)
+
+// Manifest описывает VOD-презентацию из одного периода. Сегменты - CMAF (fMP4),
+// поэтому одни и те же файлы годятся и для HLS, и для DASH.
+type Manifest struct {
+	Duration      time.Duration
+	MinBufferTime time.Duration
+	Video         []Video
+	Audio         []Audio
+	Text          []Text
+}
+
+// Segments - расположение сегментов представления относительно манифеста.
+// В шаблонах Initialization и Media допустимы $RepresentationID$ и $Number%05d$.
+type Segments struct {
+	Initialization string
+	Media          string
+	StartNumber    int
+	// StartTime - время начала первого сегмента в медиапотоке; ненулевое, если кодировщик сдвинул отсчет
+	StartTime time.Duration
+	// Durations - длительности сегментов по порядку, из них строится SegmentTimeline
+	Durations []time.Duration
+}
+
+type Video struct {
+	ID        string
+	Width     int
+	Height    int
+	Bandwidth int
+	Codecs    string
+	Segments  Segments
+}
+
+type Audio struct {
+	ID         string
+	Language   string
+	Bandwidth  int
+	Codecs     string
+	Channels   int
+	SampleRate int
+	Segments   Segments
+}
+
+// Text - внешний файл субтитров WebVTT, он отдается целиком, без сегментов.
+type Text struct {
+	ID       string
+	Language string
+	URL      string
+}
+
+// Generate строит MPD. Видео - одна AdaptationSet, аудио и субтитры - по одной на язык,
+// чтобы плеер переключал битрейт только внутри одного языка.
+func Generate(m Manifest) ([]byte, error) {
+	if len(m.Video) == 0 {
+		return nil, errors.New("manifest has no video representations")
+	}
+
+	doc := mpd{
+		XMLNS:                     mpdNamespace,
+		Profiles:                  profileLive,
+		Type:                      "static",
+		MediaPresentationDuration: formatDuration(m.Duration),
+		MinBufferTime:             formatDuration(m.MinBufferTime),
+		Period:                    period{ID: "0", Start: formatDuration(0)},
+	}
+
+	video := adaptationSet{
+		ID:               0,
+		ContentType:      "video",
+		MimeType:         "video/mp4",
+		SegmentAlignment: true,
+		StartWithSAP:     1,
+		Role:             &descriptor{SchemeIDURI: roleScheme, Value: mainRole},
+	}
+	for _, v := range m.Video {
+		template, err := segmentTemplate(v.Segments)
+		if err != nil {
+			return nil, errors.Wrapf(err, "video representation %s", v.ID)
+		}
+		video.MaxWidth = max(video.MaxWidth, v.Width)
+		video.MaxHeight = max(video.MaxHeight, v.Height)
+		video.Representations = append(video.Representations, representation{
+			ID:              v.ID,
+			Bandwidth:       v.Bandwidth,
+			Width:           v.Width,
+			Height:          v.Height,
+			Codecs:          v.Codecs,
+			SegmentTemplate: template,
+		})
+	}
+	doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, video)
+
+	for _, language := range languages(m.Audio, func(a Audio) string { return a.Language }) {
+		set := adaptationSet{
+			ID:               len(doc.Period.AdaptationSets),
+			ContentType:      "audio",
+			MimeType:         "audio/mp4",
+			Lang:             language,
+			SegmentAlignment: true,
+			StartWithSAP:     1,
+		}
+		for _, a := range m.Audio {
+			if normalizeLanguage(a.Language) != language {
+				continue
+			}
+			template, err := segmentTemplate(a.Segments)
+			if err != nil {
+				return nil, errors.Wrapf(err, "audio representation %s", a.ID)
+			}
+			channels := a.Channels
+			if channels <= 0 {
+				channels = defaultChannels
+			}
+			set.Representations = append(set.Representations, representation{
+				ID:                a.ID,
+				Bandwidth:         a.Bandwidth,
+				Codecs:            a.Codecs,
+				AudioSamplingRate: a.SampleRate,
+				AudioChannels:     &descriptor{SchemeIDURI: channelScheme, Value: fmt.Sprint(channels)},
+				SegmentTemplate:   template,
+			})
+		}
+		doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, set)
+	}
+
+	for _, t := range m.Text {
+		doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, adaptationSet{
+			ID:          len(doc.Period.AdaptationSets),
+			ContentType: "text",
+			MimeType:    "text/vtt",
+			Lang:        normalizeLanguage(t.Language),
+			Role:        &descriptor{SchemeIDURI: roleScheme, Value: subtitleRole},
+			Representations: []representation{{
+				ID:        t.ID,
+				Bandwidth: textBandwidth,
+				BaseURL:   t.URL,
+			}},
+		})
+	}
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, errors.Wrap(err, "failed to encode manifest")
+	}
+	out.WriteString("\n")
+
+	return out.Bytes(), nil
+}
+
+// ContentType - MIME-тип файла DASH-пакета по имени; пустая строка - файл пакету не принадлежит.
+func ContentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".mpd"):
+		return "application/dash+xml"
+	case strings.HasSuffix(name, ".m4s"):
+		return "video/iso.segment"
+	case strings.HasSuffix(name, ".mp4"):
+		return "video/mp4"
+	case strings.HasSuffix(name, ".vtt"):
+		return "text/vtt"
+	default:
+		return ""
+	}
+}
+
+// segmentTemplate сворачивает подряд идущие одинаковые длительности в S@r.
+// Начало каждого сегмента считается от накопленного времени, поэтому округление не накапливается.
+func segmentTemplate(segments Segments) (*segmentTemplateXML, error) {
+	if len(segments.Durations) == 0 {
+		return nil, errors.New("representation has no segments")
+	}
+
+	var timeline []segmentXML
+	elapsed := segments.StartTime
+	for i, duration := range segments.Durations {
+		if duration <= 0 {
+			return nil, errors.Errorf("segment %d has non-positive duration", i)
+		}
+
+		start, end := ticks(elapsed), ticks(elapsed+duration)
+		elapsed += duration
+
+		if last := len(timeline) - 1; last >= 0 && timeline[last].D == end-start {
+			timeline[last].R++
+			continue
+		}
+
+		s := segmentXML{D: end - start}
+		if i == 0 {
+			s.T = &start
+		}
+		timeline = append(timeline, s)
+	}
+
+	// presentationTimeOffset совмещает начало первого сегмента с началом периода
+	return &segmentTemplateXML{
+		Timescale:              timescale,
+		PresentationTimeOffset: ticks(segments.StartTime),
+		Initialization:         segments.Initialization,
+		Media:                  segments.Media,
+		StartNumber:            segments.StartNumber,
+		Timeline:               timeline,
+	}, nil
+}
+
+func ticks(d time.Duration) int64 {
+	return int64(math.Round(d.Seconds() * timescale))
+}
+
+// formatDuration записывает длительность в виде xs:duration, например PT1H2M3.500S.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+
+	var out strings.Builder
+	out.WriteString("PT")
+	if hours > 0 {
+		fmt.Fprintf(&out, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&out, "%dM", minutes)
+	}
+	fmt.Fprintf(&out, "%.3fS", d.Seconds())
+	return out.String()
+}
+
+func normalizeLanguage(language string) string {
+	if language == "" {
+		return undetermined
+	}
+	return language
+}
+
+// languages - языки в порядке первого появления.
+func languages[T any](items []T, language func(T) string) []string {
+	seen := make(map[string]bool)
+	ordered := make([]string, 0)
+	for _, item := range items {
+		lang := normalizeLanguage(language(item))
+		if !seen[lang] {
+			seen[lang] = true
+			ordered = append(ordered, lang)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,154 @@
+package dash
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func segments(durations ...time.Duration) Segments {
+	return Segments{
+		Initialization: "$RepresentationID$/init.mp4",
+		Media:          "$RepresentationID$/seg_$Number%05d$.m4s",
+		Durations:      durations,
+	}
+}
+
+func TestGenerateGolden(t *testing.T) {
+	const s = time.Second
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+	}{
+		{
+			name: "video_audio_text",
+			manifest: Manifest{
+				Duration:      22500 * time.Millisecond,
+				MinBufferTime: 2 * s,
+				Video: []Video{
+					{ID: "720p", Width: 1280, Height: 720, Bandwidth: 3080000, Codecs: "avc1.4d401f",
+						Segments: segments(6*s, 6*s, 6*s, 4500*time.Millisecond)},
+					{ID: "360p", Width: 640, Height: 360, Bandwidth: 880000, Codecs: "avc1.4d401e",
+						Segments: segments(6*s, 6*s, 6*s, 4500*time.Millisecond)},
+				},
+				Audio: []Audio{
+					{ID: "audio_128k", Language: "en", Bandwidth: 128000, Codecs: "mp4a.40.2", Channels: 2, SampleRate: 48000,
+						Segments: segments(6*s, 6*s, 6*s, 4500*time.Millisecond)},
+					{ID: "audio_96k", Bandwidth: 96000, Codecs: "mp4a.40.2", SampleRate: 48000,
+						Segments: segments(6*s, 6*s, 6*s, 4500*time.Millisecond)},
+				},
+				Text: []Text{
+					{ID: "text_3", Language: "en", URL: "text_3/subtitles.vtt"},
+					{ID: "text_4", URL: "text_4/subtitles.vtt"},
+				},
+			},
+		},
+		{
+			// Одинаковые сегменты сворачиваются в r, а дробные длительности округляются по границам
+			// сегментов: 333.4+333.3+333.3 мс дают 333, 334 и 333 тика без накопления ошибки
+			name: "timeline_folding",
+			manifest: Manifest{
+				Duration:      10 * s,
+				MinBufferTime: 2 * s,
+				Video: []Video{
+					{ID: "480p", Width: 854, Height: 480, Bandwidth: 1540000, Codecs: "avc1.4d401e",
+						Segments: segments(2002*time.Millisecond, 2002*time.Millisecond, 2002*time.Millisecond,
+							333400*time.Microsecond, 333300*time.Microsecond, 333300*time.Microsecond, 994*time.Millisecond)},
+				},
+			},
+		},
+		{
+			name: "start_time",
+			manifest: Manifest{
+				Duration:      12 * s,
+				MinBufferTime: 2 * s,
+				Video: []Video{
+					{ID: "360p", Width: 640, Height: 360, Bandwidth: 880000, Codecs: "avc1.4d401e",
+						Segments: Segments{
+							Initialization: "$RepresentationID$/init.mp4",
+							Media:          "$RepresentationID$/seg_$Number%05d$.m4s",
+							StartNumber:    1,
+							StartTime:      1400 * time.Millisecond,
+							Durations:      []time.Duration{6 * s, 6 * s},
+						}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(tt.manifest)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".mpd")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("manifest differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest Manifest
+	}{
+		{name: "no video", manifest: Manifest{}},
+		{name: "no segments", manifest: Manifest{Video: []Video{{ID: "v"}}}},
+		{name: "zero duration", manifest: Manifest{Video: []Video{{ID: "v", Segments: segments(time.Second, 0)}}}},
+		{name: "bad audio", manifest: Manifest{
+			Video: []Video{{ID: "v", Segments: segments(time.Second)}},
+			Audio: []Audio{{ID: "a"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Generate(tt.manifest); err == nil {
+				t.Error("Generate succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "PT0.000S"},
+		{400 * time.Microsecond, "PT0.000S"},
+		{500 * time.Microsecond, "PT0.001S"},
+		{1500 * time.Millisecond, "PT1.500S"},
+		{59999600 * time.Microsecond, "PT1M0.000S"},
+		{time.Minute, "PT1M0.000S"},
+		{time.Hour, "PT1H0.000S"},
+		{time.Hour + 5*time.Second, "PT1H5.000S"},
+		{26*time.Hour + 3*time.Minute + 4500*time.Millisecond, "PT26H3M4.500S"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.in); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dash
+
+import "encoding/xml"
+
+// Схема MPD (ISO/IEC 23009-1) в объеме, который нужен для VOD-пакетов сервиса.
+
+type mpd struct {
+	XMLName                   xml.Name `xml:"MPD"`
+	XMLNS                     string   `xml:"xmlns,attr"`
+	Profiles                  string   `xml:"profiles,attr"`
+	Type                      string   `xml:"type,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string   `xml:"minBufferTime,attr"`
+	Period                    period   `xml:"Period"`
+}
+
+type period struct {
+	ID             string          `xml:"id,attr"`
+	Start          string          `xml:"start,attr"`
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	ID               int              `xml:"id,attr"`
+	ContentType      string           `xml:"contentType,attr"`
+	MimeType         string           `xml:"mimeType,attr"`
+	Lang             string           `xml:"lang,attr,omitempty"`
+	SegmentAlignment bool             `xml:"segmentAlignment,attr,omitempty"`
+	StartWithSAP     int              `xml:"startWithSAP,attr,omitempty"`
+	MaxWidth         int              `xml:"maxWidth,attr,omitempty"`
+	MaxHeight        int              `xml:"maxHeight,attr,omitempty"`
+	Role             *descriptor      `xml:"Role"`
+	Representations  []representation `xml:"Representation"`
+}
+
+type descriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+type representation struct {
+	ID                string              `xml:"id,attr"`
+	Bandwidth         int                 `xml:"bandwidth,attr"`
+	Width             int                 `xml:"width,attr,omitempty"`
+	Height            int                 `xml:"height,attr,omitempty"`
+	Codecs            string              `xml:"codecs,attr,omitempty"`
+	AudioSamplingRate int                 `xml:"audioSamplingRate,attr,omitempty"`
+	AudioChannels     *descriptor         `xml:"AudioChannelConfiguration"`
+	BaseURL           string              `xml:"BaseURL,omitempty"`
+	SegmentTemplate   *segmentTemplateXML `xml:"SegmentTemplate"`
+}
+
+type segmentTemplateXML struct {
+	Timescale              int          `xml:"timescale,attr"`
+	PresentationTimeOffset int64        `xml:"presentationTimeOffset,attr,omitempty"`
+	Initialization         string       `xml:"initialization,attr"`
+	Media                  string       `xml:"media,attr"`
+	StartNumber            int          `xml:"startNumber,attr"`
+	Timeline               []segmentXML `xml:"SegmentTimeline>S"`
+}
+
+// segmentXML - элемент S: t задается только у первого сегмента, r - число повторов после него.
+type segmentXML struct {
+	T *int64 `xml:"t,attr"`
+	D int64  `xml:"d,attr"`
+	R int    `xml:"r,attr,omitempty"`
+}
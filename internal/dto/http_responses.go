@@ -3,16 +3,31 @@ package dto
 import "github.com/gofiber/fiber/v2"
 
 const (
-	FieldBadFormat     = "FIELD_BADFORMAT"
-	ServiceUnavailable = "SERVICE_UNAVAILABLE"
-	InternalError      = "Service is currently unavailable. Please try again later."
-	FieldRequired      = "FIELD_REQUIRED" // Новая константа
+	FieldBadFormat       = "FIELD_BADFORMAT"
+	ServiceUnavailable   = "SERVICE_UNAVAILABLE"
+	InternalError        = "Service is currently unavailable. Please try again later."
+	FieldRequired        = "FIELD_REQUIRED" // Новая константа
+	Unauthorized         = "UNAUTHORIZED"
+	AlreadyExists        = "ALREADY_EXISTS"
+	Forbidden            = "FORBIDDEN"
+	NotFound             = "NOT_FOUND"
+	Conflict             = "CONFLICT"
+	ReferenceNotFound    = "REFERENCE_NOT_FOUND"
+	ConstraintFailed     = "CONSTRAINT_VIOLATION"
+	InvalidUUID          = "INVALID_UUID"
+	ValidationFailed     = "VALIDATION_FAILED"
+	UnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	PreconditionFailed   = "PRECONDITION_FAILED"
+	PayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ChecksumMismatch     = "CHECKSUM_MISMATCH"
+	RangeNotSatisfiable  = "RANGE_NOT_SATISFIABLE"
 )
 
 type Response struct {
-	Status string `json:"status"`
-	Error  *Error `json:"error,omitempty"`
-	Data   any    `json:"data,omitempty"`
+	Status string       `json:"status"`
+	Error  *Error       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+	Data   any          `json:"data,omitempty"`
 }
 
 type Error struct {
@@ -20,6 +35,19 @@ type Error struct {
 	Desc string `json:"desc"`
 }
 
+type Page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 func BadRequestError(ctx *fiber.Ctx, code, desc string) error {
 	return ctx.Status(fiber.StatusBadRequest).JSON(&Response{
 		Status: "error",
@@ -39,3 +67,74 @@ func InternalServerError(ctx *fiber.Ctx) error {
 		},
 	})
 }
+
+func UnauthorizedError(ctx *fiber.Ctx, desc string) error {
+	return ctx.Status(fiber.StatusUnauthorized).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: Unauthorized,
+			Desc: desc,
+		},
+	})
+}
+
+func ConflictError(ctx *fiber.Ctx, code, desc string) error {
+	return ctx.Status(fiber.StatusConflict).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: code,
+			Desc: desc,
+		},
+	})
+}
+
+func ForbiddenError(ctx *fiber.Ctx, desc string) error {
+	return ctx.Status(fiber.StatusForbidden).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: Forbidden,
+			Desc: desc,
+		},
+	})
+}
+
+func NotFoundError(ctx *fiber.Ctx, code, desc string) error {
+	return ctx.Status(fiber.StatusNotFound).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: code,
+			Desc: desc,
+		},
+	})
+}
+
+func UnprocessableEntityError(ctx *fiber.Ctx, code, desc string) error {
+	return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: code,
+			Desc: desc,
+		},
+	})
+}
+
+func ValidationError(ctx *fiber.Ctx, fields []FieldError) error {
+	return ctx.Status(fiber.StatusBadRequest).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: ValidationFailed,
+			Desc: "Request validation failed",
+		},
+		Errors: fields,
+	})
+}
+
+func PreconditionFailedError(ctx *fiber.Ctx, desc string) error {
+	return ctx.Status(fiber.StatusPreconditionFailed).JSON(&Response{
+		Status: "error",
+		Error: &Error{
+			Code: PreconditionFailed,
+			Desc: desc,
+		},
+	})
+}
@@ -0,0 +1,192 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Source - дорожки исходника, от которых зависит состав пакета.
+type Source struct {
+	Width     int
+	Height    int
+	HasAudio  bool
+	Subtitles []SubtitleStream
+}
+
+// SubtitleStream - текстовые субтитры исходника; Index - номер потока в файле.
+type SubtitleStream struct {
+	Index    int
+	Language string
+}
+
+// Transcoder нарезает исходник на сегменты HLS: видео и аудио - отдельными вариантами, чтобы
+// те же CMAF-сегменты годились и для DASH. Реализация по умолчанию вызывает ffmpeg;
+// в тестах ее можно заменить подделкой.
+type Transcoder interface {
+	Probe(ctx context.Context, input string) (*Source, error)
+	// TranscodeVideo и TranscodeAudio пишут в outDir плейлист index.m3u8, сегменты seg_NNNNN и для fMP4 - init.mp4
+	TranscodeVideo(ctx context.Context, input, outDir string, r Rendition, opts SegmentOptions) error
+	TranscodeAudio(ctx context.Context, input, outDir string, bitrate int, opts SegmentOptions) error
+	// ExtractSubtitles сохраняет поток субтитров в output в формате WebVTT
+	ExtractSubtitles(ctx context.Context, input string, stream int, output string) error
+}
+
+type SegmentOptions struct {
+	Format   string
+	Duration time.Duration
+}
+
+// textSubtitleCodecs - кодеки субтитров, которые ffmpeg умеет перевести в WebVTT; растровые пропускаются
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "ass": true, "ssa": true, "mov_text": true, "webvtt": true, "text": true,
+}
+
+// FFmpeg - Transcoder поверх локальных ffmpeg и ffprobe.
+type FFmpeg struct {
+	ffmpeg  string
+	ffprobe string
+}
+
+func NewFFmpeg(ffmpegPath, ffprobePath string) *FFmpeg {
+	return &FFmpeg{ffmpeg: ffmpegPath, ffprobe: ffprobePath}
+}
+
+func (f *FFmpeg) Probe(ctx context.Context, input string) (*Source, error) {
+	output, err := run(ctx, f.ffprobe, "-v", "error",
+		"-show_entries", "stream=index,codec_type,codec_name,width,height:stream_tags=language", "-of", "json", input)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to probe source")
+	}
+
+	var probe struct {
+		Streams []struct {
+			Index     int    `json:"index"`
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			Tags      struct {
+				Language string `json:"language"`
+			} `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(output), &probe); err != nil {
+		return nil, errors.Wrap(err, "failed to decode ffprobe output")
+	}
+
+	var source Source
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if source.Height == 0 {
+				source.Width, source.Height = stream.Width, stream.Height
+			}
+		case "audio":
+			source.HasAudio = true
+		case "subtitle":
+			if textSubtitleCodecs[stream.CodecName] {
+				source.Subtitles = append(source.Subtitles, SubtitleStream{Index: stream.Index, Language: stream.Tags.Language})
+			}
+		}
+	}
+
+	if source.Height == 0 {
+		return nil, errors.New("source has no video stream")
+	}
+	return &source, nil
+}
+
+func (f *FFmpeg) TranscodeVideo(ctx context.Context, input, outDir string, r Rendition, opts SegmentOptions) error {
+	args := []string{
+		"-hide_banner", "-nostdin", "-y", "-i", input,
+		"-map", "0:v:0", "-an", "-sn",
+		"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+		"-level", fmt.Sprintf("%d.%d", r.Level()/10, r.Level()%10),
+		"-vf", fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2", r.Width, r.Height),
+		"-b:v", fmt.Sprintf("%dk", r.VideoBitrate),
+		"-maxrate", fmt.Sprintf("%dk", r.VideoBandwidth()/1000),
+		"-bufsize", fmt.Sprintf("%dk", r.VideoBitrate*3/2),
+		// Ключевой кадр на границе каждого сегмента: иначе варианты нельзя переключать на лету
+		"-force_key_frames", "expr:gte(t,n_forced*" + seconds(opts.Duration) + ")", "-sc_threshold", "0",
+	}
+
+	if err := f.segment(ctx, append(args, segmentArgs(outDir, opts)...)); err != nil {
+		return errors.Wrapf(err, "failed to transcode rendition %s", r.Name)
+	}
+	return nil
+}
+
+func (f *FFmpeg) TranscodeAudio(ctx context.Context, input, outDir string, bitrate int, opts SegmentOptions) error {
+	args := []string{
+		"-hide_banner", "-nostdin", "-y", "-i", input,
+		"-map", "0:a:0", "-vn", "-sn",
+		"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", bitrate), "-ac", strconv.Itoa(AudioChannels), "-ar", strconv.Itoa(AudioSampleRate),
+	}
+
+	if err := f.segment(ctx, append(args, segmentArgs(outDir, opts)...)); err != nil {
+		return errors.Wrapf(err, "failed to transcode audio at %d kbps", bitrate)
+	}
+	return nil
+}
+
+func (f *FFmpeg) ExtractSubtitles(ctx context.Context, input string, stream int, output string) error {
+	_, err := run(ctx, f.ffmpeg, "-hide_banner", "-nostdin", "-y", "-i", input,
+		"-map", "0:"+strconv.Itoa(stream), "-c:s", "webvtt", "-f", "webvtt", output)
+	if err != nil {
+		return errors.Wrapf(err, "failed to extract subtitle stream %d", stream)
+	}
+	return nil
+}
+
+func (f *FFmpeg) segment(ctx context.Context, args []string) error {
+	_, err := run(ctx, f.ffmpeg, args...)
+	return err
+}
+
+// segmentArgs - параметры HLS-мультиплексора ffmpeg, общие для видео и аудио.
+func segmentArgs(outDir string, opts SegmentOptions) []string {
+	segmentType := "mpegts"
+	if opts.Format == SegmentFMP4 {
+		segmentType = "fmp4"
+	}
+
+	args := []string{
+		"-f", "hls", "-hls_time", seconds(opts.Duration), "-hls_playlist_type", "vod", "-hls_segment_type", segmentType,
+		"-hls_segment_filename", filepath.Join(outDir, segmentTemplate+segmentExtension(opts.Format)),
+	}
+	if opts.Format == SegmentFMP4 {
+		args = append(args, "-hls_fmp4_init_filename", InitSegment)
+	}
+	return append(args, filepath.Join(outDir, MediaPlaylist))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// run выполняет команду и возвращает stdout; в ошибку попадает хвост stderr.
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		tail := stderr.String()
+		if len(tail) > 2048 {
+			tail = tail[len(tail)-2048:]
+		}
+		return "", errors.Wrapf(err, "%s: %s", filepath.Base(name), strings.TrimSpace(tail))
+	}
+	return stdout.String(), nil
+}
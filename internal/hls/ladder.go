@@ -0,0 +1,147 @@
+package hls
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Форматы сегментов: MPEG-TS совместим со старыми плеерами, fMP4 (CMAF) переиспользуется для DASH
+const (
+	SegmentTS   = "ts"
+	SegmentFMP4 = "fmp4"
+)
+
+// Аудио кодируется в AAC-LC, стерео 48 кГц
+const (
+	AudioCodec      = "mp4a.40.2"
+	AudioSampleRate = 48000
+	AudioChannels   = 2
+)
+
+// Rendition - одно качество лестницы: разрешение и битрейты в кбит/с.
+// Аудио нарезается отдельно от видео, варианты с одинаковым аудиобитрейтом делят одну аудиодорожку.
+type Rendition struct {
+	Name         string
+	Width        int
+	Height       int
+	VideoBitrate int
+	AudioBitrate int
+}
+
+var renditionName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ParseLadder разбирает лестницу вида "720p:1280x720:2800:128,480p:854x480:1400:96"
+// (имя:разрешение:видео кбит/с:аудио кбит/с). Результат упорядочен от большего разрешения к меньшему.
+func ParseLadder(spec string) ([]Rendition, error) {
+	ladder := make([]Rendition, 0)
+	names := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		fields := strings.Split(item, ":")
+		if len(fields) != 4 {
+			return nil, errors.Errorf("rendition %q must look like name:WxH:video_kbps:audio_kbps", item)
+		}
+
+		r := Rendition{Name: fields[0]}
+		if !renditionName.MatchString(r.Name) || names[r.Name] {
+			return nil, errors.Errorf("rendition name %q is invalid or duplicated", r.Name)
+		}
+		names[r.Name] = true
+
+		width, height, ok := strings.Cut(fields[1], "x")
+		var err error
+		if r.Width, err = strconv.Atoi(width); !ok || err != nil || r.Width <= 0 || r.Width%2 != 0 {
+			return nil, errors.Errorf("rendition %q has invalid width", r.Name)
+		}
+		if r.Height, err = strconv.Atoi(height); err != nil || r.Height <= 0 || r.Height%2 != 0 {
+			return nil, errors.Errorf("rendition %q has invalid height", r.Name)
+		}
+		if r.VideoBitrate, err = strconv.Atoi(fields[2]); err != nil || r.VideoBitrate <= 0 {
+			return nil, errors.Errorf("rendition %q has invalid video bitrate", r.Name)
+		}
+		if r.AudioBitrate, err = strconv.Atoi(fields[3]); err != nil || r.AudioBitrate <= 0 {
+			return nil, errors.Errorf("rendition %q has invalid audio bitrate", r.Name)
+		}
+
+		ladder = append(ladder, r)
+	}
+
+	if len(ladder) == 0 {
+		return nil, errors.New("rendition ladder is empty")
+	}
+
+	sort.SliceStable(ladder, func(i, j int) bool { return ladder[i].Height > ladder[j].Height })
+	return ladder, nil
+}
+
+// VideoBandwidth - пиковый битрейт видео в бит/с: с запасом на колебания VBV-буфера (-maxrate).
+func (r Rendition) VideoBandwidth() int {
+	return r.VideoBitrate * 1000 * 107 / 100
+}
+
+// Level - уровень H.264, умноженный на 10, которого достаточно для разрешения варианта (профиль Main, до 30 fps).
+func (r Rendition) Level() int {
+	switch {
+	case r.Height <= 480:
+		return 30
+	case r.Height <= 720:
+		return 31
+	case r.Height <= 1080:
+		return 40
+	default:
+		return 51
+	}
+}
+
+// VideoCodec - обозначение H.264 Main нужного уровня в формате RFC 6381.
+func (r Rendition) VideoCodec() string {
+	return fmt.Sprintf("avc1.4d40%02x", r.Level())
+}
+
+// AudioName - имя аудиодорожки варианта, оно же каталог и GROUP-ID в главном плейлисте.
+func (r Rendition) AudioName() string {
+	return audioName(r.AudioBitrate)
+}
+
+func audioName(bitrate int) string {
+	return fmt.Sprintf("audio_%dk", bitrate)
+}
+
+// AudioBitrates - различные аудиобитрейты лестницы по убыванию.
+func AudioBitrates(ladder []Rendition) []int {
+	seen := make(map[int]bool)
+	bitrates := make([]int, 0)
+	for _, r := range ladder {
+		if !seen[r.AudioBitrate] {
+			seen[r.AudioBitrate] = true
+			bitrates = append(bitrates, r.AudioBitrate)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(bitrates)))
+	return bitrates
+}
+
+// Fit оставляет варианты не выше исходника: апскейл только тратит место.
+// Если исходник ниже всей лестницы, остается самый низкий вариант.
+func Fit(ladder []Rendition, sourceHeight int) []Rendition {
+	fitted := make([]Rendition, 0, len(ladder))
+	for _, r := range ladder {
+		if r.Height <= sourceHeight {
+			fitted = append(fitted, r)
+		}
+	}
+	if len(fitted) == 0 && len(ladder) > 0 {
+		fitted = append(fitted, ladder[len(ladder)-1])
+	}
+	return fitted
+}
@@ -0,0 +1,97 @@
+package hls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLadder(t *testing.T) {
+	ladder, err := ParseLadder(" 480p:854x480:1400:96, 1080p:1920x1080:5000:192,,720p:1280x720:2800:128 ")
+	if err != nil {
+		t.Fatalf("ParseLadder: %v", err)
+	}
+
+	want := []Rendition{
+		{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+		{Name: "480p", Width: 854, Height: 480, VideoBitrate: 1400, AudioBitrate: 96},
+	}
+	if !reflect.DeepEqual(ladder, want) {
+		t.Errorf("ParseLadder = %+v, want %+v", ladder, want)
+	}
+}
+
+func TestParseLadderErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		" , ",
+		"720p:1280x720:2800",
+		"720p:1280x720:2800:128:1",
+		"720P:1280x720:2800:128",
+		"720p:1280x720:2800:128,720p:1280x720:2800:128",
+		"720p:1280:2800:128",
+		"720p:1279x720:2800:128",
+		"720p:1280x719:2800:128",
+		"720p:0x720:2800:128",
+		"720p:1280x720:0:128",
+		"720p:1280x720:2800:-1",
+		"720p:1280x720:fast:128",
+	} {
+		if _, err := ParseLadder(spec); err == nil {
+			t.Errorf("ParseLadder(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		height int
+		want   []string
+	}{
+		{height: 2160, want: []string{"1080p", "720p", "480p", "360p"}},
+		{height: 1080, want: []string{"1080p", "720p", "480p", "360p"}},
+		{height: 800, want: []string{"720p", "480p", "360p"}},
+		{height: 360, want: []string{"360p"}},
+		// Исходник ниже всей лестницы: остается самый низкий вариант
+		{height: 240, want: []string{"360p"}},
+	}
+
+	for _, tt := range tests {
+		names := make([]string, 0)
+		for _, r := range Fit(testLadder, tt.height) {
+			names = append(names, r.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("Fit(%d) = %v, want %v", tt.height, names, tt.want)
+		}
+	}
+
+	if fitted := Fit(nil, 1080); len(fitted) != 0 {
+		t.Errorf("Fit of empty ladder = %v, want empty", fitted)
+	}
+}
+
+func TestAudioBitrates(t *testing.T) {
+	if got, want := AudioBitrates(testLadder), []int{192, 128, 96}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AudioBitrates = %v, want %v", got, want)
+	}
+}
+
+func TestRenditionCodec(t *testing.T) {
+	tests := []struct {
+		height int
+		codec  string
+	}{
+		{360, "avc1.4d401e"},
+		{480, "avc1.4d401e"},
+		{720, "avc1.4d401f"},
+		{1080, "avc1.4d4028"},
+		{2160, "avc1.4d4033"},
+	}
+
+	for _, tt := range tests {
+		if got := (Rendition{Height: tt.height}).VideoCodec(); got != tt.codec {
+			t.Errorf("VideoCodec for %dp = %s, want %s", tt.height, got, tt.codec)
+		}
+	}
+}
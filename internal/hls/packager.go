@@ -0,0 +1,315 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"streaming-service/internal/dash"
+	"streaming-service/internal/storage"
+)
+
+type Options struct {
+	Ladder  []Rendition
+	Segment SegmentOptions
+	// WorkDir - каталог для временных файлов: исходника и сегментов до загрузки в хранилище
+	WorkDir string
+}
+
+// Packager нарезает готовый медиафайл на варианты лестницы и выкладывает пакет в хранилище:
+// <prefix>/master.m3u8, <prefix>/manifest.mpd и <prefix>/<дорожка>/{index.m3u8,init.mp4,seg_NNNNN.*}.
+type Packager struct {
+	transcoder Transcoder
+	blobs      storage.BlobStore
+	opts       Options
+}
+
+func NewPackager(transcoder Transcoder, blobs storage.BlobStore, opts Options) (*Packager, error) {
+	if len(opts.Ladder) == 0 {
+		return nil, errors.New("rendition ladder is empty")
+	}
+	if opts.Segment.Format != SegmentTS && opts.Segment.Format != SegmentFMP4 {
+		return nil, errors.Errorf("unknown segment format %q", opts.Segment.Format)
+	}
+	if opts.Segment.Duration <= 0 {
+		return nil, errors.New("segment duration must be positive")
+	}
+	if err := os.MkdirAll(opts.WorkDir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create packaging directory")
+	}
+
+	return &Packager{transcoder: transcoder, blobs: blobs, opts: opts}, nil
+}
+
+// Package упаковывает объект sourceKey под префиксом prefix и возвращает состав пакета.
+// Для fMP4 рядом с master.m3u8 выкладывается манифест DASH из тех же сегментов.
+// master.m3u8 записывается последним, поэтому его наличие означает, что пакет собран целиком.
+// progress вызывается после каждой готовой дорожки с долей выполненной работы в процентах.
+func (p *Packager) Package(ctx context.Context, sourceKey, prefix string, progress func(percent int)) (*Package, error) {
+	workDir, err := os.MkdirTemp(p.opts.WorkDir, "package-*")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create packaging directory")
+	}
+	defer os.RemoveAll(workDir)
+
+	input := filepath.Join(workDir, "source")
+	if err := p.download(ctx, sourceKey, input); err != nil {
+		return nil, err
+	}
+
+	source, err := p.transcoder.Probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	ladder := Fit(p.opts.Ladder, source.Height)
+
+	// Шаги: загрузка исходника, дорожки видео, аудио и субтитров
+	total, done := 1+len(ladder)+len(source.Subtitles), 1
+	if source.HasAudio {
+		total += len(AudioBitrates(ladder))
+	}
+	step := func() {
+		done++
+		progress(done * 100 / total)
+	}
+	progress(done * 100 / total)
+
+	pkg := &Package{Format: p.opts.Segment.Format}
+
+	for _, r := range ladder {
+		segments, err := p.track(ctx, workDir, prefix, r.Name, func(outDir string) error {
+			return p.transcoder.TranscodeVideo(ctx, input, outDir, r, p.opts.Segment)
+		})
+		if err != nil {
+			return nil, err
+		}
+		pkg.Video = append(pkg.Video, VideoTrack{Rendition: r, Segments: segments})
+		step()
+	}
+
+	for _, segment := range pkg.Video[0].Segments {
+		pkg.Duration += segment
+	}
+
+	if source.HasAudio {
+		for _, bitrate := range AudioBitrates(ladder) {
+			name := audioName(bitrate)
+			segments, err := p.track(ctx, workDir, prefix, name, func(outDir string) error {
+				return p.transcoder.TranscodeAudio(ctx, input, outDir, bitrate, p.opts.Segment)
+			})
+			if err != nil {
+				return nil, err
+			}
+			pkg.Audio = append(pkg.Audio, AudioTrack{Name: name, Bitrate: bitrate, Segments: segments})
+			step()
+		}
+	}
+
+	for _, stream := range source.Subtitles {
+		track := SubtitleTrack{Name: fmt.Sprintf("text_%d", stream.Index), Language: stream.Language}
+		if err := p.subtitles(ctx, input, workDir, prefix, track, stream.Index, pkg.Duration); err != nil {
+			return nil, err
+		}
+		pkg.Subtitles = append(pkg.Subtitles, track)
+		step()
+	}
+
+	if pkg.Format == SegmentFMP4 {
+		manifest, err := dash.Generate(p.dashManifest(pkg))
+		if err != nil {
+			return nil, err
+		}
+		if err := p.put(ctx, path.Join(prefix, dash.ManifestName), manifest, dash.ContentType(dash.ManifestName)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.put(ctx, path.Join(prefix, MasterPlaylist), []byte(Master(*pkg)), ContentType(MasterPlaylist)); err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+// track нарезает одну дорожку в каталог name, выкладывает ее и возвращает длительности сегментов.
+func (p *Packager) track(ctx context.Context, workDir, prefix, name string, transcode func(outDir string) error) ([]time.Duration, error) {
+	outDir := filepath.Join(workDir, name)
+	if err := os.Mkdir(outDir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create track directory")
+	}
+
+	if err := transcode(outDir); err != nil {
+		return nil, err
+	}
+
+	playlist, err := os.ReadFile(filepath.Join(outDir, MediaPlaylist))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read playlist of %s", name)
+	}
+	segments, err := SegmentDurations(playlist)
+	if err != nil {
+		return nil, errors.Wrapf(err, "playlist of %s", name)
+	}
+
+	if err := p.upload(ctx, outDir, path.Join(prefix, name)); err != nil {
+		return nil, err
+	}
+
+	// Сегменты уже в хранилище, место на диске больше не нужно
+	if err := os.RemoveAll(outDir); err != nil {
+		return nil, errors.Wrap(err, "failed to clean track directory")
+	}
+	return segments, nil
+}
+
+func (p *Packager) subtitles(ctx context.Context, input, workDir, prefix string, track SubtitleTrack, stream int, duration time.Duration) error {
+	outDir := filepath.Join(workDir, track.Name)
+	if err := os.Mkdir(outDir, 0o750); err != nil {
+		return errors.Wrap(err, "failed to create track directory")
+	}
+
+	if err := p.transcoder.ExtractSubtitles(ctx, input, stream, filepath.Join(outDir, SubtitlesFile)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, MediaPlaylist), []byte(SubtitlesPlaylist(duration)), 0o640); err != nil {
+		return errors.Wrap(err, "failed to write subtitles playlist")
+	}
+
+	return p.upload(ctx, outDir, path.Join(prefix, track.Name))
+}
+
+// dashManifest описывает пакет для DASH: пути сегментов те же, что у HLS, относительно корня пакета.
+func (p *Packager) dashManifest(pkg *Package) dash.Manifest {
+	manifest := dash.Manifest{
+		Duration:      pkg.Duration,
+		MinBufferTime: 2 * p.opts.Segment.Duration,
+	}
+
+	segments := func(durations []time.Duration) dash.Segments {
+		return dash.Segments{
+			Initialization: "$RepresentationID$/" + InitSegment,
+			Media:          "$RepresentationID$/seg_$Number%05d$" + segmentExtension(pkg.Format),
+			StartNumber:    0,
+			Durations:      durations,
+		}
+	}
+
+	for _, track := range pkg.Video {
+		manifest.Video = append(manifest.Video, dash.Video{
+			ID:        track.Name,
+			Width:     track.Width,
+			Height:    track.Height,
+			Bandwidth: track.VideoBandwidth(),
+			Codecs:    track.VideoCodec(),
+			Segments:  segments(track.Segments),
+		})
+	}
+	for _, track := range pkg.Audio {
+		manifest.Audio = append(manifest.Audio, dash.Audio{
+			ID:         track.Name,
+			Bandwidth:  track.Bitrate * 1000,
+			Codecs:     AudioCodec,
+			Channels:   AudioChannels,
+			SampleRate: AudioSampleRate,
+			Segments:   segments(track.Segments),
+		})
+	}
+	for _, track := range pkg.Subtitles {
+		manifest.Text = append(manifest.Text, dash.Text{
+			ID:       track.Name,
+			Language: track.Language,
+			URL:      track.Name + "/" + SubtitlesFile,
+		})
+	}
+
+	return manifest
+}
+
+// Remove удаляет пакет целиком.
+func (p *Packager) Remove(ctx context.Context, prefix string) error {
+	objects, err := p.blobs.List(ctx, prefix+"/")
+	if err != nil {
+		return err
+	}
+
+	// Сначала master.m3u8, чтобы недоудаленный пакет не считался собранным
+	if err := p.blobs.Delete(ctx, path.Join(prefix, MasterPlaylist)); err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := p.blobs.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Packager) download(ctx context.Context, key, name string) error {
+	object, err := p.blobs.Get(ctx, key, 0, -1)
+	if err != nil {
+		return errors.Wrap(err, "failed to read source")
+	}
+	defer object.Close()
+
+	file, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to create source file")
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, object); err != nil {
+		return errors.Wrap(err, "failed to download source")
+	}
+	return file.Close()
+}
+
+// upload выкладывает файлы варианта; плейлист - последним, после всех сегментов, на которые он ссылается.
+func (p *Packager) upload(ctx context.Context, dir, prefix string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read rendition directory")
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == MediaPlaylist || ContentType(entry.Name()) == "" {
+			continue
+		}
+		if err := p.putFile(ctx, filepath.Join(dir, entry.Name()), path.Join(prefix, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return p.putFile(ctx, filepath.Join(dir, MediaPlaylist), path.Join(prefix, MediaPlaylist))
+}
+
+func (p *Packager) putFile(ctx context.Context, name, key string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to open packaged file")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrap(err, "failed to stat packaged file")
+	}
+
+	if err := p.blobs.Put(ctx, key, file, info.Size(), ContentType(key)); err != nil {
+		return errors.Wrapf(err, "failed to store %s", key)
+	}
+	return nil
+}
+
+func (p *Packager) put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := p.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return errors.Wrapf(err, "failed to store %s", key)
+	}
+	return nil
+}
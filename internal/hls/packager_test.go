@@ -0,0 +1,230 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"streaming-service/internal/dash"
+	"streaming-service/internal/storage"
+)
+
+// fakeTranscoder вместо ffmpeg пишет плейлист и пустые сегменты заданной длительности.
+type fakeTranscoder struct {
+	source   Source
+	segments []time.Duration
+	// fail - имя дорожки, нарезка которой завершается ошибкой
+	fail string
+}
+
+func (f *fakeTranscoder) Probe(context.Context, string) (*Source, error) {
+	source := f.source
+	return &source, nil
+}
+
+func (f *fakeTranscoder) TranscodeVideo(_ context.Context, _, outDir string, r Rendition, opts SegmentOptions) error {
+	return f.write(outDir, r.Name, opts)
+}
+
+func (f *fakeTranscoder) TranscodeAudio(_ context.Context, _, outDir string, bitrate int, opts SegmentOptions) error {
+	return f.write(outDir, audioName(bitrate), opts)
+}
+
+func (f *fakeTranscoder) ExtractSubtitles(_ context.Context, _ string, stream int, output string) error {
+	return os.WriteFile(output, []byte(fmt.Sprintf("WEBVTT\n\n00:00.000 --> 00:01.000\nstream %d\n", stream)), 0o640)
+}
+
+func (f *fakeTranscoder) write(outDir, name string, opts SegmentOptions) error {
+	if name == f.fail {
+		return errors.Errorf("transcoding %s failed", name)
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-TARGETDURATION:6\n")
+	if opts.Format == SegmentFMP4 {
+		if err := os.WriteFile(filepath.Join(outDir, InitSegment), []byte("init"), 0o640); err != nil {
+			return err
+		}
+		fmt.Fprintf(&playlist, "#EXT-X-MAP:URI=\"%s\"\n", InitSegment)
+	}
+	for i, duration := range f.segments {
+		segment := fmt.Sprintf(segmentTemplate, i) + segmentExtension(opts.Format)
+		if err := os.WriteFile(filepath.Join(outDir, segment), []byte(name), 0o640); err != nil {
+			return err
+		}
+		fmt.Fprintf(&playlist, "#EXTINF:%.3f,\n%s\n", duration.Seconds(), segment)
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+	return os.WriteFile(filepath.Join(outDir, MediaPlaylist), []byte(playlist.String()), 0o640)
+}
+
+// recordingStore запоминает порядок и MIME-типы записанных объектов.
+type recordingStore struct {
+	storage.BlobStore
+	keys         []string
+	contentTypes map[string]string
+}
+
+func (s *recordingStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	s.keys = append(s.keys, key)
+	s.contentTypes[key] = contentType
+	return s.BlobStore.Put(ctx, key, body, size, contentType)
+}
+
+var testLadder = []Rendition{
+	{Name: "1080p", Width: 1920, Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	{Name: "720p", Width: 1280, Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Width: 854, Height: 480, VideoBitrate: 1400, AudioBitrate: 96},
+	{Name: "360p", Width: 640, Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+func newTestPackager(t *testing.T, transcoder Transcoder, format string) (*Packager, *recordingStore) {
+	t.Helper()
+
+	fs, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &recordingStore{BlobStore: fs, contentTypes: make(map[string]string)}
+	if err := store.BlobStore.Put(context.Background(), "assets/source", strings.NewReader("source"), 6, "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+
+	packager, err := NewPackager(transcoder, store, Options{
+		Ladder:  testLadder,
+		Segment: SegmentOptions{Format: format, Duration: 6 * time.Second},
+		WorkDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packager, store
+}
+
+func TestPackage(t *testing.T) {
+	transcoder := &fakeTranscoder{
+		source: Source{Width: 1280, Height: 720, HasAudio: true, Subtitles: []SubtitleStream{
+			{Index: 3, Language: "en"},
+		}},
+		segments: []time.Duration{6 * time.Second, 6 * time.Second, 2500 * time.Millisecond},
+	}
+	packager, store := newTestPackager(t, transcoder, SegmentFMP4)
+
+	var progress []int
+	pkg, err := packager.Package(context.Background(), "assets/source", "packages/a", func(percent int) {
+		progress = append(progress, percent)
+	})
+	if err != nil {
+		t.Fatalf("Package: %v", err)
+	}
+
+	// Исходник 720p: 1080p отбрасывается, остаются три варианта с двумя аудиобитрейтами
+	names := make([]string, 0)
+	for _, track := range pkg.Video {
+		names = append(names, track.Name)
+	}
+	if want := []string{"720p", "480p", "360p"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("video tracks = %v, want %v", names, want)
+	}
+	if len(pkg.Audio) != 2 || pkg.Audio[0].Name != "audio_128k" || pkg.Audio[1].Name != "audio_96k" {
+		t.Errorf("audio tracks = %+v, want audio_128k and audio_96k", pkg.Audio)
+	}
+	if pkg.Duration != 14500*time.Millisecond {
+		t.Errorf("duration = %v, want 14.5s", pkg.Duration)
+	}
+
+	// Шаги: загрузка исходника, 3 видео, 2 аудио и 1 субтитры
+	if want := []int{14, 28, 42, 57, 71, 85, 100}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+
+	keys := store.keys
+	if last := keys[len(keys)-1]; last != "packages/a/"+MasterPlaylist {
+		t.Errorf("last uploaded object = %s, want master playlist", last)
+	}
+	if keys[len(keys)-2] != "packages/a/"+dash.ManifestName {
+		t.Errorf("DASH manifest must be uploaded right before master playlist, got %v", keys)
+	}
+
+	// Плейлист дорожки выкладывается после всех ее файлов
+	uploaded := make(map[string]int)
+	for i, key := range keys {
+		uploaded[key] = i
+	}
+	for _, track := range []string{"720p", "480p", "360p", "audio_128k", "audio_96k", "text_3"} {
+		playlist, ok := uploaded["packages/a/"+track+"/"+MediaPlaylist]
+		if !ok {
+			t.Errorf("playlist of %s is not uploaded", track)
+			continue
+		}
+		files := 0
+		for key, i := range uploaded {
+			if path.Dir(key) == "packages/a/"+track && path.Base(key) != MediaPlaylist {
+				files++
+				if i > playlist {
+					t.Errorf("%s is uploaded after its playlist", key)
+				}
+			}
+		}
+		if files == 0 {
+			t.Errorf("track %s has no uploaded files", track)
+		}
+	}
+
+	for key, want := range map[string]string{
+		"packages/a/" + MasterPlaylist:          "application/vnd.apple.mpegurl",
+		"packages/a/" + dash.ManifestName:       "application/dash+xml",
+		"packages/a/720p/" + InitSegment:        "video/mp4",
+		"packages/a/720p/seg_00000.m4s":         "video/iso.segment",
+		"packages/a/text_3/" + SubtitlesFile:    "text/vtt",
+		"packages/a/audio_96k/" + MediaPlaylist: "application/vnd.apple.mpegurl",
+	} {
+		if got := store.contentTypes[key]; got != want {
+			t.Errorf("content type of %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestPackageTS(t *testing.T) {
+	transcoder := &fakeTranscoder{
+		source:   Source{Width: 640, Height: 360},
+		segments: []time.Duration{6 * time.Second},
+	}
+	packager, store := newTestPackager(t, transcoder, SegmentTS)
+
+	if _, err := packager.Package(context.Background(), "assets/source", "packages/b", func(int) {}); err != nil {
+		t.Fatalf("Package: %v", err)
+	}
+
+	want := []string{"packages/b/360p/seg_00000.ts", "packages/b/360p/" + MediaPlaylist, "packages/b/" + MasterPlaylist}
+	if !reflect.DeepEqual(store.keys, want) {
+		t.Errorf("uploaded objects = %v, want %v", store.keys, want)
+	}
+}
+
+func TestPackageFailureLeavesNoMaster(t *testing.T) {
+	transcoder := &fakeTranscoder{
+		source:   Source{Width: 1920, Height: 1080, HasAudio: true},
+		segments: []time.Duration{6 * time.Second},
+		fail:     "audio_96k",
+	}
+	packager, store := newTestPackager(t, transcoder, SegmentFMP4)
+
+	if _, err := packager.Package(context.Background(), "assets/source", "packages/c", func(int) {}); err == nil {
+		t.Fatal("Package succeeded, want error")
+	}
+	for _, key := range store.keys {
+		if path.Base(key) == MasterPlaylist || path.Base(key) == dash.ManifestName {
+			t.Errorf("%s is uploaded for a failed package", key)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Имена файлов пакета: master.m3u8 в корне, у каждой дорожки свой каталог <name>/
+const (
+	MasterPlaylist  = "master.m3u8"
+	MediaPlaylist   = "index.m3u8"
+	InitSegment     = "init.mp4"
+	SubtitlesFile   = "subtitles.vtt"
+	segmentTemplate = "seg_%05d"
+	subtitlesGroup  = "subs"
+)
+
+// Package - состав собранного пакета; по нему строятся главный плейлист HLS и манифест DASH.
+type Package struct {
+	Format    string
+	Duration  time.Duration
+	Video     []VideoTrack
+	Audio     []AudioTrack
+	Subtitles []SubtitleTrack
+}
+
+type VideoTrack struct {
+	Rendition
+	Segments []time.Duration
+}
+
+type AudioTrack struct {
+	Name     string
+	Bitrate  int
+	Segments []time.Duration
+}
+
+type SubtitleTrack struct {
+	Name     string
+	Language string
+}
+
+// Master строит главный плейлист: варианты видео ссылаются на группу аудио своего битрейта
+// и на общую группу субтитров. Пути - относительно самого плейлиста.
+func Master(pkg Package) string {
+	// fMP4-сегменты требуют EXT-X-MAP, то есть версии протокола не ниже 7
+	version := 3
+	if pkg.Format == SegmentFMP4 {
+		version = 7
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&playlist, "#EXT-X-VERSION:%d\n", version)
+	playlist.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	audio := make(map[string]AudioTrack, len(pkg.Audio))
+	for _, track := range pkg.Audio {
+		audio[track.Name] = track
+		fmt.Fprintf(&playlist, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"default\",DEFAULT=YES,AUTOSELECT=YES,"+
+			"CHANNELS=\"%d\",URI=\"%s/%s\"\n", track.Name, AudioChannels, track.Name, MediaPlaylist)
+	}
+
+	// NAME должен быть уникален в группе, поэтому повторные дорожки одного языка нумеруются
+	languages := make(map[string]int)
+	for _, track := range pkg.Subtitles {
+		language := track.Language
+		if language == "" {
+			language = "und"
+		}
+		languages[language]++
+
+		name := language
+		if languages[language] > 1 {
+			name = fmt.Sprintf("%s (%d)", language, languages[language])
+		}
+		fmt.Fprintf(&playlist, "#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"%s\",NAME=\"%s\",LANGUAGE=\"%s\",DEFAULT=NO,"+
+			"AUTOSELECT=YES,URI=\"%s/%s\"\n", subtitlesGroup, name, language, track.Name, MediaPlaylist)
+	}
+
+	for _, track := range pkg.Video {
+		bandwidth, average := track.VideoBandwidth(), track.VideoBitrate*1000
+		codecs := track.VideoCodec()
+
+		attributes := ""
+		if a, ok := audio[track.AudioName()]; ok {
+			bandwidth += a.Bitrate * 1000
+			average += a.Bitrate * 1000
+			codecs += "," + AudioCodec
+			attributes += fmt.Sprintf(",AUDIO=\"%s\"", a.Name)
+		}
+		if len(pkg.Subtitles) > 0 {
+			attributes += fmt.Sprintf(",SUBTITLES=\"%s\"", subtitlesGroup)
+		}
+
+		fmt.Fprintf(&playlist, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\"%s\n",
+			bandwidth, average, track.Width, track.Height, codecs, attributes)
+		playlist.WriteString(track.Name + "/" + MediaPlaylist + "\n")
+	}
+
+	return playlist.String()
+}
+
+// SubtitlesPlaylist - плейлист из одного файла WebVTT на всю длительность.
+func SubtitlesPlaylist(duration time.Duration) string {
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(duration.Seconds())))
+	playlist.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	fmt.Fprintf(&playlist, "#EXTINF:%.3f,\n%s\n", duration.Seconds(), SubtitlesFile)
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+	return playlist.String()
+}
+
+// SegmentDurations читает длительности сегментов (#EXTINF) из плейлиста варианта.
+func SegmentDurations(playlist []byte) ([]time.Duration, error) {
+	durations := make([]time.Duration, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "#EXTINF:")
+		if !ok {
+			continue
+		}
+
+		value, _, _ = strings.Cut(value, ",")
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || seconds <= 0 {
+			return nil, errors.Errorf("invalid segment duration %q", value)
+		}
+		durations = append(durations, time.Duration(seconds*float64(time.Second)))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read playlist")
+	}
+	if len(durations) == 0 {
+		return nil, errors.New("playlist has no segments")
+	}
+	return durations, nil
+}
+
+// segmentExtension - расширение медиасегментов данного формата.
+func segmentExtension(segmentFormat string) string {
+	if segmentFormat == SegmentFMP4 {
+		return ".m4s"
+	}
+	return ".ts"
+}
+
+// ContentType - MIME-тип файла HLS-пакета по имени; пустая строка - файл пакету не принадлежит.
+func ContentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".m3u8"):
+		return "application/vnd.apple.mpegurl"
+	case strings.HasSuffix(name, ".ts"):
+		return "video/mp2t"
+	case strings.HasSuffix(name, ".m4s"):
+		return "video/iso.segment"
+	case strings.HasSuffix(name, ".mp4"):
+		return "video/mp4"
+	case strings.HasSuffix(name, ".vtt"):
+		return "text/vtt"
+	default:
+		return ""
+	}
+}
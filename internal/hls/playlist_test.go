@@ -0,0 +1,122 @@
+package hls
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMaster(t *testing.T) {
+	video := []VideoTrack{{Rendition: testLadder[1]}, {Rendition: testLadder[3]}}
+
+	tests := []struct {
+		name string
+		pkg  Package
+		want string
+	}{
+		{
+			name: "video only",
+			pkg:  Package{Format: SegmentTS, Video: video},
+			want: "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2996000,AVERAGE-BANDWIDTH=2800000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f\"\n" +
+				"720p/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=856000,AVERAGE-BANDWIDTH=800000,RESOLUTION=640x360,CODECS=\"avc1.4d401e\"\n" +
+				"360p/index.m3u8\n",
+		},
+		{
+			name: "audio and subtitles",
+			pkg: Package{
+				Format: SegmentFMP4,
+				Video:  video,
+				Audio:  []AudioTrack{{Name: "audio_128k", Bitrate: 128}, {Name: "audio_96k", Bitrate: 96}},
+				Subtitles: []SubtitleTrack{
+					{Name: "text_3", Language: "en"},
+					{Name: "text_4", Language: "en"},
+					{Name: "text_5"},
+				},
+			},
+			want: "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-INDEPENDENT-SEGMENTS\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio_128k\",NAME=\"default\",DEFAULT=YES,AUTOSELECT=YES,CHANNELS=\"2\",URI=\"audio_128k/index.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio_96k\",NAME=\"default\",DEFAULT=YES,AUTOSELECT=YES,CHANNELS=\"2\",URI=\"audio_96k/index.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"en\",LANGUAGE=\"en\",DEFAULT=NO,AUTOSELECT=YES,URI=\"text_3/index.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"en (2)\",LANGUAGE=\"en\",DEFAULT=NO,AUTOSELECT=YES,URI=\"text_4/index.m3u8\"\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"und\",LANGUAGE=\"und\",DEFAULT=NO,AUTOSELECT=YES,URI=\"text_5/index.m3u8\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=3124000,AVERAGE-BANDWIDTH=2928000,RESOLUTION=1280x720,CODECS=\"avc1.4d401f,mp4a.40.2\",AUDIO=\"audio_128k\",SUBTITLES=\"subs\"\n" +
+				"720p/index.m3u8\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=952000,AVERAGE-BANDWIDTH=896000,RESOLUTION=640x360,CODECS=\"avc1.4d401e,mp4a.40.2\",AUDIO=\"audio_96k\",SUBTITLES=\"subs\"\n" +
+				"360p/index.m3u8\n",
+		},
+		{
+			name: "subtitles without audio",
+			pkg: Package{
+				Format:    SegmentFMP4,
+				Video:     video[1:],
+				Subtitles: []SubtitleTrack{{Name: "text_2", Language: "fr"}},
+			},
+			want: "#EXTM3U\n#EXT-X-VERSION:7\n#EXT-X-INDEPENDENT-SEGMENTS\n" +
+				"#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=\"fr\",LANGUAGE=\"fr\",DEFAULT=NO,AUTOSELECT=YES,URI=\"text_2/index.m3u8\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=856000,AVERAGE-BANDWIDTH=800000,RESOLUTION=640x360,CODECS=\"avc1.4d401e\",SUBTITLES=\"subs\"\n" +
+				"360p/index.m3u8\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Master(tt.pkg); got != tt.want {
+				t.Errorf("Master() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtitlesPlaylist(t *testing.T) {
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:15\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+		"#EXTINF:14.500,\nsubtitles.vtt\n#EXT-X-ENDLIST\n"
+	if got := SubtitlesPlaylist(14500 * time.Millisecond); got != want {
+		t.Errorf("SubtitlesPlaylist() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSegmentDurations(t *testing.T) {
+	playlist := "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:6.000000,\nseg_00000.m4s\n" +
+		"  #EXTINF:2.5  \nseg_00001.m4s\n#EXT-X-ENDLIST\n"
+
+	got, err := SegmentDurations([]byte(playlist))
+	if err != nil {
+		t.Fatalf("SegmentDurations: %v", err)
+	}
+	if want := []time.Duration{6 * time.Second, 2500 * time.Millisecond}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SegmentDurations = %v, want %v", got, want)
+	}
+}
+
+func TestSegmentDurationsErrors(t *testing.T) {
+	for name, playlist := range map[string]string{
+		"empty":        "",
+		"no segments":  "#EXTM3U\n#EXT-X-ENDLIST\n",
+		"not a number": "#EXTM3U\n#EXTINF:six,\nseg_00000.ts\n",
+		"missing":      "#EXTM3U\n#EXTINF:,\nseg_00000.ts\n",
+		"zero":         "#EXTM3U\n#EXTINF:0,\nseg_00000.ts\n",
+		"negative":     "#EXTM3U\n#EXTINF:6,\nseg_00000.ts\n#EXTINF:-1,\nseg_00001.ts\n",
+	} {
+		if _, err := SegmentDurations([]byte(playlist)); err == nil {
+			t.Errorf("SegmentDurations(%s) succeeded, want error", name)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	for name, want := range map[string]string{
+		"master.m3u8":       "application/vnd.apple.mpegurl",
+		"720p/seg_00001.ts": "video/mp2t",
+		"seg_00001.m4s":     "video/iso.segment",
+		"init.mp4":          "video/mp4",
+		"subtitles.vtt":     "text/vtt",
+		"manifest.mpd":      "",
+		"../source":         "",
+	} {
+		if got := ContentType(name); got != want {
+			t.Errorf("ContentType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
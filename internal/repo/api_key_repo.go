@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	apiKeyColumns     = `uuid, owner_id, name, prefix, key_hash, scopes, created_at, rotated_at, last_used_at, revoked_at`
+	insertAPIKeyQuery = `INSERT INTO api_keys (uuid, owner_id, name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4, $5, $6) RETURNING uuid`
+	getAPIKeysQuery   = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE owner_id = $1 ORDER BY created_at`
+	// Ключи удаленного владельца не действуют, пока он в корзине
+	getAPIKeyByHashQuery = `SELECT ` + apiKeyColumns + ` FROM api_keys k WHERE key_hash = $1
+		AND EXISTS (SELECT 1 FROM owners o WHERE o.uuid = k.owner_id AND o.deleted_at IS NULL)`
+	rotateAPIKeyQuery = `UPDATE api_keys SET prefix = $1, key_hash = $2, rotated_at = now() WHERE uuid = $3 AND owner_id = $4 AND revoked_at IS NULL`
+	revokeAPIKeyQuery = `UPDATE api_keys SET revoked_at = now() WHERE uuid = $1 AND owner_id = $2 AND revoked_at IS NULL`
+	touchAPIKeyQuery  = `UPDATE api_keys SET last_used_at = now() WHERE uuid = $1`
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) (string, error)
+	GetAPIKeys(ctx context.Context, ownerID string) ([]*APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	RotateAPIKey(ctx context.Context, ownerID, uuid, prefix, hash string) error
+	RevokeAPIKey(ctx context.Context, ownerID, uuid string) error
+	TouchAPIKey(ctx context.Context, uuid string) error
+}
+
+func (r *repository) CreateAPIKey(ctx context.Context, key *APIKey) (string, error) {
+	uuid := uuid.New().String()
+
+	err := r.db.QueryRow(ctx, insertAPIKeyQuery, uuid, key.OwnerID, key.Name, key.Prefix, key.KeyHash, key.Scopes).Scan(&uuid)
+	if err != nil {
+		return "", errors.Wrap(translate(err, "api key"), "failed to insert api key")
+	}
+	return uuid, nil
+}
+
+func (r *repository) GetAPIKeys(ctx context.Context, ownerID string) ([]*APIKey, error) {
+	if err := checkUUID("api key", ownerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getAPIKeysQuery, ownerID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "api key"), "failed to query api keys")
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan api key row")
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over api key rows")
+	}
+
+	return keys, nil
+}
+
+func (r *repository) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRow(ctx, getAPIKeyByHashQuery, hash))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "api key"), "failed to query api key")
+	}
+
+	return key, nil
+}
+
+func (r *repository) RotateAPIKey(ctx context.Context, ownerID, uuid, prefix, hash string) error {
+	if err := checkUUID("api key", ownerID, uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, rotateAPIKeyQuery, prefix, hash, uuid, ownerID)
+	if err != nil {
+		return errors.Wrap(translate(err, "api key"), "failed to execute rotate query")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("api key"), "no rows updated, active api key with given UUID not found")
+	}
+
+	return nil
+}
+
+func (r *repository) RevokeAPIKey(ctx context.Context, ownerID, uuid string) error {
+	if err := checkUUID("api key", ownerID, uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, revokeAPIKeyQuery, uuid, ownerID)
+	if err != nil {
+		return errors.Wrap(translate(err, "api key"), "failed to execute revoke query")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("api key"), "no rows updated, active api key with given UUID not found")
+	}
+
+	return nil
+}
+
+func (r *repository) TouchAPIKey(ctx context.Context, uuid string) error {
+	if _, err := r.db.Exec(ctx, touchAPIKeyQuery, uuid); err != nil {
+		return errors.Wrap(translate(err, "api key"), "failed to execute touch query")
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var key APIKey
+
+	err := row.Scan(&key.UUID, &key.OwnerID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.Created_at, &key.RotatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
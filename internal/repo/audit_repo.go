@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+
+	insertAuditEventQuery = `INSERT INTO audit_events (uuid, actor, action, entity_type, entity_id, before, after, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	auditEventColumns = `uuid, actor, action, entity_type, entity_id, before, after, request_id, created_at`
+)
+
+var auditSchema = Schema{
+	Table: "audit_events",
+	Fields: map[string]Field{
+		"entity_type": {Column: "entity_type", Type: "text"},
+		"entity_id":   {Column: "entity_id", Type: "uuid"},
+		"actor":       {Column: "actor", Type: "text"},
+		"action":      {Column: "action", Type: "text"},
+		"created_at": {Column: "created_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*AuditEvent).Created_at)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "created_at", Desc: true}},
+}
+
+// AuditSchema - поля журнала изменений, доступные для фильтрации и сортировки.
+func AuditSchema() Schema {
+	return auditSchema
+}
+
+// AuditContext - кто и в рамках какого запроса меняет данные.
+type AuditContext struct {
+	Actor     string
+	RequestID string
+}
+
+type auditContextKey struct{}
+
+// WithAuditContext прикрепляет к ctx сведения для журнала изменений.
+func WithAuditContext(ctx context.Context, audit AuditContext) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, audit)
+}
+
+// auditContextFrom достает сведения для журнала; изменения без них (фоновые задачи) записываются от имени system.
+func auditContextFrom(ctx context.Context) AuditContext {
+	audit, _ := ctx.Value(auditContextKey{}).(AuditContext)
+	if audit.Actor == "" {
+		audit.Actor = "system"
+	}
+	return audit
+}
+
+type AuditRepository interface {
+	GetAuditEvents(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*AuditEvent], error)
+}
+
+func (r *repository) GetAuditEvents(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*AuditEvent], error) {
+	events, err := listPage(ctx, r.db, auditSchema, auditEventColumns, spec, page, scanAuditEvent, func(event *AuditEvent) string {
+		return event.UUID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query audit events")
+	}
+
+	return events, nil
+}
+
+// audit записывает событие журнала. Вызывается внутри транзакции изменения, чтобы
+// запись в журнал и само изменение фиксировались или откатывались вместе.
+// before и after - снимки записи до и после (nil, если записи не было или не стало);
+// в журнал попадают только различающиеся поля.
+func (r *repository) audit(ctx context.Context, action, entityType, entityID string, before, after any) error {
+	beforeDiff, afterDiff, err := auditDiff(before, after)
+	if err != nil {
+		return errors.Wrap(err, "failed to build audit diff")
+	}
+
+	audit := auditContextFrom(ctx)
+	_, err = r.db.Exec(ctx, insertAuditEventQuery, uuid.New().String(), audit.Actor, action, entityType, entityID,
+		beforeDiff, afterDiff, audit.RequestID)
+	if err != nil {
+		return errors.Wrap(translate(err, "audit event"), "failed to insert audit event")
+	}
+
+	return nil
+}
+
+func auditDiff(before, after any) (map[string]any, map[string]any, error) {
+	beforeFields, err := snapshotFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := snapshotFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields == nil || afterFields == nil {
+		return beforeFields, afterFields, nil
+	}
+
+	beforeDiff, afterDiff := make(map[string]any), make(map[string]any)
+	for field, value := range afterFields {
+		if old, ok := beforeFields[field]; !ok || !reflect.DeepEqual(old, value) {
+			beforeDiff[field] = beforeFields[field]
+			afterDiff[field] = value
+		}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			beforeDiff[field] = old
+			afterDiff[field] = nil
+		}
+	}
+
+	return beforeDiff, afterDiff, nil
+}
+
+// snapshotFields приводит снимок записи к полям в том виде, в каком они уходят в JSON.
+func snapshotFields(snapshot any) (map[string]any, error) {
+	if snapshot == nil || reflect.ValueOf(snapshot).IsNil() {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func scanAuditEvent(row pgx.Row) (*AuditEvent, error) {
+	var event AuditEvent
+
+	err := row.Scan(&event.UUID, &event.Actor, &event.Action, &event.EntityType, &event.EntityID, &event.Before, &event.After,
+		&event.RequestID, &event.Created_at)
+	if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
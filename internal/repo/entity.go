@@ -1,19 +1,205 @@
 package repo
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Movie struct {
+	UUID        string     `json:"uuid"`
+	OwnerID     string     `json:"owner_id"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	Description string     `json:"description"`
+	Year        int        `json:"year"`
+	Version     int        `json:"version"`
+	Created_at  time.Time  `json:"created_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Owner struct {
+	UUID       string     `json:"uuid"`
+	Name       string     `json:"name"`
+	Version    int        `json:"version"`
+	Created_at time.Time  `json:"created_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+type User struct {
+	UUID         string    `json:"uuid"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	Created_at   time.Time `json:"created_at"`
+}
+
+type Session struct {
+	UUID       string     `json:"uuid"`
+	UserID     string     `json:"user_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Created_at time.Time  `json:"created_at"`
+}
+
+type OwnerMember struct {
+	OwnerID    string    `json:"owner_id"`
+	UserID     string    `json:"user_id"`
+	Role       string    `json:"role"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type APIKey struct {
+	UUID       string     `json:"uuid"`
+	OwnerID    string     `json:"owner_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	Created_at time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type MovieSearchResult struct {
+	Movie          `json:"movie"`
+	Rank           float32 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type Suggestion struct {
+	Type  string `json:"type"`
+	UUID  string `json:"uuid"`
+	Label string `json:"label"`
+}
+
+type TrashItem struct {
+	Type      string    `json:"type"`
+	UUID      string    `json:"uuid"`
+	OwnerID   string    `json:"owner_id"`
+	Label     string    `json:"label"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashPurge - итог очистки корзины. Assets - медиафайлы удаленных фильмов и эпизодов:
+// их файлы в хранилище удаляет вызывающий.
+type TrashPurge struct {
+	Movies int64
+	Owners int64
+	Assets []*MediaAsset
+}
+
+type AuditEvent struct {
+	UUID       string          `json:"uuid"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"request_id"`
+	Created_at time.Time       `json:"created_at"`
+}
+
+type Term struct {
+	UUID       string    `json:"uuid"`
+	Slug       string    `json:"slug"`
+	Name       string    `json:"name"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type Person struct {
+	UUID       string    `json:"uuid"`
+	Name       string    `json:"name"`
+	Bio        string    `json:"bio"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type Credit struct {
+	PersonID     string `json:"person_id"`
+	PersonName   string `json:"person_name,omitempty"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int    `json:"billing_order"`
+}
+
+type FilmographyEntry struct {
+	Movie        Movie  `json:"movie"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int    `json:"billing_order"`
+}
+
+type Series struct {
 	UUID        string    `json:"uuid"`
 	OwnerID     string    `json:"owner_id"`
 	Title       string    `json:"title"`
-	Author      string    `json:"author"`
 	Description string    `json:"description"`
-	Year        int       `json:"year"`
 	Created_at  time.Time `json:"created_at"`
 }
 
-type Owner struct {
-	UUID       string    `json:"uuid"`
-	Name       string    `json:"name"`
-	Created_at time.Time `json:"created_at"`
+type Season struct {
+	UUID       string     `json:"uuid"`
+	SeriesID   string     `json:"series_id"`
+	Number     int        `json:"number"`
+	Title      string     `json:"title"`
+	AirDate    *time.Time `json:"air_date"`
+	Created_at time.Time  `json:"created_at"`
+}
+
+type Episode struct {
+	UUID           string     `json:"uuid"`
+	OwnerID        string     `json:"owner_id"`
+	SeriesID       string     `json:"series_id"`
+	SeasonID       string     `json:"season_id"`
+	SeasonNumber   int        `json:"season_number"`
+	Number         int        `json:"number"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	AirDate        *time.Time `json:"air_date"`
+	RuntimeMinutes *int       `json:"runtime_minutes"`
+	Created_at     time.Time  `json:"created_at"`
+}
+
+// Title - воспроизводимая единица каталога: фильм или эпизод.
+type Title struct {
+	Kind    string `json:"kind"`
+	UUID    string `json:"uuid"`
+	OwnerID string `json:"owner_id"`
+	Label   string `json:"label"`
+}
+
+// MediaAsset - медиафайл фильма или эпизода; пока идет загрузка, Offset меньше Size.
+type MediaAsset struct {
+	UUID        string     `json:"uuid"`
+	TitleKind   string     `json:"title_kind"`
+	TitleID     string     `json:"title_id"`
+	Filename    string     `json:"filename"`
+	MimeType    string     `json:"mime_type"`
+	Size        int64      `json:"size"`
+	Offset      int64      `json:"offset"`
+	Checksum    string     `json:"checksum"`
+	Status      string     `json:"status"`
+	StorageKey  string     `json:"-"`
+	Created_at  time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Job - фоновая задача над медиафайлом. TitleKind и TitleID берутся из медиафайла.
+type Job struct {
+	UUID        string     `json:"uuid"`
+	Kind        string     `json:"kind"`
+	AssetID     string     `json:"asset_id"`
+	TitleKind   string     `json:"title_kind"`
+	TitleID     string     `json:"title_id"`
+	Status      string     `json:"status"`
+	Progress    int        `json:"progress"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	RunAt       time.Time  `json:"run_at"`
+	Created_at  time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
 }
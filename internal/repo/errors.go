@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrInvalidUUID         = errors.New("invalid uuid")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrPreconditionFailed  = errors.New("version mismatch")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+)
+
+// Error - ошибка репозитория с видом (одна из Err* выше) и исходной причиной.
+// errors.Is срабатывает и на вид, и на причину (например, pgx.ErrNoRows).
+type Error struct {
+	Kind       error
+	Entity     string
+	Constraint string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Entity, e.Kind)
+	if e.Constraint != "" {
+		msg += fmt.Sprintf(" (%s)", e.Constraint)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// translate переводит ошибки pgx и коды Postgres в типизированные ошибки репозитория.
+func translate(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Entity: entity, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var kind error
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation, pgerrcode.ExclusionViolation:
+		kind = ErrConflict
+	case pgerrcode.ForeignKeyViolation:
+		kind = ErrForeignKeyViolation
+	case pgerrcode.CheckViolation, pgerrcode.NotNullViolation:
+		kind = ErrCheckViolation
+	case pgerrcode.InvalidTextRepresentation:
+		// Код общий для любых приведений типа, UUID узнается только по тексту ошибки
+		kind = ErrInvalidInput
+		if strings.Contains(pgErr.Message, "type uuid") {
+			kind = ErrInvalidUUID
+		}
+	case pgerrcode.InvalidDatetimeFormat, pgerrcode.DatetimeFieldOverflow, pgerrcode.NumericValueOutOfRange:
+		kind = ErrInvalidInput
+	default:
+		return err
+	}
+
+	return &Error{Kind: kind, Entity: entity, Constraint: pgErr.ConstraintName, Err: err}
+}
+
+func notFound(entity string) error {
+	return &Error{Kind: ErrNotFound, Entity: entity}
+}
+
+func PreconditionFailed(entity string) error {
+	return &Error{Kind: ErrPreconditionFailed, Entity: entity}
+}
+
+// checkUUID отсекает заведомо невалидные идентификаторы до запроса в базу.
+func checkUUID(entity string, ids ...string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return &Error{Kind: ErrInvalidUUID, Entity: entity, Err: err}
+		}
+	}
+	return nil
+}
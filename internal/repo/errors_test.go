@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "no rows", err: pgx.ErrNoRows, kind: ErrNotFound},
+		{name: "unique", err: &pgconn.PgError{Code: pgerrcode.UniqueViolation}, kind: ErrConflict},
+		{name: "uuid cast", err: &pgconn.PgError{Code: pgerrcode.InvalidTextRepresentation,
+			Message: `invalid input syntax for type uuid: "abc"`}, kind: ErrInvalidUUID},
+		{name: "integer cast", err: &pgconn.PgError{Code: pgerrcode.InvalidTextRepresentation,
+			Message: `invalid input syntax for type integer: "abc"`}, kind: ErrInvalidInput},
+		{name: "enum cast", err: &pgconn.PgError{Code: pgerrcode.InvalidTextRepresentation,
+			Message: `invalid input value for enum mood: "abc"`}, kind: ErrInvalidInput},
+		{name: "timestamp", err: &pgconn.PgError{Code: pgerrcode.InvalidDatetimeFormat}, kind: ErrInvalidInput},
+		{name: "out of range", err: &pgconn.PgError{Code: pgerrcode.NumericValueOutOfRange}, kind: ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translate(errors.Wrap(tt.err, "query"), "movie")
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("translate() = %v, want kind %v", err, tt.kind)
+			}
+			if tt.kind != ErrInvalidUUID && errors.Is(err, ErrInvalidUUID) {
+				t.Errorf("translate() = %v, reported as invalid uuid", err)
+			}
+		})
+	}
+
+	if err := translate(&pgconn.PgError{Code: pgerrcode.DeadlockDetected}, "movie"); errors.As(err, new(*Error)) {
+		t.Errorf("translate() = %v, want the original error", err)
+	}
+}
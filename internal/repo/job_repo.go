@@ -0,0 +1,208 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// Типы фоновых задач
+const (
+	JobCompleteAsset = "complete_asset"
+	JobPackageAsset  = "package_asset"
+)
+
+// Состояния задачи: queued ждет запуска (в том числе повторного), dead - попытки исчерпаны
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+const (
+	jobColumns = `j.uuid, j.kind, j.asset_id, CASE WHEN a.movie_id IS NOT NULL THEN 'movie' ELSE 'episode' END,
+		coalesce(a.movie_id, a.episode_id), j.status, j.progress, j.attempts, j.max_attempts, j.last_error,
+		j.run_at, j.created_at, j.updated_at, j.finished_at`
+
+	enqueueJobQuery = `WITH j AS (
+			INSERT INTO jobs (uuid, kind, asset_id, max_attempts) VALUES (gen_random_uuid(), $1, $2, $3) RETURNING *
+		)
+		SELECT ` + jobColumns + ` FROM j JOIN media_assets a ON a.uuid = j.asset_id`
+
+	// Задача с истекшей арендой считается брошенной упавшим обработчиком и выдается заново. Если это была
+	// последняя попытка, задача уходит в dead: иначе задача, роняющая процесс, выдавалась бы бесконечно.
+	// SKIP LOCKED позволяет обработчикам разбирать очередь параллельно, не дожидаясь друг друга.
+	claimJobQuery = `WITH dead AS (
+			UPDATE jobs SET status = 'dead', last_error = 'job lease expired on the last attempt',
+				locked_by = NULL, locked_until = NULL, updated_at = now(), finished_at = now()
+			WHERE status = 'running' AND locked_until < now() AND attempts >= max_attempts
+		), next AS (
+			SELECT uuid FROM jobs
+			WHERE (status = 'queued' AND run_at <= now())
+				OR (status = 'running' AND locked_until < now() AND attempts < max_attempts)
+			ORDER BY run_at, created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j SET status = 'running', attempts = j.attempts + 1, locked_by = $1,
+			locked_until = now() + make_interval(secs => $2), updated_at = now()
+		FROM next, media_assets a
+		WHERE j.uuid = next.uuid AND a.uuid = j.asset_id
+		RETURNING ` + jobColumns
+
+	// Все изменения выполняемой задачи проверяют, что аренда все еще у этого обработчика
+	leaseCondition = ` WHERE uuid = $1 AND status = 'running' AND locked_by = $2`
+
+	extendJobLeaseQuery = `UPDATE jobs SET locked_until = now() + make_interval(secs => $3), updated_at = now()` + leaseCondition
+	setJobProgressQuery = `UPDATE jobs SET progress = $3, updated_at = now()` + leaseCondition
+	completeJobQuery    = `UPDATE jobs SET status = 'succeeded', progress = 100, locked_by = NULL, locked_until = NULL,
+		last_error = '', updated_at = now(), finished_at = now()` + leaseCondition
+	retryJobQuery = `UPDATE jobs SET status = 'queued', run_at = now() + make_interval(secs => $3), last_error = $4,
+		locked_by = NULL, locked_until = NULL, updated_at = now()` + leaseCondition
+	buryJobQuery = `UPDATE jobs SET status = 'dead', last_error = $3, locked_by = NULL, locked_until = NULL,
+		updated_at = now(), finished_at = now()` + leaseCondition
+	releaseJobQuery = `UPDATE jobs SET status = 'queued', attempts = attempts - 1, run_at = now(),
+		locked_by = NULL, locked_until = NULL, updated_at = now()` + leaseCondition
+
+	getJobQuery = `SELECT ` + jobColumns + ` FROM jobs j JOIN media_assets a ON a.uuid = j.asset_id WHERE j.uuid = $1`
+)
+
+type JobRepository interface {
+	EnqueueJob(ctx context.Context, kind, assetID string, maxAttempts int) (*Job, error)
+	// ClaimJob выдает обработчику worker следующую готовую задачу на время lease; nil - очередь пуста
+	ClaimJob(ctx context.Context, worker string, lease time.Duration) (*Job, error)
+	// Методы ниже меняют только задачу, арендованную worker; иначе возвращается ErrConflict
+	ExtendJobLease(ctx context.Context, uuid, worker string, lease time.Duration) error
+	SetJobProgress(ctx context.Context, uuid, worker string, progress int) error
+	CompleteJob(ctx context.Context, uuid, worker string) error
+	// RetryJob возвращает задачу в очередь с запуском не раньше чем через delay
+	RetryJob(ctx context.Context, uuid, worker string, delay time.Duration, lastError string) error
+	// BuryJob переводит задачу в dead: больше она не запускается
+	BuryJob(ctx context.Context, uuid, worker, lastError string) error
+	// ReleaseJob возвращает прерванную задачу в очередь, не засчитывая попытку
+	ReleaseJob(ctx context.Context, uuid, worker string) error
+	GetJob(ctx context.Context, uuid string) (*Job, error)
+	GetTitleJobs(ctx context.Context, kind, titleID string) ([]*Job, error)
+}
+
+func (r *repository) EnqueueJob(ctx context.Context, kind, assetID string, maxAttempts int) (*Job, error) {
+	if err := checkUUID("media asset", assetID); err != nil {
+		return nil, err
+	}
+
+	job, err := scanJob(r.db.QueryRow(ctx, enqueueJobQuery, kind, assetID, maxAttempts))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "job"), "failed to enqueue job")
+	}
+	return job, nil
+}
+
+func (r *repository) ClaimJob(ctx context.Context, worker string, lease time.Duration) (*Job, error) {
+	job, err := scanJob(r.db.QueryRow(ctx, claimJobQuery, worker, lease.Seconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to claim job")
+	}
+	return job, nil
+}
+
+func (r *repository) ExtendJobLease(ctx context.Context, uuid, worker string, lease time.Duration) error {
+	return r.updateLeasedJob(ctx, extendJobLeaseQuery, uuid, worker, lease.Seconds())
+}
+
+func (r *repository) SetJobProgress(ctx context.Context, uuid, worker string, progress int) error {
+	return r.updateLeasedJob(ctx, setJobProgressQuery, uuid, worker, progress)
+}
+
+func (r *repository) CompleteJob(ctx context.Context, uuid, worker string) error {
+	return r.updateLeasedJob(ctx, completeJobQuery, uuid, worker)
+}
+
+func (r *repository) RetryJob(ctx context.Context, uuid, worker string, delay time.Duration, lastError string) error {
+	return r.updateLeasedJob(ctx, retryJobQuery, uuid, worker, delay.Seconds(), lastError)
+}
+
+func (r *repository) BuryJob(ctx context.Context, uuid, worker, lastError string) error {
+	return r.updateLeasedJob(ctx, buryJobQuery, uuid, worker, lastError)
+}
+
+func (r *repository) ReleaseJob(ctx context.Context, uuid, worker string) error {
+	return r.updateLeasedJob(ctx, releaseJobQuery, uuid, worker)
+}
+
+func (r *repository) GetJob(ctx context.Context, uuid string) (*Job, error) {
+	if err := checkUUID("job", uuid); err != nil {
+		return nil, err
+	}
+
+	job, err := scanJob(r.db.QueryRow(ctx, getJobQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "job"), "failed to query job")
+	}
+	return job, nil
+}
+
+func (r *repository) GetTitleJobs(ctx context.Context, kind, titleID string) ([]*Job, error) {
+	column, err := titleColumn(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUUID(kind, titleID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + jobColumns + ` FROM jobs j JOIN media_assets a ON a.uuid = j.asset_id
+		WHERE a.` + column + ` = $1 ORDER BY j.created_at DESC, j.uuid`
+
+	rows, err := r.db.Query(ctx, query, titleID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "job"), "failed to query jobs")
+	}
+	defer rows.Close()
+
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan job")
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over jobs")
+	}
+
+	return jobs, nil
+}
+
+// updateLeasedJob выполняет изменение задачи под арендой; ноль строк - аренду перехватил другой обработчик.
+func (r *repository) updateLeasedJob(ctx context.Context, query, uuid, worker string, args ...any) error {
+	commandTag, err := r.db.Exec(ctx, query, append([]any{uuid, worker}, args...)...)
+	if err != nil {
+		return errors.Wrap(translate(err, "job"), "failed to update job")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return &Error{Kind: ErrConflict, Entity: "job", Err: errors.New("job lease is lost")}
+	}
+
+	return nil
+}
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+
+	err := row.Scan(&job.UUID, &job.Kind, &job.AssetID, &job.TitleKind, &job.TitleID, &job.Status, &job.Progress,
+		&job.Attempts, &job.MaxAttempts, &job.LastError, &job.RunAt, &job.Created_at, &job.UpdatedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
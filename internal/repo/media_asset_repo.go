@@ -0,0 +1,239 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// Состояния загрузки медиафайла: completing - файл принят целиком и ждет проверки в фоновой задаче
+const (
+	AssetUploading  = "uploading"
+	AssetCompleting = "completing"
+	AssetReady      = "ready"
+	AssetFailed     = "failed"
+)
+
+const (
+	mediaAssetColumns = `uuid, CASE WHEN movie_id IS NOT NULL THEN 'movie' ELSE 'episode' END, coalesce(movie_id, episode_id),
+		filename, mime_type, size, upload_offset, checksum, status, storage_key, created_at, completed_at`
+
+	lockAssetQuery      = `SELECT ` + mediaAssetColumns + ` FROM media_assets WHERE uuid = $1 FOR UPDATE`
+	setAssetOffsetQuery = `UPDATE media_assets SET upload_offset = $2,
+			status = CASE WHEN $2 = size THEN 'completing' ELSE status END
+		WHERE uuid = $1 AND status = 'uploading' RETURNING status`
+	completeAssetQuery = `UPDATE media_assets SET status = $2, mime_type = $3, storage_key = $4, completed_at = now()
+		WHERE uuid = $1 AND status = 'completing' RETURNING ` + mediaAssetColumns
+	deleteAssetQuery = `DELETE FROM media_assets WHERE uuid = $1`
+)
+
+// titleColumns - колонка media_assets, которая ссылается на единицу каталога данного вида.
+var titleColumns = map[string]string{
+	TitleMovie:   "movie_id",
+	TitleEpisode: "episode_id",
+}
+
+type MediaAssetRepository interface {
+	// CreateAsset заводит загрузку; asset.UUID задает вызывающий - под ним уже создан файл
+	CreateAsset(ctx context.Context, asset *MediaAsset) (*MediaAsset, error)
+	GetAssets(ctx context.Context, kind, titleID string) ([]*MediaAsset, error)
+	GetAsset(ctx context.Context, kind, titleID, uuid string) (*MediaAsset, error)
+	// GetPrimaryAsset - файл, который проигрывается для фильма или эпизода: последний завершенный
+	GetPrimaryAsset(ctx context.Context, kind, titleID string) (*MediaAsset, error)
+	// LockAsset блокирует загрузку до конца транзакции, чтобы фрагменты принимались по одному
+	LockAsset(ctx context.Context, uuid string) (*MediaAsset, error)
+	// SetAssetOffset сдвигает смещение и возвращает новое состояние: приняв последний байт, загрузка
+	// переходит в AssetCompleting, и следующие фрагменты получают ErrConflict
+	SetAssetOffset(ctx context.Context, uuid string, offset int64) (string, error)
+	// CompleteAsset фиксирует итог проверки загрузки в AssetCompleting: status - AssetReady или AssetFailed
+	CompleteAsset(ctx context.Context, uuid, status, mimeType, storageKey string) (*MediaAsset, error)
+	DeleteAsset(ctx context.Context, uuid string) error
+}
+
+func (r *repository) CreateAsset(ctx context.Context, asset *MediaAsset) (*MediaAsset, error) {
+	column, err := titleColumn(asset.TitleKind)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUUID("media asset", asset.UUID); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO media_assets (uuid, %s, filename, mime_type, size, checksum) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING %s`, column, mediaAssetColumns)
+
+	created, err := scanMediaAsset(r.db.QueryRow(ctx, query, asset.UUID, asset.TitleID, asset.Filename, asset.MimeType, asset.Size,
+		asset.Checksum))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to insert media asset")
+	}
+	return created, nil
+}
+
+func (r *repository) GetAssets(ctx context.Context, kind, titleID string) ([]*MediaAsset, error) {
+	column, err := titleColumn(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUUID(kind, titleID); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM media_assets WHERE %s = $1 ORDER BY created_at, uuid`, mediaAssetColumns, column)
+
+	rows, err := r.db.Query(ctx, query, titleID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to query media assets")
+	}
+	defer rows.Close()
+
+	assets := make([]*MediaAsset, 0)
+	for rows.Next() {
+		asset, err := scanMediaAsset(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan media asset")
+		}
+		assets = append(assets, asset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over media assets")
+	}
+
+	return assets, nil
+}
+
+func (r *repository) GetAsset(ctx context.Context, kind, titleID, uuid string) (*MediaAsset, error) {
+	column, err := titleColumn(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUUID("media asset", titleID, uuid); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM media_assets WHERE uuid = $1 AND %s = $2`, mediaAssetColumns, column)
+
+	asset, err := scanMediaAsset(r.db.QueryRow(ctx, query, uuid, titleID))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to query media asset")
+	}
+	return asset, nil
+}
+
+func (r *repository) GetPrimaryAsset(ctx context.Context, kind, titleID string) (*MediaAsset, error) {
+	column, err := titleColumn(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUUID(kind, titleID); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM media_assets WHERE %s = $1 AND status = 'ready'
+		ORDER BY completed_at DESC, uuid LIMIT 1`, mediaAssetColumns, column)
+
+	asset, err := scanMediaAsset(r.db.QueryRow(ctx, query, titleID))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to query primary media asset")
+	}
+	return asset, nil
+}
+
+func (r *repository) LockAsset(ctx context.Context, uuid string) (*MediaAsset, error) {
+	if err := checkUUID("media asset", uuid); err != nil {
+		return nil, err
+	}
+
+	asset, err := scanMediaAsset(r.db.QueryRow(ctx, lockAssetQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to lock media asset")
+	}
+	return asset, nil
+}
+
+func (r *repository) SetAssetOffset(ctx context.Context, uuid string, offset int64) (string, error) {
+	var status string
+	if err := r.db.QueryRow(ctx, setAssetOffsetQuery, uuid, offset).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", &Error{Kind: ErrConflict, Entity: "media asset", Err: errors.New("upload is already completed")}
+		}
+		return "", errors.Wrap(translate(err, "media asset"), "failed to update upload offset")
+	}
+	return status, nil
+}
+
+func (r *repository) CompleteAsset(ctx context.Context, uuid, status, mimeType, storageKey string) (*MediaAsset, error) {
+	asset, err := scanMediaAsset(r.db.QueryRow(ctx, completeAssetQuery, uuid, status, mimeType, storageKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &Error{Kind: ErrConflict, Entity: "media asset", Err: errors.New("upload is already completed")}
+		}
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to complete media asset")
+	}
+	return asset, nil
+}
+
+func (r *repository) DeleteAsset(ctx context.Context, uuid string) error {
+	if err := checkUUID("media asset", uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, deleteAssetQuery, uuid)
+	if err != nil {
+		return errors.Wrap(translate(err, "media asset"), "failed to delete media asset")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("media asset"), "no rows deleted, media asset with given uuid not found")
+	}
+
+	return nil
+}
+
+// deleteAssets удаляет медиафайлы по условию и возвращает их. Перед удалением фильмов и эпизодов медиафайлы
+// удаляются явно: каскад унес бы их молча, и файлы в хранилище остались бы без записей.
+func (r *repository) deleteAssets(ctx context.Context, where string, args ...any) ([]*MediaAsset, error) {
+	rows, err := r.db.Query(ctx, `DELETE FROM media_assets WHERE `+where+` RETURNING `+mediaAssetColumns, args...)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "media asset"), "failed to delete media assets")
+	}
+	defer rows.Close()
+
+	assets := make([]*MediaAsset, 0)
+	for rows.Next() {
+		asset, err := scanMediaAsset(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan media asset")
+		}
+		assets = append(assets, asset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over deleted media assets")
+	}
+
+	return assets, nil
+}
+
+func titleColumn(kind string) (string, error) {
+	column, ok := titleColumns[kind]
+	if !ok {
+		return "", errors.Errorf("unknown title kind %q", kind)
+	}
+	return column, nil
+}
+
+func scanMediaAsset(row pgx.Row) (*MediaAsset, error) {
+	var asset MediaAsset
+
+	err := row.Scan(&asset.UUID, &asset.TitleKind, &asset.TitleID, &asset.Filename, &asset.MimeType, &asset.Size, &asset.Offset,
+		&asset.Checksum, &asset.Status, &asset.StorageKey, &asset.Created_at, &asset.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
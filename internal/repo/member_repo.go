@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"github.com/pkg/errors"
+)
+
+const (
+	upsertOwnerMemberQuery = `INSERT INTO owner_members (owner_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (owner_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	getOwnerMemberQuery    = `SELECT role, created_at FROM owner_members WHERE owner_id = $1 AND user_id = $2`
+	getOwnerMembersQuery   = `SELECT user_id, role, created_at FROM owner_members WHERE owner_id = $1 ORDER BY created_at`
+	deleteOwnerMemberQuery = `DELETE FROM owner_members WHERE owner_id = $1 AND user_id = $2`
+)
+
+type MemberRepository interface {
+	AddOwnerMember(ctx context.Context, member *OwnerMember) error
+	GetOwnerMember(ctx context.Context, ownerID, userID string) (*OwnerMember, error)
+	GetOwnerMembers(ctx context.Context, ownerID string) ([]*OwnerMember, error)
+	RemoveOwnerMember(ctx context.Context, ownerID, userID string) error
+}
+
+func (r *repository) AddOwnerMember(ctx context.Context, member *OwnerMember) error {
+	if err := checkUUID("owner member", member.OwnerID, member.UserID); err != nil {
+		return err
+	}
+
+	if _, err := r.db.Exec(ctx, upsertOwnerMemberQuery, member.OwnerID, member.UserID, member.Role); err != nil {
+		return errors.Wrap(translate(err, "owner member"), "failed to upsert owner member")
+	}
+	return nil
+}
+
+func (r *repository) GetOwnerMember(ctx context.Context, ownerID, userID string) (*OwnerMember, error) {
+	if err := checkUUID("owner member", ownerID, userID); err != nil {
+		return nil, err
+	}
+
+	member := &OwnerMember{OwnerID: ownerID, UserID: userID}
+
+	err := r.db.QueryRow(ctx, getOwnerMemberQuery, ownerID, userID).Scan(&member.Role, &member.Created_at)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "owner member"), "failed to query owner member")
+	}
+
+	return member, nil
+}
+
+func (r *repository) GetOwnerMembers(ctx context.Context, ownerID string) ([]*OwnerMember, error) {
+	if err := checkUUID("owner member", ownerID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getOwnerMembersQuery, ownerID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "owner member"), "failed to query owner members")
+	}
+	defer rows.Close()
+
+	var members []*OwnerMember
+	for rows.Next() {
+		member := &OwnerMember{OwnerID: ownerID}
+
+		if err := rows.Scan(&member.UserID, &member.Role, &member.Created_at); err != nil {
+			return nil, errors.Wrap(err, "failed to scan owner member row")
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over owner member rows")
+	}
+
+	return members, nil
+}
+
+func (r *repository) RemoveOwnerMember(ctx context.Context, ownerID, userID string) error {
+	if err := checkUUID("owner member", ownerID, userID); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, deleteOwnerMemberQuery, ownerID, userID)
+	if err != nil {
+		return errors.Wrap(translate(err, "owner member"), "failed to execute delete query")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("owner member"), "no rows deleted, owner member not found")
+	}
+
+	return nil
+}
+
+// MemberOwners ограничивает выборку с колонкой owner_id владельцами, в которых состоит пользователь.
+func MemberOwners(userID string) Condition {
+	return Condition{SQL: `owner_id IN (SELECT owner_id FROM owner_members WHERE user_id = $?::uuid)`, Args: []any{userID}}
+}
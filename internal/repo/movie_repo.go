@@ -2,118 +2,252 @@ package repo
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"strconv"
 
 	"github.com/pkg/errors"
 )
 
 const (
-	insertMovieQuery  = `INSERT INTO movies (uuid, owner_id, title, author, description, year) VALUES ($1, $2, $3, $4, $5, $6) RETURNING uuid`
-	getAllMoviesQuery = `SELECT uuid, title, description, author, year FROM movies LIMIT $1 OFFSET $2`
-	getMovieQuery     = `SELECT title, author, description, year FROM movies WHERE uuid = $1`
-	updateMovieQuery  = `UPDATE movies SET title = $1, author = $2, description = $3, year = $4 WHERE uuid = $5`
-	deleteMovieQuery  = `DELETE FROM movies WHERE uuid = $1`
+	insertMovieQuery = `INSERT INTO movies (uuid, owner_id, title, author, description, year) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + movieReturning
+	getMovieQuery    = `SELECT owner_id, title, author, description, year, version, created_at FROM movies WHERE uuid = $1 AND deleted_at IS NULL`
+	lockMovieQuery   = `SELECT ` + movieReturning + ` FROM movies WHERE uuid = $1 FOR UPDATE`
+	updateMovieQuery = `UPDATE movies SET title = $1, description = $2, year = $3, version = version + 1
+		WHERE uuid = $4 AND deleted_at IS NULL AND ($5::int = 0 OR version = $5::int) RETURNING ` + movieReturning
+	deleteMovieQuery = `UPDATE movies SET deleted_at = now(), version = version + 1
+		WHERE uuid = $1 AND deleted_at IS NULL AND ($2::int = 0 OR version = $2::int) RETURNING ` + movieReturning
+
+	movieReturning = `uuid, owner_id, title, author, description, year, version, created_at, deleted_at`
 )
 
+var patchableMovieColumns = map[string]bool{
+	"title":       true,
+	"description": true,
+	"year":        true,
+}
+
+var movieSchema = Schema{
+	Table:      "movies",
+	SoftDelete: true,
+	Fields: map[string]Field{
+		"owner_id": {Column: "owner_id", Type: "uuid"},
+		"title": {Column: "title", Type: "text", Sortable: true, Value: func(item any) string {
+			return item.(*Movie).Title
+		}},
+		"author": {Column: "author", Type: "text", Sortable: true, Value: func(item any) string {
+			return item.(*Movie).Author
+		}},
+		"year": {Column: "year", Type: "int", Sortable: true, Value: func(item any) string {
+			return strconv.Itoa(item.(*Movie).Year)
+		}},
+		"created_at": {Column: "created_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*Movie).Created_at)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "created_at"}},
+}
+
+// MovieSchema - поля фильмов, доступные для фильтрации и сортировки.
+func MovieSchema() Schema {
+	return movieSchema
+}
+
 type MovieRepository interface {
-	CreateMovie(ctx context.Context, movie *Movie, ownerName string) (string, error)
-	GetAllMovies(ctx context.Context, limit, offset int) (map[string]*Movie, error)
+	// CreateMovie создает фильм владельца movie.OwnerID; владельца по имени находит сервис
+	CreateMovie(ctx context.Context, movie *Movie) (string, error)
+	GetAllMovies(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Movie], error)
 	GetMovieByID(ctx context.Context, uuid string) (*Movie, error)
 	UpdateMovie(ctx context.Context, uuid string, film *Movie) error
-	DeleteMovie(ctx context.Context, uuid string) error
+	PatchMovie(ctx context.Context, uuid string, version int, fields map[string]any) (*Movie, error)
+	DeleteMovie(ctx context.Context, uuid string, version int) error
 }
 
-func (r *repository) CreateMovie(ctx context.Context, movie *Movie, ownerName string) (string, error) {
-	uuid := uuid.New().String()
+func (r *repository) CreateMovie(ctx context.Context, movie *Movie) (string, error) {
+	if err := checkUUID("owner", movie.OwnerID); err != nil {
+		return "", err
+	}
 
-	var ownerUUID string
-	owner, err := r.GetOwnerByName(ctx, ownerName)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			newOwnerUUID, err := r.CreateOwner(ctx, &Owner{Name: ownerName})
-			if err != nil {
-				return "", fmt.Errorf("owner of movie not found, failed to create new owner: %w", err)
-			}
-			ownerUUID = newOwnerUUID
-		} else {
-			return "", fmt.Errorf("owner of movie not found, failed to query owner: %w", err)
+	var movieID string
+	err := r.withTx(ctx, func(tx *repository) error {
+		created, err := scanMovie(tx.db.QueryRow(ctx, insertMovieQuery, uuid.New().String(), movie.OwnerID, movie.Title,
+			movie.Author, movie.Description, movie.Year))
+		if err != nil {
+			return errors.Wrap(translate(err, "movie"), "failed to insert movie")
 		}
-	} else {
-		ownerUUID = owner.UUID
+		movieID = created.UUID
+
+		return tx.audit(ctx, AuditCreate, "movie", created.UUID, nil, created)
+	})
+	if err != nil {
+		return "", err
 	}
 
-	err = r.pool.QueryRow(ctx, insertMovieQuery, uuid, ownerUUID, movie.Title, movie.Author, movie.Description, movie.Year).Scan(&uuid)
+	return movieID, nil
+}
+
+func (r *repository) GetAllMovies(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Movie], error) {
+	movies, err := listPage(ctx, r.db, movieSchema, movieReturning, spec, page, scanMovie, func(movie *Movie) string {
+		return movie.UUID
+	})
 	if err != nil {
-		return "", errors.Wrap(err, "failed to insert movie")
+		return nil, errors.Wrap(err, "failed to query all movies")
 	}
-	return uuid, nil
+
+	return movies, nil
 }
 
-func (r *repository) GetAllMovies(ctx context.Context, limit, offset int) (map[string]*Movie, error) {
-	movies := make(map[string]*Movie)
+func (r *repository) GetMovieByID(ctx context.Context, uuid string) (*Movie, error) {
+	if err := checkUUID("movie", uuid); err != nil {
+		return nil, err
+	}
+
+	movie := &Movie{UUID: uuid}
 
-	rows, err := r.pool.Query(ctx, getAllMoviesQuery, limit, offset)
+	err := r.db.QueryRow(ctx, getMovieQuery, uuid).Scan(&movie.OwnerID, &movie.Title, &movie.Author, &movie.Description, &movie.Year, &movie.Version, &movie.Created_at)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to query all movies")
+		return nil, errors.Wrap(translate(err, "movie"), "failed to query movie")
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var movie Movie
-		var uuid string
+	return movie, nil
+}
+
+func (r *repository) UpdateMovie(ctx context.Context, uuid string, movie *Movie) error {
+	if err := checkUUID("movie", uuid); err != nil {
+		return err
+	}
 
-		err := rows.Scan(&uuid, &movie.Title, &movie.Author, &movie.Description, &movie.Year)
+	return r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockMovie(ctx, uuid, false)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to scan movie row")
+			return err
 		}
-		movies[uuid] = &movie
-	}
 
-	if err := rows.Err(); err != nil {
-		return nil, errors.Wrap(err, "error occurred during iteration over movie rows")
-	}
+		// movie.Version - ожидаемая версия (0 - без проверки), после записи в нее кладется новая.
+		// author не пишется: он выводится из режиссеров в титрах, см. SetMovieCredits
+		after, err := scanMovie(tx.db.QueryRow(ctx, updateMovieQuery, movie.Title, movie.Description, movie.Year, uuid,
+			movie.Version))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("movie"), "no rows updated")
+			}
+			return errors.Wrap(translate(err, "movie"), "failed to execute update query")
+		}
+		movie.Version = after.Version
 
-	return movies, nil
+		return tx.audit(ctx, AuditUpdate, "movie", uuid, before, after)
+	})
 }
 
-func (r *repository) GetMovieByID(ctx context.Context, uuid string) (*Movie, error) {
-	movie := &Movie{UUID: uuid}
+// PatchMovie с пустым fields только увеличивает версию - так отражаются изменения связанных данных (жанров, тегов).
+func (r *repository) PatchMovie(ctx context.Context, uuid string, version int, fields map[string]any) (*Movie, error) {
+	if err := checkUUID("movie", uuid); err != nil {
+		return nil, err
+	}
 
-	err := r.pool.QueryRow(ctx, getMovieQuery, uuid).Scan(&movie.Title, &movie.Author, &movie.Description, &movie.Year)
+	query, args, err := buildPatchQuery("movies", patchableMovieColumns, fields, movieReturning)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.Wrap(err, "movie not found")
+		return nil, errors.Wrap(err, "failed to build patch query")
+	}
+
+	var movie *Movie
+	err = r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockMovie(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+
+		movie, err = scanMovie(tx.db.QueryRow(ctx, query, append(args, uuid, version)...))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("movie"), "no rows updated")
+			}
+			return errors.Wrap(translate(err, "movie"), "failed to execute patch query")
 		}
-		return nil, errors.Wrap(err, "failed to query movie")
+
+		return tx.audit(ctx, AuditUpdate, "movie", uuid, before, movie)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return movie, nil
 }
 
-func (r *repository) UpdateMovie(ctx context.Context, uuid string, movie *Movie) error {
-	commandTag, err := r.pool.Exec(ctx, updateMovieQuery, movie.Title, movie.Author, movie.Description, movie.Year, uuid)
+func (r *repository) DeleteMovie(ctx context.Context, uuid string, version int) error {
+	if err := checkUUID("movie", uuid); err != nil {
+		return err
+	}
+
+	return r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockMovie(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanMovie(tx.db.QueryRow(ctx, deleteMovieQuery, uuid, version))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("movie"), "no rows deleted")
+			}
+			return errors.Wrap(translate(err, "movie"), "failed to execute delete query")
+		}
+
+		return tx.audit(ctx, AuditDelete, "movie", uuid, before, after)
+	})
+}
+
+// lockMovie блокирует строку фильма до конца транзакции и возвращает ее как снимок "до" для журнала.
+// deleted - ожидается фильм из корзины, а не действующий.
+func (r *repository) lockMovie(ctx context.Context, uuid string, deleted bool) (*Movie, error) {
+	movie, err := scanMovie(r.db.QueryRow(ctx, lockMovieQuery, uuid))
 	if err != nil {
-		return errors.Wrap(err, "failed to execute update query")
+		return nil, errors.Wrap(translate(err, "movie"), "failed to lock movie")
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("no rows updated, movie with given UUID not found")
+	if (movie.DeletedAt != nil) != deleted {
+		if deleted {
+			return nil, notFound("deleted movie")
+		}
+		return nil, notFound("movie")
 	}
 
-	return nil
+	return movie, nil
 }
 
-func (r *repository) DeleteMovie(ctx context.Context, uuid string) error {
-	commandTag, err := r.pool.Exec(ctx, deleteMovieQuery, uuid)
+// queryMovies выполняет запрос, возвращающий movieReturning, и собирает все строки.
+func (r *repository) queryMovies(ctx context.Context, query string, args ...any) ([]*Movie, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute delete query")
+		return nil, errors.Wrap(translate(err, "movie"), "failed to query movies")
 	}
+	defer rows.Close()
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("no rows deleted, movie with given UUID not found")
+	var movies []*Movie
+	for rows.Next() {
+		movie, err := scanMovie(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan movie")
+		}
+		movies = append(movies, movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over movies")
+	}
+
+	return movies, nil
+}
+
+func scanMovie(row pgx.Row) (*Movie, error) {
+	var movie Movie
+
+	err := row.Scan(&movie.UUID, &movie.OwnerID, &movie.Title, &movie.Author, &movie.Description, &movie.Year,
+		&movie.Version, &movie.Created_at, &movie.DeletedAt)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &movie, nil
 }
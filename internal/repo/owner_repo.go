@@ -8,68 +8,125 @@ import (
 )
 
 const (
-	insertOwnerQuery    = `INSERT INTO owners (uuid, name) VALUES ($1, $2) RETURNING uuid`
-	getAllOwnersQuery   = `SELECT uuid, name, created_at FROM owners LIMIT $1 OFFSET $2`
-	getOwnerByIdQuery   = `SELECT name, created_at FROM owners WHERE uuid = $1`
-	getOwnerByNameQuery = `SELECT uuid, created_at FROM owners WHERE name = $1`
-	updateOwnerQuery    = `UPDATE owners SET name = $1 WHERE uuid = $2`
-	deleteOwnerQuery    = `DELETE FROM owners WHERE uuid = $1`
+	insertOwnerQuery = `INSERT INTO owners (uuid, name) VALUES ($1, $2) RETURNING ` + ownerReturning
+	// Пустой DO UPDATE нужен, чтобы RETURNING отдал и уже существующую строку;
+	// xmax = 0 только у строки, вставленной этим запросом
+	upsertOwnerQuery = `INSERT INTO owners (uuid, name) VALUES ($1, $2)
+		ON CONFLICT (name) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name
+		RETURNING ` + ownerReturning + `, xmax = 0`
+	getOwnerByIdQuery   = `SELECT name, version, created_at FROM owners WHERE uuid = $1 AND deleted_at IS NULL`
+	getOwnerByNameQuery = `SELECT uuid, version, created_at FROM owners WHERE name = $1 AND deleted_at IS NULL`
+	lockOwnerQuery      = `SELECT ` + ownerReturning + ` FROM owners WHERE uuid = $1 FOR UPDATE`
+	updateOwnerQuery    = `UPDATE owners SET name = $1, version = version + 1
+		WHERE uuid = $2 AND deleted_at IS NULL AND ($3::int = 0 OR version = $3::int) RETURNING ` + ownerReturning
+	deleteOwnerQuery = `UPDATE owners SET deleted_at = now(), version = version + 1
+		WHERE uuid = $1 AND deleted_at IS NULL AND ($2::int = 0 OR version = $2::int) RETURNING ` + ownerReturning
+	// Фильмы удаляются вместе с владельцем и с той же меткой времени - по ней восстановление их и находит
+	lockOwnerMoviesQuery   = `SELECT ` + movieReturning + ` FROM movies WHERE owner_id = $1 AND deleted_at IS NULL FOR UPDATE`
+	deleteOwnerMoviesQuery = `UPDATE movies SET deleted_at = $2, version = version + 1
+		WHERE owner_id = $1 AND deleted_at IS NULL RETURNING ` + movieReturning
+
+	ownerReturning = `uuid, name, version, created_at, deleted_at`
 )
 
+var patchableOwnerColumns = map[string]bool{
+	"name": true,
+}
+
+var ownerSchema = Schema{
+	Table:      "owners",
+	SoftDelete: true,
+	Fields: map[string]Field{
+		"name": {Column: "name", Type: "text", Sortable: true, Value: func(item any) string {
+			return item.(*Owner).Name
+		}},
+		"created_at": {Column: "created_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*Owner).Created_at)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "created_at"}},
+}
+
+// OwnerSchema - поля владельцев, доступные для фильтрации и сортировки.
+func OwnerSchema() Schema {
+	return ownerSchema
+}
+
 type OwnerRepository interface {
 	CreateOwner(ctx context.Context, owner *Owner) (string, error)
-	GetAllOwners(ctx context.Context, limit, offset int) (map[string]*Owner, error)
+	// UpsertOwner возвращает владельца с именем name, создавая его при отсутствии; created - был ли он создан
+	UpsertOwner(ctx context.Context, name string) (owner *Owner, created bool, err error)
+	GetAllOwners(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Owner], error)
 	GetOwnerByID(ctx context.Context, uuid string) (*Owner, error)
 	GetOwnerByName(ctx context.Context, name string) (*Owner, error)
 	UpdateOwner(ctx context.Context, uuid string, film *Owner) error
-	DeleteOwner(ctx context.Context, uuid string) error
+	PatchOwner(ctx context.Context, uuid string, version int, fields map[string]any) (*Owner, error)
+	DeleteOwner(ctx context.Context, uuid string, version int) error
 }
 
 func (r *repository) CreateOwner(ctx context.Context, owner *Owner) (string, error) {
-	uuid := uuid.New().String()
-
-	err := r.pool.QueryRow(ctx, insertOwnerQuery, uuid, owner.Name).Scan(&uuid)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to insert owner")
-	}
-	return uuid, nil
-}
+	var ownerID string
 
-func (r *repository) GetAllOwners(ctx context.Context, limit, offset int) (map[string]*Owner, error) {
-	owners := make(map[string]*Owner)
+	err := r.withTx(ctx, func(tx *repository) error {
+		created, err := scanOwner(tx.db.QueryRow(ctx, insertOwnerQuery, uuid.New().String(), owner.Name))
+		if err != nil {
+			return errors.Wrap(translate(err, "owner"), "failed to insert owner")
+		}
+		ownerID = created.UUID
 
-	rows, err := r.pool.Query(ctx, getAllOwnersQuery, limit, offset)
+		return tx.audit(ctx, AuditCreate, "owner", created.UUID, nil, created)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to query all owners")
+		return "", err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var owner Owner
+	return ownerID, nil
+}
+
+func (r *repository) UpsertOwner(ctx context.Context, name string) (*Owner, bool, error) {
+	var owner Owner
+	var created bool
 
-		err = rows.Scan(&owner.UUID, &owner.Name, &owner.Created_at)
+	err := r.withTx(ctx, func(tx *repository) error {
+		err := tx.db.QueryRow(ctx, upsertOwnerQuery, uuid.New().String(), name).
+			Scan(&owner.UUID, &owner.Name, &owner.Version, &owner.Created_at, &owner.DeletedAt, &created)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to scan owner row")
+			return errors.Wrap(translate(err, "owner"), "failed to upsert owner")
 		}
-		owners[owner.UUID] = &owner
+
+		if !created {
+			return nil
+		}
+		return tx.audit(ctx, AuditCreate, "owner", owner.UUID, nil, &owner)
+	})
+	if err != nil {
+		return nil, false, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, errors.Wrap(err, "error occurred during iteration over owner rows")
+	return &owner, created, nil
+}
+
+func (r *repository) GetAllOwners(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Owner], error) {
+	owners, err := listPage(ctx, r.db, ownerSchema, ownerReturning, spec, page, scanOwner, func(owner *Owner) string {
+		return owner.UUID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query all owners")
 	}
 
 	return owners, nil
 }
 
 func (r *repository) GetOwnerByID(ctx context.Context, uuid string) (*Owner, error) {
+	if err := checkUUID("owner", uuid); err != nil {
+		return nil, err
+	}
+
 	owner := &Owner{UUID: uuid}
 
-	err := r.pool.QueryRow(ctx, getOwnerByIdQuery, uuid).Scan(&owner.Name, &owner.Created_at)
+	err := r.db.QueryRow(ctx, getOwnerByIdQuery, uuid).Scan(&owner.Name, &owner.Version, &owner.Created_at)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.Wrap(err, "owner not found")
-		}
-		return nil, errors.Wrap(err, "failed to query owner by uuid")
+		return nil, errors.Wrap(translate(err, "owner"), "failed to query owner by uuid")
 	}
 
 	return owner, nil
@@ -78,39 +135,157 @@ func (r *repository) GetOwnerByID(ctx context.Context, uuid string) (*Owner, err
 func (r *repository) GetOwnerByName(ctx context.Context, name string) (*Owner, error) {
 	owner := &Owner{Name: name}
 
-	err := r.pool.QueryRow(ctx, getOwnerByNameQuery, name).Scan(&owner.UUID, &owner.Created_at)
+	err := r.db.QueryRow(ctx, getOwnerByNameQuery, name).Scan(&owner.UUID, &owner.Version, &owner.Created_at)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.Wrap(err, "owner not found")
-		}
-		return nil, errors.Wrap(err, "failed to query owner by uuid")
+		return nil, errors.Wrap(translate(err, "owner"), "failed to query owner by uuid")
 	}
 
 	return owner, nil
 }
 
 func (r *repository) UpdateOwner(ctx context.Context, uuid string, owner *Owner) error {
-	commandTag, err := r.pool.Exec(ctx, updateOwnerQuery, owner.Name, uuid)
+	if err := checkUUID("owner", uuid); err != nil {
+		return err
+	}
+
+	return r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockOwner(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+
+		// owner.Version - ожидаемая версия (0 - без проверки), после записи в нее кладется новая
+		after, err := scanOwner(tx.db.QueryRow(ctx, updateOwnerQuery, owner.Name, uuid, owner.Version))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("owner"), "no rows updated")
+			}
+			return errors.Wrap(translate(err, "owner"), "failed to execute update query")
+		}
+		owner.Version = after.Version
+
+		return tx.audit(ctx, AuditUpdate, "owner", uuid, before, after)
+	})
+}
+
+func (r *repository) PatchOwner(ctx context.Context, uuid string, version int, fields map[string]any) (*Owner, error) {
+	if len(fields) == 0 {
+		return r.GetOwnerByID(ctx, uuid)
+	}
+
+	if err := checkUUID("owner", uuid); err != nil {
+		return nil, err
+	}
+
+	query, args, err := buildPatchQuery("owners", patchableOwnerColumns, fields, ownerReturning)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute update query")
+		return nil, errors.Wrap(err, "failed to build patch query")
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("no rows updated, owner with given UUID not found")
+	var owner *Owner
+	err = r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockOwner(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+
+		owner, err = scanOwner(tx.db.QueryRow(ctx, query, append(args, uuid, version)...))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("owner"), "no rows updated")
+			}
+			return errors.Wrap(translate(err, "owner"), "failed to execute patch query")
+		}
+
+		return tx.audit(ctx, AuditUpdate, "owner", uuid, before, owner)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return owner, nil
+}
+
+func (r *repository) DeleteOwner(ctx context.Context, uuid string, version int) error {
+	if err := checkUUID("owner", uuid); err != nil {
+		return err
+	}
+
+	return r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockOwner(ctx, uuid, false)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanOwner(tx.db.QueryRow(ctx, deleteOwnerQuery, uuid, version))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrap(PreconditionFailed("owner"), "no rows deleted")
+			}
+			return errors.Wrap(translate(err, "owner"), "failed to execute delete query")
+		}
+
+		if err := tx.audit(ctx, AuditDelete, "owner", uuid, before, after); err != nil {
+			return err
+		}
+
+		return tx.cascadeOwnerMovies(ctx, AuditDelete,
+			lockOwnerMoviesQuery, []any{uuid},
+			deleteOwnerMoviesQuery, []any{uuid, after.DeletedAt})
+	})
 }
 
-func (r *repository) DeleteOwner(ctx context.Context, uuid string) error {
-	commandTag, err := r.pool.Exec(ctx, deleteOwnerQuery, uuid)
+// lockOwner блокирует строку владельца до конца транзакции и возвращает ее как снимок "до" для журнала.
+// deleted - ожидается владелец из корзины, а не действующий.
+func (r *repository) lockOwner(ctx context.Context, uuid string, deleted bool) (*Owner, error) {
+	owner, err := scanOwner(r.db.QueryRow(ctx, lockOwnerQuery, uuid))
 	if err != nil {
-		return errors.Wrap(err, "failed to execute delete query")
+		return nil, errors.Wrap(translate(err, "owner"), "failed to lock owner")
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("no rows deleted, owner with given UUID not found")
+	if (owner.DeletedAt != nil) != deleted {
+		if deleted {
+			return nil, notFound("deleted owner")
+		}
+		return nil, notFound("owner")
+	}
+
+	return owner, nil
+}
+
+// cascadeOwnerMovies применяет к фильмам владельца запрос update и пишет в журнал событие по каждому фильму.
+// Запрос lock должен выбрать и заблокировать те же фильмы - это их снимки "до".
+func (r *repository) cascadeOwnerMovies(ctx context.Context, action, lock string, lockArgs []any, update string, updateArgs []any) error {
+	before, err := r.queryMovies(ctx, lock, lockArgs...)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock movies of owner")
+	}
+
+	byUUID := make(map[string]*Movie, len(before))
+	for _, movie := range before {
+		byUUID[movie.UUID] = movie
+	}
+
+	after, err := r.queryMovies(ctx, update, updateArgs...)
+	if err != nil {
+		return errors.Wrap(err, "failed to update movies of owner")
+	}
+
+	for _, movie := range after {
+		if err := r.audit(ctx, action, "movie", movie.UUID, byUUID[movie.UUID], movie); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+func scanOwner(row pgx.Row) (*Owner, error) {
+	var owner Owner
+
+	if err := row.Scan(&owner.UUID, &owner.Name, &owner.Version, &owner.Created_at, &owner.DeletedAt); err != nil {
+		return nil, err
+	}
+
+	return &owner, nil
+}
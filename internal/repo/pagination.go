@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
+
+// Cursor - позиция в выдаче: значения ключей сортировки и uuid записи.
+// Клиенту отдается только в закодированном виде.
+type Cursor struct {
+	Values []string `json:"v"`
+	UUID   string   `json:"u"`
+	// Sort - сортировка, для которой выдан курсор
+	Sort string `json:"s"`
+	// Backward - курсор указывает на предыдущую страницу
+	Backward bool `json:"b,omitempty"`
+}
+
+type PageRequest struct {
+	Limit     int
+	Cursor    *Cursor
+	WithTotal bool
+}
+
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+	Total      *int64
+}
+
+func EncodeCursor(cursor Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalidCursor, Entity: "cursor", Err: err}
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, &Error{Kind: ErrInvalidCursor, Entity: "cursor", Err: err}
+	}
+	if err := checkUUID("cursor", cursor.UUID); err != nil {
+		return nil, &Error{Kind: ErrInvalidCursor, Entity: "cursor", Err: err}
+	}
+
+	return &cursor, nil
+}
+
+// listPage читает одну страницу выборки по спецификации spec и схеме schema.
+// Запрашивается на одну строку больше, чтобы узнать, есть ли следующая страница.
+func listPage[T any](
+	ctx context.Context,
+	db querier,
+	schema Schema,
+	columns string,
+	spec ListSpec,
+	req PageRequest,
+	scan func(row pgx.Row) (T, error),
+	uuidOf func(item T) string,
+) (*Page[T], error) {
+	sorts := spec.Sorts
+	if len(sorts) == 0 {
+		sorts = schema.DefaultSort
+	}
+
+	if req.Cursor != nil && req.Cursor.Sort != SortKey(sorts) {
+		return nil, &Error{Kind: ErrInvalidCursor, Entity: schema.Table, Err: errors.New("cursor was issued for another sort order")}
+	}
+	backward := req.Cursor != nil && req.Cursor.Backward
+
+	b := &builder{}
+	conditions, err := schema.where(b, spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build filters")
+	}
+	filterArgs := len(b.args)
+	filters := conditions
+
+	if req.Cursor != nil {
+		keyset, err := schema.keyset(b, sorts, req.Cursor, backward)
+		if err != nil {
+			return nil, &Error{Kind: ErrInvalidCursor, Entity: schema.Table, Err: err}
+		}
+		conditions = append(conditions, keyset)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s %s ORDER BY %s LIMIT %s`,
+		columns, schema.from(), whereClause(conditions), schema.orderBy(sorts, backward), b.param(req.Limit+1, ""))
+
+	rows, err := db.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, schema.Table), "failed to query page")
+	}
+	defer rows.Close()
+
+	items := make([]T, 0, req.Limit+1)
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan page row")
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over page rows")
+	}
+
+	hasMore := len(items) > req.Limit
+	if hasMore {
+		items = items[:req.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) > 0 {
+		// Вперед: следующая страница есть, если нашлась лишняя строка; предыдущая - если пришли по курсору.
+		// Назад: наоборот.
+		hasNext, hasPrev := hasMore, req.Cursor != nil
+		if backward {
+			hasNext, hasPrev = true, hasMore
+		}
+
+		if hasNext {
+			last := items[len(items)-1]
+			page.NextCursor = EncodeCursor(schema.cursorFor(last, uuidOf(last), sorts))
+		}
+		if hasPrev {
+			prev := schema.cursorFor(items[0], uuidOf(items[0]), sorts)
+			prev.Backward = true
+			page.PrevCursor = EncodeCursor(prev)
+		}
+	}
+
+	if req.WithTotal {
+		var total int64
+		countQuery := fmt.Sprintf(`SELECT count(*) FROM %s %s`, schema.from(), whereClause(filters))
+		if err := db.QueryRow(ctx, countQuery, b.args[:filterArgs]...).Scan(&total); err != nil {
+			return nil, errors.Wrap(translate(err, schema.Table), "failed to count rows")
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
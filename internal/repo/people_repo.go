@@ -0,0 +1,339 @@
+package repo
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// Роли в титрах фильма
+const (
+	CreditDirector = "director"
+	CreditWriter   = "writer"
+	CreditActor    = "actor"
+	CreditProducer = "producer"
+)
+
+const (
+	insertPersonQuery = `INSERT INTO people (uuid, name, bio) VALUES ($1, $2, $3) RETURNING ` + personReturning
+	getPersonQuery    = `SELECT ` + personReturning + ` FROM people WHERE uuid = $1`
+	// Имена не уникальны; как и при переносе авторов в миграции, берется самый ранний человек с этим именем
+	findPersonByNameQuery = `SELECT ` + personReturning + ` FROM people WHERE name = $1 ORDER BY created_at, uuid LIMIT 1`
+	updatePersonQuery     = `UPDATE people SET name = $1, bio = $2 WHERE uuid = $3 RETURNING ` + personReturning
+	deletePersonQuery     = `DELETE FROM people WHERE uuid = $1`
+
+	getMovieCreditsQuery = `SELECT c.person_id, p.name, c.role, c.character_name, c.billing_order
+		FROM movie_credits c JOIN people p ON p.uuid = c.person_id
+		WHERE c.movie_id = $1 ORDER BY c.billing_order, c.role, p.name`
+	deleteMovieCreditsQuery = `DELETE FROM movie_credits WHERE movie_id = $1`
+	insertMovieCreditsQuery = `INSERT INTO movie_credits (movie_id, person_id, role, character_name, billing_order)
+		SELECT $1, * FROM unnest($2::uuid[], $3::text[], $4::text[], $5::int[])`
+	// author фильма - его режиссеры через запятую в порядке титров; без режиссеров - пустая строка
+	movieAuthorExpr = `coalesce((SELECT string_agg(p.name, ', ' ORDER BY c.billing_order, p.name)
+		FROM movie_credits c JOIN people p ON p.uuid = c.person_id WHERE c.movie_id = m.uuid AND c.role = 'director'), '')`
+	// old - та же строка до обновления: прежний author нужен для журнала
+	syncMovieAuthorQuery = `UPDATE movies m SET author = ` + movieAuthorExpr + ` FROM movies old
+		WHERE m.uuid = $1 AND old.uuid = m.uuid RETURNING old.author, m.author`
+	// Имя человека входит в титры фильма, поэтому переименование поднимает версию (ETag) всех его фильмов,
+	// а фильмам, где он режиссер, заодно меняет author. Фильмы в корзине пересчитываются при восстановлении
+	lockPersonMoviesQuery = `SELECT ` + movieReturning + ` FROM movies
+		WHERE uuid IN (SELECT movie_id FROM movie_credits WHERE person_id = $1) AND deleted_at IS NULL FOR UPDATE`
+	syncPersonMoviesQuery = `UPDATE movies m SET author = ` + movieAuthorExpr + `, version = version + 1
+		WHERE m.uuid IN (SELECT movie_id FROM movie_credits WHERE person_id = $1) AND m.deleted_at IS NULL
+		RETURNING ` + movieReturning
+	// В фильмографию попадают только действующие фильмы
+	getFilmographyQuery = `SELECT m.uuid, m.owner_id, m.title, m.author, m.description, m.year, m.version, m.created_at, m.deleted_at,
+			c.role, c.character_name, c.billing_order
+		FROM movie_credits c JOIN movies m ON m.uuid = c.movie_id
+		WHERE c.person_id = $1 AND m.deleted_at IS NULL ORDER BY m.year DESC, m.title, c.billing_order`
+
+	personReturning = `uuid, name, bio, created_at`
+)
+
+var peopleSchema = Schema{
+	Table: "people",
+	Fields: map[string]Field{
+		"name": {Column: "name", Type: "text", Sortable: true, Value: func(item any) string {
+			return item.(*Person).Name
+		}},
+		"created_at": {Column: "created_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*Person).Created_at)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "name"}},
+}
+
+// PeopleSchema - поля людей, доступные для фильтрации и сортировки.
+func PeopleSchema() Schema {
+	return peopleSchema
+}
+
+type PeopleRepository interface {
+	CreatePerson(ctx context.Context, person *Person) (*Person, error)
+	GetPeople(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Person], error)
+	GetPersonByID(ctx context.Context, uuid string) (*Person, error)
+	// FindPersonByName возвращает человека с именем name; если такого нет - ErrNotFound
+	FindPersonByName(ctx context.Context, name string) (*Person, error)
+	UpdatePerson(ctx context.Context, uuid string, person *Person) (*Person, error)
+	// DeletePerson удаляет человека без титров; при наличии титров - ErrConflict
+	DeletePerson(ctx context.Context, uuid string) error
+	GetFilmography(ctx context.Context, personID string) ([]*FilmographyEntry, error)
+	GetMovieCredits(ctx context.Context, movieID string) ([]*Credit, error)
+	// SetMovieCredits заменяет титры фильма и пересчитывает его author по режиссерам;
+	// неизвестный человек - ошибка ErrForeignKeyViolation
+	SetMovieCredits(ctx context.Context, movieID string, credits []*Credit) error
+}
+
+func (r *repository) CreatePerson(ctx context.Context, person *Person) (*Person, error) {
+	created, err := scanPerson(r.db.QueryRow(ctx, insertPersonQuery, uuid.New().String(), person.Name, person.Bio))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "person"), "failed to insert person")
+	}
+	return created, nil
+}
+
+func (r *repository) GetPeople(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Person], error) {
+	people, err := listPage(ctx, r.db, peopleSchema, personReturning, spec, page, scanPerson, func(person *Person) string {
+		return person.UUID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query people")
+	}
+
+	return people, nil
+}
+
+func (r *repository) GetPersonByID(ctx context.Context, uuid string) (*Person, error) {
+	if err := checkUUID("person", uuid); err != nil {
+		return nil, err
+	}
+
+	person, err := scanPerson(r.db.QueryRow(ctx, getPersonQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "person"), "failed to query person")
+	}
+	return person, nil
+}
+
+func (r *repository) FindPersonByName(ctx context.Context, name string) (*Person, error) {
+	person, err := scanPerson(r.db.QueryRow(ctx, findPersonByNameQuery, name))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "person"), "failed to query person by name")
+	}
+	return person, nil
+}
+
+func (r *repository) UpdatePerson(ctx context.Context, uuid string, person *Person) (*Person, error) {
+	if err := checkUUID("person", uuid); err != nil {
+		return nil, err
+	}
+
+	var updated *Person
+	err := r.withTx(ctx, func(tx *repository) error {
+		var err error
+		if updated, err = scanPerson(tx.db.QueryRow(ctx, updatePersonQuery, person.Name, person.Bio, uuid)); err != nil {
+			return errors.Wrap(translate(err, "person"), "failed to update person")
+		}
+
+		return tx.syncPersonMovies(ctx, uuid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// syncPersonMovies обновляет фильмы с титрами человека personID и пишет их изменение в журнал.
+func (r *repository) syncPersonMovies(ctx context.Context, personID string) error {
+	before, err := r.queryMovies(ctx, lockPersonMoviesQuery, personID)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock movies of person")
+	}
+
+	byUUID := make(map[string]*Movie, len(before))
+	for _, movie := range before {
+		byUUID[movie.UUID] = movie
+	}
+
+	after, err := r.queryMovies(ctx, syncPersonMoviesQuery, personID)
+	if err != nil {
+		return errors.Wrap(err, "failed to update movies of person")
+	}
+
+	for _, movie := range after {
+		if err := r.audit(ctx, AuditUpdate, "movie", movie.UUID, byUUID[movie.UUID], movie); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) DeletePerson(ctx context.Context, uuid string) error {
+	if err := checkUUID("person", uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, deletePersonQuery, uuid)
+	if err != nil {
+		err = translate(err, "person")
+		// Ссылка из movie_credits - это не отсутствующая запись, а конфликт с существующими титрами
+		if errors.Is(err, ErrForeignKeyViolation) {
+			return &Error{Kind: ErrConflict, Entity: "person", Err: errors.Wrap(err, "person has credits")}
+		}
+		return errors.Wrap(err, "failed to delete person")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("person"), "no rows deleted, person with given uuid not found")
+	}
+
+	return nil
+}
+
+func (r *repository) GetFilmography(ctx context.Context, personID string) ([]*FilmographyEntry, error) {
+	if _, err := r.GetPersonByID(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getFilmographyQuery, personID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "person"), "failed to query filmography")
+	}
+	defer rows.Close()
+
+	entries := make([]*FilmographyEntry, 0)
+	for rows.Next() {
+		var entry FilmographyEntry
+		movie := &entry.Movie
+		err := rows.Scan(&movie.UUID, &movie.OwnerID, &movie.Title, &movie.Author, &movie.Description, &movie.Year,
+			&movie.Version, &movie.Created_at, &movie.DeletedAt, &entry.Role, &entry.Character, &entry.BillingOrder)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan filmography entry")
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over filmography")
+	}
+
+	return entries, nil
+}
+
+func (r *repository) GetMovieCredits(ctx context.Context, movieID string) ([]*Credit, error) {
+	if err := checkUUID("movie", movieID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getMovieCreditsQuery, movieID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "movie"), "failed to query movie credits")
+	}
+	defer rows.Close()
+
+	credits := make([]*Credit, 0)
+	for rows.Next() {
+		var credit Credit
+		if err := rows.Scan(&credit.PersonID, &credit.PersonName, &credit.Role, &credit.Character, &credit.BillingOrder); err != nil {
+			return nil, errors.Wrap(err, "failed to scan movie credit")
+		}
+		credits = append(credits, &credit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over movie credits")
+	}
+
+	return credits, nil
+}
+
+func (r *repository) SetMovieCredits(ctx context.Context, movieID string, credits []*Credit) error {
+	if err := checkUUID("movie", movieID); err != nil {
+		return err
+	}
+	for _, credit := range credits {
+		if err := checkUUID("person", credit.PersonID); err != nil {
+			return err
+		}
+	}
+
+	return r.withTx(ctx, func(tx *repository) error {
+		current, err := tx.GetMovieCredits(ctx, movieID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.db.Exec(ctx, deleteMovieCreditsQuery, movieID); err != nil {
+			return errors.Wrap(translate(err, "movie"), "failed to clear movie credits")
+		}
+
+		people := make([]string, 0, len(credits))
+		roles := make([]string, 0, len(credits))
+		characters := make([]string, 0, len(credits))
+		orders := make([]int32, 0, len(credits))
+		for _, credit := range credits {
+			people = append(people, credit.PersonID)
+			roles = append(roles, credit.Role)
+			characters = append(characters, credit.Character)
+			orders = append(orders, int32(credit.BillingOrder))
+		}
+
+		if _, err := tx.db.Exec(ctx, insertMovieCreditsQuery, movieID, people, roles, characters, orders); err != nil {
+			return errors.Wrap(translate(err, "person"), "failed to insert movie credits")
+		}
+
+		var authorBefore, authorAfter string
+		if err := tx.db.QueryRow(ctx, syncMovieAuthorQuery, movieID).Scan(&authorBefore, &authorAfter); err != nil {
+			return errors.Wrap(translate(err, "movie"), "failed to update movie author")
+		}
+
+		// author меняется вслед за титрами уже после записи самого фильма в журнал, поэтому пишется здесь
+		before, after := creditSnapshot(current), creditSnapshot(credits)
+		if reflect.DeepEqual(before, after) && authorBefore == authorAfter {
+			return nil
+		}
+		return tx.audit(ctx, AuditUpdate, "movie", movieID,
+			map[string]any{"credits": before, "author": authorBefore}, map[string]any{"credits": after, "author": authorAfter})
+	})
+}
+
+// creditSnapshot приводит титры к виду для сравнения и журнала: без имен и в стабильном порядке.
+func creditSnapshot(credits []*Credit) []Credit {
+	snapshot := make([]Credit, 0, len(credits))
+	for _, credit := range credits {
+		c := *credit
+		c.PersonName = ""
+		snapshot = append(snapshot, c)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		a, b := snapshot[i], snapshot[j]
+		if a.BillingOrder != b.BillingOrder {
+			return a.BillingOrder < b.BillingOrder
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.PersonID != b.PersonID {
+			return a.PersonID < b.PersonID
+		}
+		return a.Character < b.Character
+	})
+
+	return snapshot
+}
+
+func scanPerson(row pgx.Row) (*Person, error) {
+	var person Person
+
+	if err := row.Scan(&person.UUID, &person.Name, &person.Bio, &person.Created_at); err != nil {
+		return nil, err
+	}
+
+	return &person, nil
+}
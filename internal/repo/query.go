@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// buildPatchQuery строит UPDATE только по переданным колонкам из белого списка.
+// Колонки сортируются, чтобы одинаковые наборы полей давали одинаковый SQL.
+// Последними аргументами запрос ожидает uuid и ожидаемую версию (0 - без проверки).
+func buildPatchQuery(table string, allowed map[string]bool, fields map[string]any, returning string) (string, []any, error) {
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		if !allowed[column] {
+			return "", nil, errors.Errorf("column %q of %s cannot be patched", column, table)
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, i+1))
+		args = append(args, fields[column])
+	}
+
+	sets = append(sets, "version = version + 1")
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE uuid = $%d AND deleted_at IS NULL AND %s RETURNING %s`,
+		table, strings.Join(sets, ", "), len(args)+1, versionCondition(len(args)+2), returning)
+
+	return query, args, nil
+}
+
+func versionCondition(param int) string {
+	return fmt.Sprintf("($%[1]d::int = 0 OR version = $%[1]d::int)", param)
+}
@@ -7,19 +7,50 @@ import (
 	pgxMigrate "github.com/golang-migrate/migrate/v4/database/pgx"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pkg/errors"
 	"streaming-service/internal/config"
 )
 
+// querier - общее подмножество методов пула и транзакции.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type repository struct {
 	pool *pgxpool.Pool
+	// db - пул или текущая транзакция, через него идут все запросы
+	db querier
+	// inTx - репозиторий привязан к транзакции, вложенный WithTx ее переиспользует
+	inTx bool
+}
+
+type Transactor interface {
+	// WithTx выполняет fn в одной транзакции: коммит при nil, откат при ошибке или панике.
+	WithTx(ctx context.Context, fn func(tx Repositories) error) error
 }
 
 type Repositories interface {
+	Transactor
 	MovieRepository
 	OwnerRepository
+	UserRepository
+	MemberRepository
+	APIKeyRepository
+	SearchRepository
+	SuggestRepository
+	TrashRepository
+	AuditRepository
+	TaxonomyRepository
+	PeopleRepository
+	SeriesRepository
+	TitleRepository
+	MediaAssetRepository
+	JobRepository
 }
 
 func NewRepository(ctx context.Context, cfg config.PostgreSQL) (Repositories, error) {
@@ -53,7 +84,24 @@ func NewRepository(ctx context.Context, cfg config.PostgreSQL) (Repositories, er
 		return nil, errors.Wrap(err, "failed to apply migrations")
 	}
 
-	return &repository{pool}, nil
+	return &repository{pool: pool, db: pool}, nil
+}
+
+func (r *repository) WithTx(ctx context.Context, fn func(tx Repositories) error) error {
+	return r.withTx(ctx, func(tx *repository) error {
+		return fn(tx)
+	})
+}
+
+// withTx - то же, что WithTx, но отдает сам репозиторий, чтобы внутри пакета выполнять запросы напрямую.
+func (r *repository) withTx(ctx context.Context, fn func(tx *repository) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		return fn(&repository{pool: r.pool, db: tx, inTx: true})
+	})
 }
 
 func applyMigrations(pool *pgxpool.Pool) error {
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Полнотекстовое совпадение ранжируется ts_rank, опечатки в названии ловятся триграммами.
+// Итоговый ранг - сумма обоих, так что точные совпадения всегда выше нечетких.
+const searchMoviesQuery = `WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query)
+	SELECT m.uuid, m.owner_id, m.title, m.author, m.description, m.year, m.version, m.created_at,
+		ts_rank(m.search_vector, q.query) + similarity(m.title, $1) AS rank,
+		ts_headline('simple', m.title, q.query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+		ts_headline('simple', coalesce(m.description, ''), q.query,
+			'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') AS snippet
+	FROM movies m, q
+	WHERE m.deleted_at IS NULL AND (m.search_vector @@ q.query OR m.title % $1)
+	ORDER BY rank DESC, m.uuid
+	LIMIT $2`
+
+type SearchRepository interface {
+	SearchMovies(ctx context.Context, query string, limit int) ([]*MovieSearchResult, error)
+}
+
+func (r *repository) SearchMovies(ctx context.Context, query string, limit int) ([]*MovieSearchResult, error) {
+	rows, err := r.db.Query(ctx, searchMoviesQuery, query, limit)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "movie"), "failed to execute search query")
+	}
+	defer rows.Close()
+
+	results := make([]*MovieSearchResult, 0, limit)
+	for rows.Next() {
+		var result MovieSearchResult
+		movie := &result.Movie
+
+		err := rows.Scan(&movie.UUID, &movie.OwnerID, &movie.Title, &movie.Author, &movie.Description, &movie.Year,
+			&movie.Version, &movie.Created_at, &result.Rank, &result.TitleHighlight, &result.Snippet)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan search result")
+		}
+		results = append(results, &result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over search results")
+	}
+
+	return results, nil
+}
@@ -0,0 +1,483 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	insertSeriesQuery = `INSERT INTO series (uuid, owner_id, title, description) VALUES ($1, $2, $3, $4) RETURNING ` + seriesReturning
+	// Сериалы удаленного владельца скрыты вместе с ним и возвращаются при его восстановлении
+	getSeriesQuery = `SELECT ` + seriesReturning + ` FROM series
+		WHERE uuid = $1 AND owner_id IN (SELECT uuid FROM owners WHERE deleted_at IS NULL)`
+	lockSeriesQuery   = getSeriesQuery + ` FOR UPDATE`
+	updateSeriesQuery = `UPDATE series SET title = $1, description = $2 WHERE uuid = $3 RETURNING ` + seriesReturning
+	deleteSeriesQuery = `DELETE FROM series WHERE uuid = $1`
+	// Медиафайлы эпизодов удаляются до сериала, сезона или эпизода, см. deleteAssets
+	seriesAssetsCondition = `episode_id IN (SELECT e.uuid FROM episodes e JOIN seasons sn ON sn.uuid = e.season_id
+		WHERE sn.series_id = $1)`
+	seasonAssetsCondition  = `episode_id IN (SELECT uuid FROM episodes WHERE season_id = $1)`
+	episodeAssetsCondition = `episode_id = $1`
+
+	insertSeasonQuery = `INSERT INTO seasons (uuid, series_id, number, title, air_date) VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + seasonReturning
+	getSeasonsQuery   = `SELECT ` + seasonReturning + ` FROM seasons WHERE series_id = $1 ORDER BY number`
+	getSeasonQuery    = `SELECT ` + seasonReturning + ` FROM seasons WHERE series_id = $1 AND number = $2`
+	lockSeasonQuery   = getSeasonQuery + ` FOR UPDATE`
+	updateSeasonQuery = `UPDATE seasons SET number = $1, title = $2, air_date = $3 WHERE uuid = $4 RETURNING ` + seasonReturning
+	deleteSeasonQuery = `DELETE FROM seasons WHERE uuid = $1`
+
+	insertEpisodeQuery = `INSERT INTO episodes (uuid, season_id, number, title, description, air_date, runtime_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	episodeSelect = `SELECT e.uuid, sr.owner_id, sn.series_id, e.season_id, sn.number, e.number, e.title, e.description,
+			e.air_date, e.runtime_minutes, e.created_at
+		FROM episodes e JOIN seasons sn ON sn.uuid = e.season_id JOIN series sr ON sr.uuid = sn.series_id`
+	getEpisodesQuery     = episodeSelect + ` WHERE sn.series_id = $1 AND sn.number = $2 ORDER BY e.number`
+	getEpisodeQuery      = episodeSelect + ` WHERE sn.series_id = $1 AND sn.number = $2 AND e.number = $3`
+	lockEpisodeQuery     = getEpisodeQuery + ` FOR UPDATE OF e`
+	getEpisodeByIDQuery  = episodeSelect + ` WHERE e.uuid = $1 AND sr.owner_id IN (SELECT uuid FROM owners WHERE deleted_at IS NULL)`
+	updateEpisodeQuery   = `UPDATE episodes SET number = $1, title = $2, description = $3, air_date = $4, runtime_minutes = $5 WHERE uuid = $6`
+	deleteEpisodeQuery   = `DELETE FROM episodes WHERE uuid = $1`
+	seriesReturning      = `uuid, owner_id, title, description, created_at`
+	seasonReturning      = `uuid, series_id, number, title, air_date, created_at`
+	aliveSeriesCondition = `owner_id IN (SELECT uuid FROM owners WHERE deleted_at IS NULL)`
+)
+
+var seriesSchema = Schema{
+	Table: "series",
+	Fields: map[string]Field{
+		"owner_id": {Column: "owner_id", Type: "uuid"},
+		"title": {Column: "title", Type: "text", Sortable: true, Value: func(item any) string {
+			return item.(*Series).Title
+		}},
+		"created_at": {Column: "created_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*Series).Created_at)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "created_at"}},
+}
+
+// SeriesSchema - поля сериалов, доступные для фильтрации и сортировки.
+func SeriesSchema() Schema {
+	return seriesSchema
+}
+
+// SeriesRepository - сериалы и вложенные в них сезоны и эпизоды.
+// Сезон адресуется номером в сериале, эпизод - номером в сезоне. Удаление возвращает удаленные
+// медиафайлы эпизодов: их файлы в хранилище удаляет вызывающий.
+type SeriesRepository interface {
+	CreateSeries(ctx context.Context, series *Series) (*Series, error)
+	GetAllSeries(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Series], error)
+	GetSeriesByID(ctx context.Context, uuid string) (*Series, error)
+	UpdateSeries(ctx context.Context, uuid string, series *Series) (*Series, error)
+	DeleteSeries(ctx context.Context, uuid string) ([]*MediaAsset, error)
+
+	CreateSeason(ctx context.Context, season *Season) (*Season, error)
+	GetSeasons(ctx context.Context, seriesID string) ([]*Season, error)
+	GetSeason(ctx context.Context, seriesID string, number int) (*Season, error)
+	UpdateSeason(ctx context.Context, seriesID string, number int, season *Season) (*Season, error)
+	DeleteSeason(ctx context.Context, seriesID string, number int) ([]*MediaAsset, error)
+
+	CreateEpisode(ctx context.Context, seriesID string, seasonNumber int, episode *Episode) (*Episode, error)
+	GetEpisodes(ctx context.Context, seriesID string, seasonNumber int) ([]*Episode, error)
+	GetEpisode(ctx context.Context, seriesID string, seasonNumber, number int) (*Episode, error)
+	GetEpisodeByID(ctx context.Context, uuid string) (*Episode, error)
+	UpdateEpisode(ctx context.Context, seriesID string, seasonNumber, number int, episode *Episode) (*Episode, error)
+	DeleteEpisode(ctx context.Context, seriesID string, seasonNumber, number int) ([]*MediaAsset, error)
+}
+
+func (r *repository) CreateSeries(ctx context.Context, series *Series) (*Series, error) {
+	var created *Series
+
+	err := r.withTx(ctx, func(tx *repository) error {
+		var err error
+		created, err = scanSeries(tx.db.QueryRow(ctx, insertSeriesQuery, uuid.New().String(), series.OwnerID, series.Title,
+			series.Description))
+		if err != nil {
+			return errors.Wrap(translate(err, "series"), "failed to insert series")
+		}
+
+		return tx.audit(ctx, AuditCreate, "series", created.UUID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *repository) GetAllSeries(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*Series], error) {
+	spec.Where = append(spec.Where, Condition{SQL: aliveSeriesCondition})
+
+	series, err := listPage(ctx, r.db, seriesSchema, seriesReturning, spec, page, scanSeries, func(series *Series) string {
+		return series.UUID
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query all series")
+	}
+
+	return series, nil
+}
+
+func (r *repository) GetSeriesByID(ctx context.Context, uuid string) (*Series, error) {
+	if err := checkUUID("series", uuid); err != nil {
+		return nil, err
+	}
+
+	series, err := scanSeries(r.db.QueryRow(ctx, getSeriesQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "series"), "failed to query series")
+	}
+	return series, nil
+}
+
+func (r *repository) UpdateSeries(ctx context.Context, uuid string, series *Series) (*Series, error) {
+	if err := checkUUID("series", uuid); err != nil {
+		return nil, err
+	}
+
+	var updated *Series
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanSeries(tx.db.QueryRow(ctx, lockSeriesQuery, uuid))
+		if err != nil {
+			return errors.Wrap(translate(err, "series"), "failed to lock series")
+		}
+
+		updated, err = scanSeries(tx.db.QueryRow(ctx, updateSeriesQuery, series.Title, series.Description, uuid))
+		if err != nil {
+			return errors.Wrap(translate(err, "series"), "failed to execute update query")
+		}
+
+		return tx.audit(ctx, AuditUpdate, "series", uuid, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteSeries удаляет сериал окончательно вместе с сезонами и эпизодами.
+func (r *repository) DeleteSeries(ctx context.Context, uuid string) ([]*MediaAsset, error) {
+	if err := checkUUID("series", uuid); err != nil {
+		return nil, err
+	}
+
+	var assets []*MediaAsset
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanSeries(tx.db.QueryRow(ctx, lockSeriesQuery, uuid))
+		if err != nil {
+			return errors.Wrap(translate(err, "series"), "failed to lock series")
+		}
+
+		if assets, err = tx.deleteAssets(ctx, seriesAssetsCondition, uuid); err != nil {
+			return err
+		}
+
+		if _, err := tx.db.Exec(ctx, deleteSeriesQuery, uuid); err != nil {
+			return errors.Wrap(translate(err, "series"), "failed to execute delete query")
+		}
+
+		return tx.audit(ctx, AuditDelete, "series", uuid, before, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (r *repository) CreateSeason(ctx context.Context, season *Season) (*Season, error) {
+	if err := checkUUID("series", season.SeriesID); err != nil {
+		return nil, err
+	}
+
+	var created *Season
+	err := r.withTx(ctx, func(tx *repository) error {
+		var err error
+		created, err = scanSeason(tx.db.QueryRow(ctx, insertSeasonQuery, uuid.New().String(), season.SeriesID, season.Number,
+			season.Title, season.AirDate))
+		if err != nil {
+			return errors.Wrap(translate(err, "season"), "failed to insert season")
+		}
+
+		return tx.audit(ctx, AuditCreate, "season", created.UUID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *repository) GetSeasons(ctx context.Context, seriesID string) ([]*Season, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getSeasonsQuery, seriesID)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "season"), "failed to query seasons")
+	}
+	defer rows.Close()
+
+	seasons := make([]*Season, 0)
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan season")
+		}
+		seasons = append(seasons, season)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over seasons")
+	}
+
+	return seasons, nil
+}
+
+func (r *repository) GetSeason(ctx context.Context, seriesID string, number int) (*Season, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	season, err := scanSeason(r.db.QueryRow(ctx, getSeasonQuery, seriesID, number))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "season"), "failed to query season")
+	}
+	return season, nil
+}
+
+func (r *repository) UpdateSeason(ctx context.Context, seriesID string, number int, season *Season) (*Season, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	var updated *Season
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanSeason(tx.db.QueryRow(ctx, lockSeasonQuery, seriesID, number))
+		if err != nil {
+			return errors.Wrap(translate(err, "season"), "failed to lock season")
+		}
+
+		updated, err = scanSeason(tx.db.QueryRow(ctx, updateSeasonQuery, season.Number, season.Title, season.AirDate, before.UUID))
+		if err != nil {
+			return errors.Wrap(translate(err, "season"), "failed to execute update query")
+		}
+
+		return tx.audit(ctx, AuditUpdate, "season", before.UUID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *repository) DeleteSeason(ctx context.Context, seriesID string, number int) ([]*MediaAsset, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	var assets []*MediaAsset
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanSeason(tx.db.QueryRow(ctx, lockSeasonQuery, seriesID, number))
+		if err != nil {
+			return errors.Wrap(translate(err, "season"), "failed to lock season")
+		}
+
+		if assets, err = tx.deleteAssets(ctx, seasonAssetsCondition, before.UUID); err != nil {
+			return err
+		}
+
+		if _, err := tx.db.Exec(ctx, deleteSeasonQuery, before.UUID); err != nil {
+			return errors.Wrap(translate(err, "season"), "failed to execute delete query")
+		}
+
+		return tx.audit(ctx, AuditDelete, "season", before.UUID, before, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (r *repository) CreateEpisode(ctx context.Context, seriesID string, seasonNumber int, episode *Episode) (*Episode, error) {
+	var created *Episode
+
+	err := r.withTx(ctx, func(tx *repository) error {
+		season, err := tx.GetSeason(ctx, seriesID, seasonNumber)
+		if err != nil {
+			return err
+		}
+
+		episodeID := uuid.New().String()
+		_, err = tx.db.Exec(ctx, insertEpisodeQuery, episodeID, season.UUID, episode.Number, episode.Title, episode.Description,
+			episode.AirDate, episode.RuntimeMinutes)
+		if err != nil {
+			return errors.Wrap(translate(err, "episode"), "failed to insert episode")
+		}
+
+		if created, err = tx.GetEpisodeByID(ctx, episodeID); err != nil {
+			return err
+		}
+		return tx.audit(ctx, AuditCreate, "episode", episodeID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *repository) GetEpisodes(ctx context.Context, seriesID string, seasonNumber int) ([]*Episode, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	// Пустой список несуществующего сезона отличаем от пустого сезона
+	if _, err := r.GetSeason(ctx, seriesID, seasonNumber); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getEpisodesQuery, seriesID, seasonNumber)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "episode"), "failed to query episodes")
+	}
+	defer rows.Close()
+
+	episodes := make([]*Episode, 0)
+	for rows.Next() {
+		episode, err := scanEpisode(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan episode")
+		}
+		episodes = append(episodes, episode)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over episodes")
+	}
+
+	return episodes, nil
+}
+
+func (r *repository) GetEpisode(ctx context.Context, seriesID string, seasonNumber, number int) (*Episode, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	episode, err := scanEpisode(r.db.QueryRow(ctx, getEpisodeQuery, seriesID, seasonNumber, number))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "episode"), "failed to query episode")
+	}
+	return episode, nil
+}
+
+func (r *repository) GetEpisodeByID(ctx context.Context, uuid string) (*Episode, error) {
+	if err := checkUUID("episode", uuid); err != nil {
+		return nil, err
+	}
+
+	episode, err := scanEpisode(r.db.QueryRow(ctx, getEpisodeByIDQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "episode"), "failed to query episode")
+	}
+	return episode, nil
+}
+
+func (r *repository) UpdateEpisode(ctx context.Context, seriesID string, seasonNumber, number int, episode *Episode) (*Episode, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	var updated *Episode
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanEpisode(tx.db.QueryRow(ctx, lockEpisodeQuery, seriesID, seasonNumber, number))
+		if err != nil {
+			return errors.Wrap(translate(err, "episode"), "failed to lock episode")
+		}
+
+		_, err = tx.db.Exec(ctx, updateEpisodeQuery, episode.Number, episode.Title, episode.Description, episode.AirDate,
+			episode.RuntimeMinutes, before.UUID)
+		if err != nil {
+			return errors.Wrap(translate(err, "episode"), "failed to execute update query")
+		}
+
+		if updated, err = tx.GetEpisodeByID(ctx, before.UUID); err != nil {
+			return err
+		}
+		return tx.audit(ctx, AuditUpdate, "episode", before.UUID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *repository) DeleteEpisode(ctx context.Context, seriesID string, seasonNumber, number int) ([]*MediaAsset, error) {
+	if err := checkUUID("series", seriesID); err != nil {
+		return nil, err
+	}
+
+	var assets []*MediaAsset
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := scanEpisode(tx.db.QueryRow(ctx, lockEpisodeQuery, seriesID, seasonNumber, number))
+		if err != nil {
+			return errors.Wrap(translate(err, "episode"), "failed to lock episode")
+		}
+
+		if assets, err = tx.deleteAssets(ctx, episodeAssetsCondition, before.UUID); err != nil {
+			return err
+		}
+
+		if _, err := tx.db.Exec(ctx, deleteEpisodeQuery, before.UUID); err != nil {
+			return errors.Wrap(translate(err, "episode"), "failed to execute delete query")
+		}
+
+		return tx.audit(ctx, AuditDelete, "episode", before.UUID, before, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func scanSeries(row pgx.Row) (*Series, error) {
+	var series Series
+
+	if err := row.Scan(&series.UUID, &series.OwnerID, &series.Title, &series.Description, &series.Created_at); err != nil {
+		return nil, err
+	}
+
+	return &series, nil
+}
+
+func scanSeason(row pgx.Row) (*Season, error) {
+	var season Season
+
+	if err := row.Scan(&season.UUID, &season.SeriesID, &season.Number, &season.Title, &season.AirDate, &season.Created_at); err != nil {
+		return nil, err
+	}
+
+	return &season, nil
+}
+
+func scanEpisode(row pgx.Row) (*Episode, error) {
+	var episode Episode
+
+	err := row.Scan(&episode.UUID, &episode.OwnerID, &episode.SeriesID, &episode.SeasonID, &episode.SeasonNumber, &episode.Number,
+		&episode.Title, &episode.Description, &episode.AirDate, &episode.RuntimeMinutes, &episode.Created_at)
+	if err != nil {
+		return nil, err
+	}
+
+	return &episode, nil
+}
@@ -0,0 +1,240 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Op string
+
+const (
+	OpEq  Op = "="
+	OpGt  Op = ">"
+	OpGte Op = ">="
+	OpLte Op = "<="
+	// OpIEq - равенство без учета регистра
+	OpIEq Op = "ieq"
+)
+
+// Field - колонка (или выражение), доступная для фильтрации и сортировки.
+// Type используется для приведения параметров, в том числе значений из курсора.
+type Field struct {
+	Column   string
+	Type     string
+	Sortable bool
+	// Value достает значение поля из записи для курсора
+	Value func(item any) string
+}
+
+// Schema - белый список полей сущности. Ничего, кроме описанного здесь, в SQL не попадает.
+type Schema struct {
+	Table string
+	// From - источник строк, если это не сама таблица (например, подзапрос)
+	From string
+	// SoftDelete - в выборку попадают только строки с deleted_at IS NULL
+	SoftDelete bool
+	Fields     map[string]Field
+	// DefaultSort применяется, если клиент не передал sort
+	DefaultSort []Sort
+}
+
+type Filter struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ListSpec - фильтры и сортировка выборки.
+type ListSpec struct {
+	Filters []Filter
+	Sorts   []Sort
+	// Where - дополнительные условия, которые не выражаются через поля схемы
+	Where []Condition
+}
+
+// Condition - произвольное условие, в котором каждый '$?' заменяется очередным параметром.
+type Condition struct {
+	SQL  string
+	Args []any
+}
+
+// ParseSort разбирает строку вида "title,-year,created_at".
+func (s Schema) ParseSort(value string) ([]Sort, error) {
+	var sorts []Sort
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		sort := Sort{Field: part}
+		if strings.HasPrefix(part, "-") {
+			sort = Sort{Field: part[1:], Desc: true}
+		}
+
+		field, ok := s.Fields[sort.Field]
+		if !ok || !field.Sortable {
+			return nil, errors.Errorf("field %q is not sortable", sort.Field)
+		}
+		if seen[sort.Field] {
+			return nil, errors.Errorf("field %q is listed twice", sort.Field)
+		}
+		seen[sort.Field] = true
+
+		sorts = append(sorts, sort)
+	}
+
+	return sorts, nil
+}
+
+// SortKey - каноничное представление сортировки, сохраняется в курсоре.
+func SortKey(sorts []Sort) string {
+	parts := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		if sort.Desc {
+			parts = append(parts, "-"+sort.Field)
+		} else {
+			parts = append(parts, sort.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// builder собирает параметризованный SQL, нумеруя параметры по мере добавления.
+type builder struct {
+	args []any
+}
+
+func (b *builder) param(value any, sqlType string) string {
+	b.args = append(b.args, value)
+	if sqlType == "" {
+		return fmt.Sprintf("$%d", len(b.args))
+	}
+	return fmt.Sprintf("$%d::%s", len(b.args), sqlType)
+}
+
+func (b *builder) condition(cond Condition) string {
+	sql := cond.SQL
+	for _, arg := range cond.Args {
+		sql = strings.Replace(sql, "$?", b.param(arg, ""), 1)
+	}
+	return sql
+}
+
+// where строит условия фильтров спецификации.
+func (s Schema) where(b *builder, spec ListSpec) ([]string, error) {
+	conditions := make([]string, 0, len(spec.Filters)+len(spec.Where)+1)
+	if s.SoftDelete {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	for _, filter := range spec.Filters {
+		field, ok := s.Fields[filter.Field]
+		if !ok {
+			return nil, errors.Errorf("field %q cannot be filtered", filter.Field)
+		}
+
+		switch filter.Op {
+		case OpEq, OpGt, OpGte, OpLte:
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", field.Column, filter.Op, b.param(filter.Value, field.Type)))
+		case OpIEq:
+			conditions = append(conditions, fmt.Sprintf("lower(%s) = lower(%s)", field.Column, b.param(filter.Value, "text")))
+		default:
+			return nil, errors.Errorf("unsupported filter operator %q", filter.Op)
+		}
+	}
+
+	for _, cond := range spec.Where {
+		conditions = append(conditions, b.condition(cond))
+	}
+
+	return conditions, nil
+}
+
+// keyset строит условие "строго после курсора" для произвольной сортировки:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... с учетом направления каждого ключа.
+// Последним ключом всегда идет uuid по возрастанию - он делает порядок однозначным.
+func (s Schema) keyset(b *builder, sorts []Sort, cursor *Cursor, backward bool) (string, error) {
+	if len(cursor.Values) != len(sorts) {
+		return "", errors.New("cursor does not match sort order")
+	}
+
+	type key struct {
+		column string
+		value  string
+		typ    string
+		desc   bool
+	}
+	keys := make([]key, 0, len(sorts)+1)
+	for i, sort := range sorts {
+		field := s.Fields[sort.Field]
+		keys = append(keys, key{column: field.Column, value: cursor.Values[i], typ: field.Type, desc: sort.Desc})
+	}
+	keys = append(keys, key{column: "uuid", value: cursor.UUID, typ: "uuid"})
+
+	alternatives := make([]string, 0, len(keys))
+	for i, k := range keys {
+		parts := make([]string, 0, i+1)
+		for _, prev := range keys[:i] {
+			parts = append(parts, fmt.Sprintf("%s = %s", prev.column, b.param(prev.value, "text::"+prev.typ)))
+		}
+
+		op := ">"
+		if k.desc != backward {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", k.column, op, b.param(k.value, "text::"+k.typ)))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+func (s Schema) from() string {
+	if s.From != "" {
+		return s.From
+	}
+	return s.Table
+}
+
+func (s Schema) orderBy(sorts []Sort, backward bool) string {
+	parts := make([]string, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		dir := "ASC"
+		if sort.Desc != backward {
+			dir = "DESC"
+		}
+		parts = append(parts, s.Fields[sort.Field].Column+" "+dir)
+	}
+
+	dir := "ASC"
+	if backward {
+		dir = "DESC"
+	}
+	return strings.Join(append(parts, "uuid "+dir), ", ")
+}
+
+// cursorFor формирует курсор, указывающий на запись item.
+func (s Schema) cursorFor(item any, uuid string, sorts []Sort) Cursor {
+	values := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		values = append(values, s.Fields[sort.Field].Value(item))
+	}
+	return Cursor{Values: values, UUID: uuid, Sort: SortKey(sorts)}
+}
+
+// formatTime - формат времени в курсоре, Postgres разбирает его обратно в timestamp без потерь.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
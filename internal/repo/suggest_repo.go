@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Совпадения по префиксу идут первыми, дальше - по убыванию триграммного сходства.
+const suggestQuery = `SELECT type, uuid, label FROM (
+		SELECT 'movie' AS type, uuid, title AS label,
+			title ILIKE $2 AS prefix_match, similarity(title, $1) AS score
+		FROM movies WHERE deleted_at IS NULL AND (title ILIKE $2 OR title % $1)
+		UNION ALL
+		SELECT 'owner' AS type, uuid, name AS label,
+			name ILIKE $2 AS prefix_match, similarity(name, $1) AS score
+		FROM owners WHERE deleted_at IS NULL AND (name ILIKE $2 OR name % $1)
+	) s
+	ORDER BY prefix_match DESC, score DESC, label, uuid
+	LIMIT $3`
+
+const (
+	SuggestionMovie = "movie"
+	SuggestionOwner = "owner"
+)
+
+type SuggestRepository interface {
+	Suggest(ctx context.Context, prefix string, limit int) ([]*Suggestion, error)
+}
+
+func (r *repository) Suggest(ctx context.Context, prefix string, limit int) ([]*Suggestion, error) {
+	rows, err := r.db.Query(ctx, suggestQuery, prefix, likePrefix(prefix), limit)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "suggestion"), "failed to execute suggest query")
+	}
+	defer rows.Close()
+
+	suggestions := make([]*Suggestion, 0, limit)
+	for rows.Next() {
+		var suggestion Suggestion
+		if err := rows.Scan(&suggestion.Type, &suggestion.UUID, &suggestion.Label); err != nil {
+			return nil, errors.Wrap(err, "failed to scan suggestion")
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error occurred during iteration over suggestions")
+	}
+
+	return suggestions, nil
+}
+
+// likePrefix экранирует спецсимволы LIKE, чтобы префикс сравнивался буквально.
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	return escaped + "%"
+}
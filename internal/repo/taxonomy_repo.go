@@ -0,0 +1,255 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+// Taxonomy описывает словарь (жанры, теги) и его связь с фильмами.
+// Запросы собираются только из этих описаний, пользовательский ввод в имена таблиц не попадает.
+type Taxonomy struct {
+	Table      string
+	JoinTable  string
+	JoinColumn string
+	Entity     string
+}
+
+var (
+	Genres = Taxonomy{Table: "genres", JoinTable: "movie_genres", JoinColumn: "genre_id", Entity: "genre"}
+	Tags   = Taxonomy{Table: "tags", JoinTable: "movie_tags", JoinColumn: "tag_id", Entity: "tag"}
+)
+
+const termColumns = `uuid, slug, name, created_at`
+
+type TaxonomyRepository interface {
+	CreateTerm(ctx context.Context, taxonomy Taxonomy, term *Term) (*Term, error)
+	GetTerms(ctx context.Context, taxonomy Taxonomy) ([]*Term, error)
+	GetTerm(ctx context.Context, taxonomy Taxonomy, slug string) (*Term, error)
+	UpdateTerm(ctx context.Context, taxonomy Taxonomy, slug string, term *Term) (*Term, error)
+	DeleteTerm(ctx context.Context, taxonomy Taxonomy, slug string) error
+	GetMovieTerms(ctx context.Context, taxonomy Taxonomy, movieID string) ([]*Term, error)
+	// SetMovieTerms заменяет набор терминов фильма; неизвестный slug - ошибка ErrForeignKeyViolation
+	SetMovieTerms(ctx context.Context, taxonomy Taxonomy, movieID string, slugs []string) error
+}
+
+func (r *repository) CreateTerm(ctx context.Context, taxonomy Taxonomy, term *Term) (*Term, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (uuid, slug, name) VALUES ($1, $2, $3) RETURNING %s`, taxonomy.Table, termColumns)
+
+	created, err := scanTerm(r.db.QueryRow(ctx, query, uuid.New().String(), term.Slug, term.Name))
+	if err != nil {
+		return nil, errors.Wrapf(translate(err, taxonomy.Entity), "failed to insert %s", taxonomy.Entity)
+	}
+	return created, nil
+}
+
+func (r *repository) GetTerms(ctx context.Context, taxonomy Taxonomy) ([]*Term, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY name, slug`, termColumns, taxonomy.Table)
+
+	terms, err := r.queryTerms(ctx, query)
+	if err != nil {
+		return nil, errors.Wrapf(translate(err, taxonomy.Entity), "failed to query %s list", taxonomy.Entity)
+	}
+	return terms, nil
+}
+
+func (r *repository) GetTerm(ctx context.Context, taxonomy Taxonomy, slug string) (*Term, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE slug = $1`, termColumns, taxonomy.Table)
+
+	term, err := scanTerm(r.db.QueryRow(ctx, query, slug))
+	if err != nil {
+		return nil, errors.Wrapf(translate(err, taxonomy.Entity), "failed to query %s", taxonomy.Entity)
+	}
+	return term, nil
+}
+
+func (r *repository) UpdateTerm(ctx context.Context, taxonomy Taxonomy, slug string, term *Term) (*Term, error) {
+	query := fmt.Sprintf(`UPDATE %s SET slug = $1, name = $2 WHERE slug = $3 RETURNING %s`, taxonomy.Table, termColumns)
+
+	var updated *Term
+	err := r.withTx(ctx, func(tx *repository) error {
+		var err error
+		if updated, err = scanTerm(tx.db.QueryRow(ctx, query, term.Slug, term.Name, slug)); err != nil {
+			return errors.Wrapf(translate(err, taxonomy.Entity), "failed to update %s", taxonomy.Entity)
+		}
+		return tx.bumpTermMovies(ctx, taxonomy, updated.UUID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *repository) DeleteTerm(ctx context.Context, taxonomy Taxonomy, slug string) error {
+	lockQuery := fmt.Sprintf(`SELECT uuid FROM %s WHERE slug = $1 FOR UPDATE`, taxonomy.Table)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE uuid = $1`, taxonomy.Table)
+
+	return r.withTx(ctx, func(tx *repository) error {
+		// Версии поднимаются до удаления: связи с фильмами уходят вместе с термином
+		var termID string
+		if err := tx.db.QueryRow(ctx, lockQuery, slug).Scan(&termID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.Wrapf(notFound(taxonomy.Entity), "no rows deleted, %s with given slug not found", taxonomy.Entity)
+			}
+			return errors.Wrapf(translate(err, taxonomy.Entity), "failed to lock %s", taxonomy.Entity)
+		}
+		if err := tx.bumpTermMovies(ctx, taxonomy, termID); err != nil {
+			return err
+		}
+
+		if _, err := tx.db.Exec(ctx, query, termID); err != nil {
+			return errors.Wrapf(translate(err, taxonomy.Entity), "failed to delete %s", taxonomy.Entity)
+		}
+		return nil
+	})
+}
+
+// bumpTermMovies поднимает версию фильмов с термином termID: термины входят в представление фильма,
+// и без этого ETag фильма не изменился бы после переименования или удаления термина.
+func (r *repository) bumpTermMovies(ctx context.Context, taxonomy Taxonomy, termID string) error {
+	query := fmt.Sprintf(`UPDATE movies SET version = version + 1 WHERE uuid IN (SELECT movie_id FROM %s WHERE %s = $1)`,
+		taxonomy.JoinTable, taxonomy.JoinColumn)
+
+	if _, err := r.db.Exec(ctx, query, termID); err != nil {
+		return errors.Wrap(translate(err, "movie"), "failed to bump movie versions")
+	}
+	return nil
+}
+
+func (r *repository) GetMovieTerms(ctx context.Context, taxonomy Taxonomy, movieID string) ([]*Term, error) {
+	if err := checkUUID("movie", movieID); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT t.uuid, t.slug, t.name, t.created_at FROM %s t JOIN %s j ON j.%s = t.uuid
+		WHERE j.movie_id = $1 ORDER BY t.name, t.slug`, taxonomy.Table, taxonomy.JoinTable, taxonomy.JoinColumn)
+
+	terms, err := r.queryTerms(ctx, query, movieID)
+	if err != nil {
+		return nil, errors.Wrapf(translate(err, taxonomy.Entity), "failed to query %s list of movie", taxonomy.Entity)
+	}
+	return terms, nil
+}
+
+func (r *repository) SetMovieTerms(ctx context.Context, taxonomy Taxonomy, movieID string, slugs []string) error {
+	if err := checkUUID("movie", movieID); err != nil {
+		return err
+	}
+
+	slugs = uniqueSorted(slugs)
+
+	return r.withTx(ctx, func(tx *repository) error {
+		current, err := tx.GetMovieTerms(ctx, taxonomy, movieID)
+		if err != nil {
+			return err
+		}
+
+		terms, err := tx.queryTerms(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE slug = ANY($1)`, termColumns, taxonomy.Table), slugs)
+		if err != nil {
+			return errors.Wrapf(translate(err, taxonomy.Entity), "failed to resolve %s slugs", taxonomy.Entity)
+		}
+		if len(terms) != len(slugs) {
+			return &Error{Kind: ErrForeignKeyViolation, Entity: taxonomy.Entity,
+				Err: errors.Errorf("unknown %s slugs in %v", taxonomy.Entity, slugs)}
+		}
+
+		ids := make([]string, 0, len(terms))
+		for _, term := range terms {
+			ids = append(ids, term.UUID)
+		}
+
+		deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE movie_id = $1`, taxonomy.JoinTable)
+		if _, err := tx.db.Exec(ctx, deleteQuery, movieID); err != nil {
+			return errors.Wrapf(translate(err, taxonomy.Entity), "failed to clear %s list of movie", taxonomy.Entity)
+		}
+
+		insertQuery := fmt.Sprintf(`INSERT INTO %s (movie_id, %s) SELECT $1, unnest($2::uuid[])`,
+			taxonomy.JoinTable, taxonomy.JoinColumn)
+		if _, err := tx.db.Exec(ctx, insertQuery, movieID, ids); err != nil {
+			return errors.Wrapf(translate(err, "movie"), "failed to assign %s list to movie", taxonomy.Entity)
+		}
+
+		before := termSlugs(current)
+		if equalStrings(before, slugs) {
+			return nil
+		}
+		return tx.audit(ctx, AuditUpdate, "movie", movieID,
+			map[string]any{taxonomy.Table: before}, map[string]any{taxonomy.Table: slugs})
+	})
+}
+
+// MovieHasTerm ограничивает выборку фильмов теми, у которых есть термин slug.
+func MovieHasTerm(taxonomy Taxonomy, slug string) Condition {
+	return Condition{
+		SQL: fmt.Sprintf(`EXISTS (SELECT 1 FROM %s j JOIN %s t ON t.uuid = j.%s WHERE j.movie_id = movies.uuid AND t.slug = $?)`,
+			taxonomy.JoinTable, taxonomy.Table, taxonomy.JoinColumn),
+		Args: []any{slug},
+	}
+}
+
+func (r *repository) queryTerms(ctx context.Context, query string, args ...any) ([]*Term, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := make([]*Term, 0)
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, rows.Err()
+}
+
+func scanTerm(row pgx.Row) (*Term, error) {
+	var term Term
+
+	if err := row.Scan(&term.UUID, &term.Slug, &term.Name, &term.Created_at); err != nil {
+		return nil, err
+	}
+
+	return &term, nil
+}
+
+func termSlugs(terms []*Term) []string {
+	slugs := make([]string, 0, len(terms))
+	for _, term := range terms {
+		slugs = append(slugs, term.Slug)
+	}
+	sort.Strings(slugs)
+	return slugs
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Виды воспроизводимых единиц каталога
+const (
+	TitleMovie   = "movie"
+	TitleEpisode = "episode"
+)
+
+const getTitleQuery = `SELECT kind, uuid, owner_id, label FROM titles WHERE uuid = $1 AND ($2 = '' OR kind = $2)`
+
+// TitleRepository - общий доступ к фильмам и эпизодам там, где их различие неважно:
+// воспроизведение, медиафайлы, права по владельцу.
+type TitleRepository interface {
+	// GetTitle ищет действующую единицу каталога; пустой kind - любого вида
+	GetTitle(ctx context.Context, kind, uuid string) (*Title, error)
+}
+
+func (r *repository) GetTitle(ctx context.Context, kind, uuid string) (*Title, error) {
+	entity := kind
+	if entity == "" {
+		entity = "title"
+	}
+	if err := checkUUID(entity, uuid); err != nil {
+		return nil, err
+	}
+
+	var title Title
+	err := r.db.QueryRow(ctx, getTitleQuery, uuid, kind).Scan(&title.Kind, &title.UUID, &title.OwnerID, &title.Label)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, entity), "failed to query title")
+	}
+
+	return &title, nil
+}
@@ -0,0 +1,205 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	getDeletedMovieQuery = `SELECT ` + movieReturning + ` FROM movies WHERE uuid = $1 AND deleted_at IS NOT NULL`
+	getDeletedOwnerQuery = `SELECT ` + ownerReturning + ` FROM owners WHERE uuid = $1 AND deleted_at IS NOT NULL`
+	// Фильм нельзя восстановить, пока его владелец в корзине. author пересчитывается: пока фильм
+	// был в корзине, его режиссеров могли переименовать, см. syncPersonMoviesQuery
+	restoreMovieQuery = `UPDATE movies m SET deleted_at = NULL, author = ` + movieAuthorExpr + `, version = version + 1
+		WHERE uuid = $1 AND deleted_at IS NOT NULL
+			AND EXISTS (SELECT 1 FROM owners o WHERE o.uuid = m.owner_id AND o.deleted_at IS NULL)
+		RETURNING ` + movieReturning
+	restoreOwnerQuery = `UPDATE owners SET deleted_at = NULL, version = version + 1
+		WHERE uuid = $1 AND deleted_at IS NOT NULL RETURNING ` + ownerReturning
+	// Вместе с владельцем возвращаются только фильмы, удаленные вместе с ним
+	lockDeletedOwnerMoviesQuery = `SELECT ` + movieReturning + ` FROM movies WHERE owner_id = $1 AND deleted_at = $2 FOR UPDATE`
+	restoreOwnerMoviesQuery     = `UPDATE movies m SET deleted_at = NULL, author = ` + movieAuthorExpr + `, version = version + 1
+		WHERE owner_id = $1 AND deleted_at = $2 RETURNING ` + movieReturning
+	// Медиафайлы удаляются до фильмов и владельцев, см. deleteAssets
+	purgeAssetsCondition = `movie_id IN (SELECT m.uuid FROM movies m JOIN owners o ON o.uuid = m.owner_id
+			WHERE m.deleted_at < now() - make_interval(secs => $1) OR o.deleted_at < now() - make_interval(secs => $1))
+		OR episode_id IN (SELECT e.uuid FROM episodes e JOIN seasons sn ON sn.uuid = e.season_id
+			JOIN series sr ON sr.uuid = sn.series_id JOIN owners o ON o.uuid = sr.owner_id
+			WHERE o.deleted_at < now() - make_interval(secs => $1))`
+	purgeMoviesQuery = `DELETE FROM movies WHERE deleted_at < now() - make_interval(secs => $1)`
+	purgeOwnersQuery = `DELETE FROM owners WHERE deleted_at < now() - make_interval(secs => $1)`
+)
+
+var trashSchema = Schema{
+	Table: "trash",
+	From: `(SELECT 'movie' AS type, uuid, owner_id, title AS label, deleted_at FROM movies WHERE deleted_at IS NOT NULL
+		UNION ALL
+		SELECT 'owner' AS type, uuid, uuid AS owner_id, name AS label, deleted_at FROM owners WHERE deleted_at IS NOT NULL) trash`,
+	Fields: map[string]Field{
+		"type":     {Column: "type", Type: "text"},
+		"owner_id": {Column: "owner_id", Type: "uuid"},
+		"deleted_at": {Column: "deleted_at", Type: "timestamp", Sortable: true, Value: func(item any) string {
+			return formatTime(item.(*TrashItem).DeletedAt)
+		}},
+	},
+	DefaultSort: []Sort{{Field: "deleted_at", Desc: true}},
+}
+
+// TrashSchema - поля корзины, доступные для фильтрации и сортировки.
+func TrashSchema() Schema {
+	return trashSchema
+}
+
+type TrashRepository interface {
+	GetTrash(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*TrashItem], error)
+	GetDeletedMovie(ctx context.Context, uuid string) (*Movie, error)
+	GetDeletedOwner(ctx context.Context, uuid string) (*Owner, error)
+	RestoreMovie(ctx context.Context, uuid string) (*Movie, error)
+	// RestoreOwner восстанавливает владельца и фильмы, удаленные вместе с ним
+	RestoreOwner(ctx context.Context, uuid string) (*Owner, error)
+	// PurgeTrash окончательно удаляет все, что лежит в корзине дольше retention
+	PurgeTrash(ctx context.Context, retention time.Duration) (*TrashPurge, error)
+}
+
+func (r *repository) GetTrash(ctx context.Context, spec ListSpec, page PageRequest) (*Page[*TrashItem], error) {
+	items, err := listPage(ctx, r.db, trashSchema, `type, uuid, owner_id, label, deleted_at`, spec, page, scanTrashItem,
+		func(item *TrashItem) string {
+			return item.UUID
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query trash")
+	}
+
+	return items, nil
+}
+
+func (r *repository) GetDeletedMovie(ctx context.Context, uuid string) (*Movie, error) {
+	if err := checkUUID("movie", uuid); err != nil {
+		return nil, err
+	}
+
+	movie, err := scanMovie(r.db.QueryRow(ctx, getDeletedMovieQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "deleted movie"), "failed to query deleted movie")
+	}
+
+	return movie, nil
+}
+
+func (r *repository) GetDeletedOwner(ctx context.Context, uuid string) (*Owner, error) {
+	if err := checkUUID("owner", uuid); err != nil {
+		return nil, err
+	}
+
+	owner, err := scanOwner(r.db.QueryRow(ctx, getDeletedOwnerQuery, uuid))
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "deleted owner"), "failed to query deleted owner")
+	}
+
+	return owner, nil
+}
+
+func (r *repository) RestoreMovie(ctx context.Context, uuid string) (*Movie, error) {
+	if err := checkUUID("movie", uuid); err != nil {
+		return nil, err
+	}
+
+	var movie *Movie
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockMovie(ctx, uuid, true)
+		if err != nil {
+			return err
+		}
+
+		movie, err = scanMovie(tx.db.QueryRow(ctx, restoreMovieQuery, uuid))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return &Error{Kind: ErrConflict, Entity: "movie", Err: errors.New("owner of movie is deleted")}
+			}
+			return errors.Wrap(translate(err, "movie"), "failed to execute restore query")
+		}
+
+		return tx.audit(ctx, AuditRestore, "movie", uuid, before, movie)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+func (r *repository) RestoreOwner(ctx context.Context, uuid string) (*Owner, error) {
+	if err := checkUUID("owner", uuid); err != nil {
+		return nil, err
+	}
+
+	var owner *Owner
+	err := r.withTx(ctx, func(tx *repository) error {
+		before, err := tx.lockOwner(ctx, uuid, true)
+		if err != nil {
+			return err
+		}
+
+		owner, err = scanOwner(tx.db.QueryRow(ctx, restoreOwnerQuery, uuid))
+		if err != nil {
+			return errors.Wrap(translate(err, "owner"), "failed to execute restore query")
+		}
+
+		if err := tx.audit(ctx, AuditRestore, "owner", uuid, before, owner); err != nil {
+			return err
+		}
+
+		return tx.cascadeOwnerMovies(ctx, AuditRestore,
+			lockDeletedOwnerMoviesQuery, []any{uuid, before.DeletedAt},
+			restoreOwnerMoviesQuery, []any{uuid, before.DeletedAt})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return owner, nil
+}
+
+func (r *repository) PurgeTrash(ctx context.Context, retention time.Duration) (*TrashPurge, error) {
+	var purge TrashPurge
+
+	err := r.withTx(ctx, func(tx *repository) error {
+		var err error
+		if purge.Assets, err = tx.deleteAssets(ctx, purgeAssetsCondition, retention.Seconds()); err != nil {
+			return err
+		}
+
+		commandTag, err := tx.db.Exec(ctx, purgeMoviesQuery, retention.Seconds())
+		if err != nil {
+			return errors.Wrap(translate(err, "movie"), "failed to purge movies")
+		}
+		purge.Movies = commandTag.RowsAffected()
+
+		// Фильмы и сериалы владельцев удаляются каскадом
+		commandTag, err = tx.db.Exec(ctx, purgeOwnersQuery, retention.Seconds())
+		if err != nil {
+			return errors.Wrap(translate(err, "owner"), "failed to purge owners")
+		}
+		purge.Owners = commandTag.RowsAffected()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &purge, nil
+}
+
+func scanTrashItem(row pgx.Row) (*TrashItem, error) {
+	var item TrashItem
+
+	if err := row.Scan(&item.Type, &item.UUID, &item.OwnerID, &item.Label, &item.DeletedAt); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	insertUserQuery     = `INSERT INTO users (uuid, email, password_hash) VALUES ($1, $2, $3) RETURNING uuid`
+	getUserByIdQuery    = `SELECT email, password_hash, role, created_at FROM users WHERE uuid = $1`
+	getUserByEmailQuery = `SELECT uuid, email, password_hash, role, created_at FROM users WHERE lower(email) = lower($1)`
+	updateUserRoleQuery = `UPDATE users SET role = $1 WHERE uuid = $2`
+	insertSessionQuery  = `INSERT INTO sessions (uuid, user_id, expires_at) VALUES ($1, $2, $3) RETURNING uuid`
+	getSessionQuery     = `SELECT user_id, expires_at, revoked_at, created_at FROM sessions WHERE uuid = $1`
+	revokeSessionQuery  = `UPDATE sessions SET revoked_at = now() WHERE uuid = $1 AND revoked_at IS NULL`
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *User) (string, error)
+	GetUserByID(ctx context.Context, uuid string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	UpdateUserRole(ctx context.Context, uuid, role string) error
+	CreateSession(ctx context.Context, session *Session) (string, error)
+	GetSession(ctx context.Context, uuid string) (*Session, error)
+	RevokeSession(ctx context.Context, uuid string) error
+}
+
+func (r *repository) CreateUser(ctx context.Context, user *User) (string, error) {
+	uuid := uuid.New().String()
+
+	err := r.db.QueryRow(ctx, insertUserQuery, uuid, user.Email, user.PasswordHash).Scan(&uuid)
+	if err != nil {
+		return "", errors.Wrap(translate(err, "user"), "failed to insert user")
+	}
+	return uuid, nil
+}
+
+func (r *repository) GetUserByID(ctx context.Context, uuid string) (*User, error) {
+	if err := checkUUID("user", uuid); err != nil {
+		return nil, err
+	}
+
+	user := &User{UUID: uuid}
+
+	err := r.db.QueryRow(ctx, getUserByIdQuery, uuid).Scan(&user.Email, &user.PasswordHash, &user.Role, &user.Created_at)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "user"), "failed to query user by uuid")
+	}
+
+	return user, nil
+}
+
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	user := &User{}
+
+	err := r.db.QueryRow(ctx, getUserByEmailQuery, email).Scan(&user.UUID, &user.Email, &user.PasswordHash, &user.Role, &user.Created_at)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "user"), "failed to query user by email")
+	}
+
+	return user, nil
+}
+
+func (r *repository) UpdateUserRole(ctx context.Context, uuid, role string) error {
+	if err := checkUUID("user", uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, updateUserRoleQuery, role, uuid)
+	if err != nil {
+		return errors.Wrap(translate(err, "user"), "failed to execute update query")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("user"), "no rows updated, user with given UUID not found")
+	}
+
+	return nil
+}
+
+func (r *repository) CreateSession(ctx context.Context, session *Session) (string, error) {
+	uuid := uuid.New().String()
+
+	err := r.db.QueryRow(ctx, insertSessionQuery, uuid, session.UserID, session.ExpiresAt).Scan(&uuid)
+	if err != nil {
+		return "", errors.Wrap(translate(err, "session"), "failed to insert session")
+	}
+	return uuid, nil
+}
+
+func (r *repository) GetSession(ctx context.Context, uuid string) (*Session, error) {
+	if err := checkUUID("session", uuid); err != nil {
+		return nil, err
+	}
+
+	session := &Session{UUID: uuid}
+
+	err := r.db.QueryRow(ctx, getSessionQuery, uuid).Scan(&session.UserID, &session.ExpiresAt, &session.RevokedAt, &session.Created_at)
+	if err != nil {
+		return nil, errors.Wrap(translate(err, "session"), "failed to query session")
+	}
+
+	return session, nil
+}
+
+func (r *repository) RevokeSession(ctx context.Context, uuid string) error {
+	if err := checkUUID("session", uuid); err != nil {
+		return err
+	}
+
+	commandTag, err := r.db.Exec(ctx, revokeSessionQuery, uuid)
+	if err != nil {
+		return errors.Wrap(translate(err, "session"), "failed to execute revoke query")
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return errors.Wrap(notFound("session"), "no rows updated, active session with given UUID not found")
+	}
+
+	return nil
+}
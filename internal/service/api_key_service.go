@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx *fiber.Ctx) error
+	GetAPIKeys(ctx *fiber.Ctx) error
+	RotateAPIKey(ctx *fiber.Ctx) error
+	RevokeAPIKey(ctx *fiber.Ctx) error
+	VerifyAPIKey(ctx context.Context, key string) (*auth.Identity, error)
+}
+
+func (s *service) CreateAPIKey(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	if ownerID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.authorize(ctx, ownerID, PermAPIKeysManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		s.log.Error("Failed to generate api key", zap.Error(err))
+		return err
+	}
+
+	apiKey := repo.APIKey{
+		OwnerID: ownerID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  req.Scopes,
+	}
+	keyID, err := s.apiKeyRepo.CreateAPIKey(ctx.UserContext(), &apiKey)
+	if err != nil {
+		s.log.Error("Failed to create api key", zap.Error(err))
+		return err
+	}
+
+	// Ключ в открытом виде возвращается только один раз
+	response := dto.Response{
+		Status: "success",
+		Data: map[string]interface{}{
+			"apiKeyID": keyID,
+			"key":      key,
+			"prefix":   prefix,
+			"scopes":   req.Scopes,
+		},
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetAPIKeys(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	if ownerID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	if err := s.authorize(ctx, ownerID, PermAPIKeysManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	keys, err := s.apiKeyRepo.GetAPIKeys(ctx.UserContext(), ownerID)
+	if err != nil {
+		s.log.Error("Failed to get api keys", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   keys,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) RotateAPIKey(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	keyID := ctx.Params("keyId")
+	if ownerID == "" || keyID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	if err := s.authorize(ctx, ownerID, PermAPIKeysManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		s.log.Error("Failed to generate api key", zap.Error(err))
+		return err
+	}
+
+	if err := s.apiKeyRepo.RotateAPIKey(ctx.UserContext(), ownerID, keyID, prefix, hash); err != nil {
+		s.log.Error("Failed to rotate api key", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data: map[string]string{
+			"apiKeyID": keyID,
+			"key":      key,
+			"prefix":   prefix,
+		},
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) RevokeAPIKey(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	keyID := ctx.Params("keyId")
+	if ownerID == "" || keyID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	if err := s.authorize(ctx, ownerID, PermAPIKeysManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx.UserContext(), ownerID, keyID); err != nil {
+		s.log.Error("Failed to revoke api key", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   keyID,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) VerifyAPIKey(ctx context.Context, key string) (*auth.Identity, error) {
+	apiKey, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		return nil, errors.Wrap(auth.ErrInvalidToken, err.Error())
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil, errors.Wrap(auth.ErrInvalidToken, "api key revoked")
+	}
+
+	if err := s.apiKeyRepo.TouchAPIKey(ctx, apiKey.UUID); err != nil {
+		s.log.Error("Failed to update api key usage", zap.Error(err))
+	}
+
+	return &auth.Identity{
+		APIKeyID: apiKey.UUID,
+		OwnerID:  apiKey.OwnerID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+	"streaming-service/internal/worker"
+)
+
+// AssetService - загрузка медиафайлов фильмов и эпизодов по протоколу tus.
+// Маршруты одинаковые для обоих видов: /movies/:id/assets и /episodes/:id/assets.
+type AssetService interface {
+	TusOptions(ctx *fiber.Ctx) error
+
+	CreateMovieAsset(ctx *fiber.Ctx) error
+	GetMovieAssets(ctx *fiber.Ctx) error
+	GetMovieAsset(ctx *fiber.Ctx) error
+	HeadMovieAsset(ctx *fiber.Ctx) error
+	PatchMovieAsset(ctx *fiber.Ctx) error
+	DeleteMovieAsset(ctx *fiber.Ctx) error
+
+	CreateEpisodeAsset(ctx *fiber.Ctx) error
+	GetEpisodeAssets(ctx *fiber.Ctx) error
+	GetEpisodeAsset(ctx *fiber.Ctx) error
+	HeadEpisodeAsset(ctx *fiber.Ctx) error
+	PatchEpisodeAsset(ctx *fiber.Ctx) error
+	DeleteEpisodeAsset(ctx *fiber.Ctx) error
+
+	// RunCompleteJob - обработчик задач repo.JobCompleteAsset
+	RunCompleteJob(ctx context.Context, job *repo.Job, progress func(percent int)) error
+}
+
+func (s *service) CreateMovieAsset(ctx *fiber.Ctx) error { return s.createAsset(ctx, repo.TitleMovie) }
+func (s *service) GetMovieAssets(ctx *fiber.Ctx) error   { return s.getAssets(ctx, repo.TitleMovie) }
+func (s *service) GetMovieAsset(ctx *fiber.Ctx) error    { return s.getAsset(ctx, repo.TitleMovie) }
+func (s *service) HeadMovieAsset(ctx *fiber.Ctx) error   { return s.headAsset(ctx, repo.TitleMovie) }
+func (s *service) PatchMovieAsset(ctx *fiber.Ctx) error  { return s.patchAsset(ctx, repo.TitleMovie) }
+func (s *service) DeleteMovieAsset(ctx *fiber.Ctx) error { return s.deleteAsset(ctx, repo.TitleMovie) }
+
+func (s *service) CreateEpisodeAsset(ctx *fiber.Ctx) error {
+	return s.createAsset(ctx, repo.TitleEpisode)
+}
+func (s *service) GetEpisodeAssets(ctx *fiber.Ctx) error { return s.getAssets(ctx, repo.TitleEpisode) }
+func (s *service) GetEpisodeAsset(ctx *fiber.Ctx) error  { return s.getAsset(ctx, repo.TitleEpisode) }
+func (s *service) HeadEpisodeAsset(ctx *fiber.Ctx) error { return s.headAsset(ctx, repo.TitleEpisode) }
+func (s *service) PatchEpisodeAsset(ctx *fiber.Ctx) error {
+	return s.patchAsset(ctx, repo.TitleEpisode)
+}
+func (s *service) DeleteEpisodeAsset(ctx *fiber.Ctx) error {
+	return s.deleteAsset(ctx, repo.TitleEpisode)
+}
+
+// TusOptions сообщает клиенту возможности сервера; по протоколу доступен без авторизации.
+func (s *service) TusOptions(ctx *fiber.Ctx) error {
+	ctx.Set(headerTusResumable, tusVersion)
+	ctx.Set(headerTusVersion, tusVersion)
+	ctx.Set(headerTusExtension, tusExtensions)
+	ctx.Set(headerTusMaxSize, strconv.FormatInt(s.uploads.MaxSize(), 10))
+	ctx.Set(headerTusChecksumAlgorithm, tusChecksumAlgorithms)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// createAsset начинает загрузку (расширение creation): заводит файл и запись в статусе uploading.
+func (s *service) createAsset(ctx *fiber.Ctx, kind string) error {
+	if err := checkTusResumable(ctx); err != nil {
+		return err
+	}
+
+	req := GetAssetsRequest{TitleID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	length, err := parseUploadLength(ctx)
+	if err != nil {
+		return err
+	}
+	if length > s.uploads.MaxSize() {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "Upload-Length exceeds Tus-Max-Size")
+	}
+
+	values, err := parseUploadMetadata(ctx.Get(headerUploadMetadata))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid Upload-Metadata: "+err.Error())
+	}
+	metadata := AssetMetadata{
+		Filename: values["filename"],
+		FileType: values["filetype"],
+		Checksum: strings.ToLower(values["checksum"]),
+	}
+	if errs := validateRequest(&metadata); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.titleFor(ctx, kind, req.TitleID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	assetID := uuid.New().String()
+	if err := s.uploads.Create(assetID); err != nil {
+		s.log.Error("Failed to create upload file", zap.Error(err))
+		return err
+	}
+
+	asset, err := s.assetRepo.CreateAsset(ctx.UserContext(), &repo.MediaAsset{
+		UUID:      assetID,
+		TitleKind: kind,
+		TitleID:   req.TitleID,
+		Filename:  metadata.Filename,
+		MimeType:  metadata.FileType,
+		Size:      length,
+		Checksum:  metadata.Checksum,
+	})
+	if err != nil {
+		s.log.Error("Failed to create media asset", zap.Error(err))
+		if err := s.uploads.Remove(assetID); err != nil {
+			s.log.Error("Failed to remove upload file", zap.Error(err))
+		}
+		return err
+	}
+
+	ctx.Location(strings.TrimSuffix(ctx.Path(), "/") + "/" + asset.UUID)
+	ctx.Set(headerUploadOffset, "0")
+
+	response := dto.Response{
+		Status: "success",
+		Data:   asset,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) getAssets(ctx *fiber.Ctx, kind string) error {
+	req := GetAssetsRequest{TitleID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.titleFor(ctx, kind, req.TitleID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	assets, err := s.assetRepo.GetAssets(ctx.UserContext(), kind, req.TitleID)
+	if err != nil {
+		s.log.Error("Failed to get media assets", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   assets,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) getAsset(ctx *fiber.Ctx, kind string) error {
+	req := GetAssetRequest{TitleID: ctx.Params("id"), AssetID: ctx.Params("assetId")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	asset, err := s.assetFor(ctx, kind, req, "")
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   asset,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// headAsset отдает текущее смещение загрузки - с него клиент продолжает после обрыва.
+func (s *service) headAsset(ctx *fiber.Ctx, kind string) error {
+	if err := checkTusResumable(ctx); err != nil {
+		return err
+	}
+
+	req := GetAssetRequest{TitleID: ctx.Params("id"), AssetID: ctx.Params("assetId")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	asset, err := s.assetFor(ctx, kind, req, PermMoviesWrite)
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(headerUploadOffset, strconv.FormatInt(asset.Offset, 10))
+	ctx.Set(headerUploadLength, strconv.FormatInt(asset.Size, 10))
+	return ctx.SendStatus(fiber.StatusOK)
+}
+
+// patchAsset принимает очередной фрагмент. Фрагменты одной загрузки принимаются строго по очереди:
+// строка загрузки блокируется на время записи, а смещение должно совпасть с уже принятым.
+func (s *service) patchAsset(ctx *fiber.Ctx, kind string) error {
+	if err := checkTusResumable(ctx); err != nil {
+		return err
+	}
+
+	contentType, _, _ := strings.Cut(ctx.Get(fiber.HeaderContentType), ";")
+	if strings.TrimSpace(strings.ToLower(contentType)) != tusContentType {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Content-Type must be "+tusContentType)
+	}
+
+	offset, err := parseUploadOffset(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := GetAssetRequest{TitleID: ctx.Params("id"), AssetID: ctx.Params("assetId")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	asset, err := s.assetFor(ctx, kind, req, PermMoviesWrite)
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	chunk := ctx.Body()
+	if offset+int64(len(chunk)) > asset.Size {
+		return fiber.NewError(fiber.StatusBadRequest, "Chunk exceeds Upload-Length")
+	}
+	if err := verifyChunkChecksum(ctx, chunk); err != nil {
+		return err
+	}
+
+	// Приняв последний байт, загрузка под той же блокировкой переходит в completing и ставит задачу
+	// завершения: повторный PATCH с тем же смещением получит 409, а завершение выполнится один раз
+	var newOffset int64
+	err = s.txRepo.WithTx(ctx.UserContext(), func(tx repo.Repositories) error {
+		locked, err := tx.LockAsset(ctx.UserContext(), asset.UUID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != repo.AssetUploading || locked.Offset != offset {
+			return fiber.NewError(fiber.StatusConflict, "Upload-Offset does not match the current offset "+
+				strconv.FormatInt(locked.Offset, 10))
+		}
+
+		if newOffset, err = s.uploads.Append(asset.UUID, offset, bytes.NewReader(chunk)); err != nil {
+			return err
+		}
+		status, err := tx.SetAssetOffset(ctx.UserContext(), asset.UUID, newOffset)
+		if err != nil || status != repo.AssetCompleting {
+			return err
+		}
+		_, err = tx.EnqueueJob(ctx.UserContext(), repo.JobCompleteAsset, asset.UUID, s.jobAttempts)
+		return err
+	})
+	if err != nil {
+		s.log.Error("Failed to accept upload chunk", zap.Error(err))
+		return err
+	}
+
+	ctx.Set(headerUploadOffset, strconv.FormatInt(newOffset, 10))
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// RunCompleteJob сверяет SHA-256 принятого файла с заявленным при создании загрузки и переносит файл
+// в хранилище; вызывается пулом фоновых задач. При несовпадении загрузка помечается failed, файл удаляется.
+// Повторный запуск безопасен: ключ в хранилище зависит только от загрузки, а итог фиксируется один раз.
+func (s *service) RunCompleteJob(ctx context.Context, job *repo.Job, progress func(percent int)) error {
+	asset, err := s.assetRepo.GetAsset(ctx, job.TitleKind, job.TitleID, job.AssetID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return worker.Permanent(err)
+		}
+		return err
+	}
+	if asset.Status != repo.AssetCompleting {
+		return nil
+	}
+
+	err = s.completeAsset(ctx, asset, progress)
+	if err != nil && job.Attempts >= job.MaxAttempts {
+		// Попыток больше не будет: загрузка не должна остаться в completing навсегда
+		if _, err := s.assetRepo.CompleteAsset(ctx, asset.UUID, repo.AssetFailed, asset.MimeType, ""); err != nil && !errors.Is(err, repo.ErrConflict) {
+			s.log.Error("Failed to mark media asset as failed", zap.Error(err))
+		}
+	}
+	return err
+}
+
+func (s *service) completeAsset(ctx context.Context, asset *repo.MediaAsset, progress func(percent int)) error {
+	checksum, err := s.uploads.Checksum(asset.UUID)
+	if err != nil {
+		return errors.Wrap(err, "failed to compute upload checksum")
+	}
+	progress(50)
+
+	if checksum != asset.Checksum {
+		if _, err := s.assetRepo.CompleteAsset(ctx, asset.UUID, repo.AssetFailed, asset.MimeType, ""); err != nil {
+			if errors.Is(err, repo.ErrConflict) {
+				return nil
+			}
+			return err
+		}
+		if err := s.uploads.Remove(asset.UUID); err != nil {
+			s.log.Error("Failed to remove upload file", zap.Error(err))
+		}
+		return worker.Permanent(errors.New("checksum mismatch"))
+	}
+
+	mimeType := asset.MimeType
+	if mimeType == "" {
+		if mimeType, err = s.uploads.DetectContentType(asset.UUID); err != nil {
+			return errors.Wrap(err, "failed to detect upload content type")
+		}
+	}
+
+	key := assetStorageKey(asset.UUID)
+	if err := s.storeUpload(ctx, asset, key, mimeType); err != nil {
+		return errors.Wrap(err, "failed to store uploaded file")
+	}
+
+	// Задача упаковки ставится в той же транзакции: готовый файл не останется без пакета
+	err = s.txRepo.WithTx(ctx, func(tx repo.Repositories) error {
+		if _, err := tx.CompleteAsset(ctx, asset.UUID, repo.AssetReady, mimeType, key); err != nil {
+			return err
+		}
+		if s.packager == nil {
+			return nil
+		}
+		_, err := tx.EnqueueJob(ctx, repo.JobPackageAsset, asset.UUID, s.jobAttempts)
+		return err
+	})
+	if errors.Is(err, repo.ErrConflict) {
+		// Итог уже зафиксирован, и файл под этим ключом принадлежит ему. Если же загрузку
+		// успели удалить, файл больше никому не нужен
+		if _, err := s.assetRepo.GetAsset(ctx, asset.TitleKind, asset.TitleID, asset.UUID); errors.Is(err, repo.ErrNotFound) {
+			if err := s.blobs.Delete(ctx, key); err != nil {
+				s.log.Error("Failed to delete stored file", zap.Error(err))
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.uploads.Remove(asset.UUID); err != nil {
+		s.log.Error("Failed to remove upload file", zap.Error(err))
+	}
+
+	return nil
+}
+
+// storeUpload переносит собранный файл из каталога загрузок в хранилище медиафайлов.
+func (s *service) storeUpload(ctx context.Context, asset *repo.MediaAsset, key, mimeType string) error {
+	file, err := s.uploads.Open(asset.UUID)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return s.blobs.Put(ctx, key, file, asset.Size, mimeType)
+}
+
+// assetStorageKey - ключ готового медиафайла в хранилище.
+func assetStorageKey(assetID string) string {
+	return "assets/" + assetID
+}
+
+// deleteAsset прерывает загрузку или удаляет готовый файл (расширение termination).
+func (s *service) deleteAsset(ctx *fiber.Ctx, kind string) error {
+	if err := checkTusResumable(ctx); err != nil {
+		return err
+	}
+
+	req := GetAssetRequest{TitleID: ctx.Params("id"), AssetID: ctx.Params("assetId")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	asset, err := s.assetFor(ctx, kind, req, PermMoviesWrite)
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.assetRepo.DeleteAsset(ctx.UserContext(), asset.UUID); err != nil {
+		s.log.Error("Failed to delete media asset", zap.Error(err))
+		return err
+	}
+
+	s.removeAssetFiles(ctx.UserContext(), asset)
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// removeAssetFiles удаляет файлы уже удаленной из базы загрузки: недокачанный файл, готовый файл и пакет.
+// Ошибки только логируются - запись удалена, и повторить удаление по ней уже нельзя.
+func (s *service) removeAssetFiles(ctx context.Context, asset *repo.MediaAsset) {
+	if err := s.uploads.Remove(asset.UUID); err != nil {
+		s.log.Error("Failed to remove upload file", zap.Error(err))
+	}
+	if asset.Status == repo.AssetUploading {
+		return
+	}
+
+	// Завершение могло успеть выложить файл, но не зафиксировать ключ, поэтому ключ строится заново
+	if err := s.blobs.Delete(ctx, assetStorageKey(asset.UUID)); err != nil {
+		s.log.Error("Failed to delete stored file", zap.Error(err))
+	}
+	if s.packager != nil && asset.Status == repo.AssetReady {
+		if err := s.packager.Remove(ctx, packagePrefix(asset.UUID)); err != nil {
+			s.log.Error("Failed to delete media package", zap.Error(err))
+		}
+	}
+}
+
+// assetFor проверяет права на фильм или эпизод и загружает его медиафайл; ошибку нужно передать в denied.
+func (s *service) assetFor(ctx *fiber.Ctx, kind string, req GetAssetRequest, perm Permission) (*repo.MediaAsset, error) {
+	if _, err := s.titleFor(ctx, kind, req.TitleID, perm); err != nil {
+		return nil, err
+	}
+
+	asset, err := s.assetRepo.GetAsset(ctx.UserContext(), kind, req.TitleID, req.AssetID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get media asset")
+	}
+
+	return asset, nil
+}
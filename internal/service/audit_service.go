@@ -0,0 +1,71 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type AuditService interface {
+	GetAuditEvents(ctx *fiber.Ctx) error
+}
+
+func (s *service) GetAuditEvents(ctx *fiber.Ctx) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var query ListAuditQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+
+	errs := validateRequest(&query)
+	page, pageErrs := parsePageRequest(ctx)
+	errs = append(errs, pageErrs...)
+	sorts, sortErrs := parseSort(repo.AuditSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	spec := auditListSpec(&query)
+	spec.Sorts = sorts
+
+	events, err := s.auditRepo.GetAuditEvents(ctx.UserContext(), spec, page)
+	if err != nil {
+		s.log.Error("Failed to get audit events", zap.Error(err))
+		return err
+	}
+
+	return sendPage(ctx, events)
+}
+
+// auditListSpec переводит провалидированные параметры запроса в фильтры журнала.
+func auditListSpec(query *ListAuditQuery) repo.ListSpec {
+	var spec repo.ListSpec
+
+	if query.EntityType != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "entity_type", Op: repo.OpEq, Value: query.EntityType})
+	}
+	if query.EntityID != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "entity_id", Op: repo.OpEq, Value: query.EntityID})
+	}
+	if query.Actor != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "actor", Op: repo.OpEq, Value: query.Actor})
+	}
+	if query.Action != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "action", Op: repo.OpEq, Value: query.Action})
+	}
+	if query.From != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "created_at", Op: repo.OpGte, Value: parseDateTime(query.From)})
+	}
+	if query.To != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "created_at", Op: repo.OpLte, Value: parseDateTime(query.To)})
+	}
+
+	return spec
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+const maxPasswordBytes = 72
+
+type AuthService interface {
+	Register(ctx *fiber.Ctx) error
+	Login(ctx *fiber.Ctx) error
+	Refresh(ctx *fiber.Ctx) error
+	Logout(ctx *fiber.Ctx) error
+	VerifyAccessToken(ctx context.Context, token string) (*auth.Identity, error)
+}
+
+func (s *service) Register(ctx *fiber.Ctx) error {
+	var req RegisterRequest
+
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// validate считает длину в символах, а bcrypt ограничен 72 байтами
+	if len(req.Password) > maxPasswordBytes {
+		return dto.ValidationError(ctx, []dto.FieldError{{
+			Field:   "password",
+			Code:    dto.FieldBadFormat,
+			Message: "Field 'password' must be at most 72 bytes long",
+		}})
+	}
+
+	email := strings.TrimSpace(req.Email)
+
+	if _, err := s.userRepo.GetUserByEmail(ctx.UserContext(), email); err == nil {
+		return dto.ConflictError(ctx, dto.AlreadyExists, "User with this email already exists")
+	} else if !errors.Is(err, repo.ErrNotFound) {
+		s.log.Error("Failed to query user", zap.Error(err))
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.log.Error("Failed to hash password", zap.Error(err))
+		return err
+	}
+
+	userID, err := s.userRepo.CreateUser(ctx.UserContext(), &repo.User{
+		Email:        email,
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		s.log.Error("Failed to create user", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   map[string]string{"userID": userID},
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) Login(ctx *fiber.Ctx) error {
+	var req LoginRequest
+
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx.UserContext(), strings.TrimSpace(req.Email))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return dto.UnauthorizedError(ctx, "Invalid email or password")
+		}
+		s.log.Error("Failed to query user", zap.Error(err))
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return dto.UnauthorizedError(ctx, "Invalid email or password")
+	}
+
+	tokens, err := s.startSession(ctx.UserContext(), user.UUID)
+	if err != nil {
+		s.log.Error("Failed to start session", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   tokens,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) Refresh(ctx *fiber.Ctx) error {
+	var req RefreshRequest
+
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	claims, err := s.activeSession(ctx.UserContext(), req.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		s.log.Error("Failed to verify refresh token", zap.Error(err))
+		return dto.UnauthorizedError(ctx, "Invalid or expired refresh token")
+	}
+
+	// Refresh-токен одноразовый: старая сессия отзывается, выдается новая пара
+	if err := s.userRepo.RevokeSession(ctx.UserContext(), claims.SessionID); err != nil {
+		s.log.Error("Failed to revoke session", zap.Error(err))
+		return dto.UnauthorizedError(ctx, "Invalid or expired refresh token")
+	}
+
+	tokens, err := s.startSession(ctx.UserContext(), claims.Subject)
+	if err != nil {
+		s.log.Error("Failed to start session", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   tokens,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) Logout(ctx *fiber.Ctx) error {
+	var req LogoutRequest
+
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	claims, err := s.activeSession(ctx.UserContext(), req.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		s.log.Error("Failed to verify refresh token", zap.Error(err))
+		return dto.UnauthorizedError(ctx, "Invalid or expired refresh token")
+	}
+
+	if err := s.userRepo.RevokeSession(ctx.UserContext(), claims.SessionID); err != nil {
+		s.log.Error("Failed to revoke session", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   claims.SessionID,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) VerifyAccessToken(ctx context.Context, token string) (*auth.Identity, error) {
+	claims, err := s.activeSession(ctx, token, auth.TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.Subject)
+	if err != nil {
+		return nil, errors.Wrap(auth.ErrInvalidToken, err.Error())
+	}
+
+	return &auth.Identity{
+		UserID:    user.UUID,
+		SessionID: claims.SessionID,
+		Role:      auth.Role(user.Role),
+	}, nil
+}
+
+// activeSession проверяет токен и то, что его сессия не отозвана и не истекла.
+func (s *service) activeSession(ctx context.Context, token, tokenType string) (*auth.Claims, error) {
+	claims, err := s.tokens.Parse(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.userRepo.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		return nil, errors.Wrap(auth.ErrInvalidToken, err.Error())
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) || session.UserID != claims.Subject {
+		return nil, errors.Wrap(auth.ErrInvalidToken, "session revoked or expired")
+	}
+
+	return claims, nil
+}
+
+func (s *service) startSession(ctx context.Context, userID string) (*auth.TokenPair, error) {
+	sessionID, err := s.userRepo.CreateSession(ctx, &repo.Session{
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(s.tokens.RefreshTTL()),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create session")
+	}
+
+	return s.tokens.IssuePair(userID, sessionID)
+}
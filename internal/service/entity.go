@@ -1,44 +1,235 @@
 package service
 
 type CreateMovieRequest struct {
-	Title       string `json:"title"`
-	Author      string `json:"author"`
-	Description string `json:"description"`
-	Year        int    `json:"year"`
-	OwnerName   string `json:"owner_name"`
+	Title       string          `json:"title" validate:"required,max=255"`
+	Author      string          `json:"author" validate:"max=255"`
+	Description string          `json:"description" validate:"max=5000"`
+	Year        int             `json:"year" validate:"required,gt=0"`
+	OwnerName   string          `json:"owner_name" validate:"max=255"`
+	Genres      []string        `json:"genres" validate:"max=20,dive,slug,max=64"`
+	Tags        []string        `json:"tags" validate:"max=50,dive,slug,max=64"`
+	Credits     []CreditRequest `json:"credits" validate:"max=200,dive"`
 }
 
 type CreateOwnerRequest struct {
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,max=255"`
 }
+
+// Genres, Tags и Credits заменяют набор целиком; nil (поле не передано) оставляет его без изменений.
+// Author в ответах выводится из режиссеров в титрах; в запросе он задает режиссера, см. authorCredits
 type UpdateMovieRequest struct {
-	UUID        string `json:"uuid"`
-	Title       string `json:"title"`
-	Author      string `json:"author"`
-	Description string `json:"description"`
-	Year        int    `json:"year"`
+	UUID        string          `json:"uuid" validate:"required,uuid"`
+	Title       string          `json:"title" validate:"required,max=255"`
+	Author      string          `json:"author" validate:"max=255"`
+	Description string          `json:"description" validate:"max=5000"`
+	Year        int             `json:"year" validate:"required,gt=0"`
+	Genres      []string        `json:"genres" validate:"max=20,dive,slug,max=64"`
+	Tags        []string        `json:"tags" validate:"max=50,dive,slug,max=64"`
+	Credits     []CreditRequest `json:"credits" validate:"max=200,dive"`
 }
 
 type UpdateOwnerRequest struct {
-	UUID string `json:"uuid"`
-	Name string `json:"name"`
+	UUID string `json:"uuid" validate:"required,uuid"`
+	Name string `json:"name" validate:"required,max=255"`
 }
 type GetMovieRequest struct {
-	UUID string `json:"uuid"`
+	UUID string `json:"uuid" validate:"required,uuid"`
 }
 
 type GetOwnerByUUIDRequest struct {
-	UUID string `json:"uuid"`
+	UUID string `json:"uuid" validate:"required,uuid"`
 }
 
 type GetOwnerByNameRequest struct {
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,max=255"`
 }
 
 type DeleteMovieRequest struct {
-	UUID string `json:"uuid"`
+	UUID string `json:"uuid" validate:"required,uuid"`
 }
 
 type DeleteOwnerRequest struct {
-	UUID string `json:"uuid"`
+	UUID string `json:"uuid" validate:"required,uuid"`
+}
+
+type RegisterRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	// bcrypt не принимает пароли длиннее 72 байт
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type AddOwnerMemberRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+	Role   string `json:"role" validate:"required,oneof=owner-member editor viewer"`
+}
+
+// Глобально назначаются только admin и viewer, остальные роли - в рамках владельца
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin viewer"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=movies:read movies:write"`
+}
+
+type ListMoviesQuery struct {
+	OwnerID      string `query:"owner_id" json:"owner_id" validate:"omitempty,uuid"`
+	Author       string `query:"author" json:"author" validate:"max=255"`
+	YearFrom     int    `query:"year_from" json:"year_from" validate:"omitempty,gt=0"`
+	YearTo       int    `query:"year_to" json:"year_to" validate:"omitempty,gt=0,gtefield=YearFrom"`
+	CreatedAfter string `query:"created_after" json:"created_after" validate:"omitempty,datetime=2006-01-02|datetime=2006-01-02T15:04:05Z07:00"`
+	// Genre и Tag - списки slug через запятую, фильм должен иметь их все
+	Genre string `query:"genre" json:"genre" validate:"max=255"`
+	Tag   string `query:"tag" json:"tag" validate:"max=255"`
+	Sort  string `query:"sort" json:"sort"`
+}
+
+type ListOwnersQuery struct {
+	Sort string `query:"sort" json:"sort"`
+}
+
+type SearchQuery struct {
+	Q     string `query:"q" json:"q" validate:"required,max=255"`
+	Limit int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+type SuggestQuery struct {
+	Prefix string `query:"prefix" json:"prefix" validate:"required,max=100"`
+	Limit  int    `query:"limit" json:"limit" validate:"omitempty,min=1,max=20"`
+}
+
+type ListTrashQuery struct {
+	Type string `query:"type" json:"type" validate:"omitempty,oneof=movie owner"`
+	Sort string `query:"sort" json:"sort"`
+}
+
+type ListAuditQuery struct {
+	EntityType string `query:"entity_type" json:"entity_type" validate:"omitempty,oneof=movie owner series season episode"`
+	EntityID   string `query:"entity_id" json:"entity_id" validate:"omitempty,uuid"`
+	Actor      string `query:"actor" json:"actor" validate:"max=255"`
+	Action     string `query:"action" json:"action" validate:"omitempty,oneof=create update delete restore"`
+	From       string `query:"from" json:"from" validate:"omitempty,datetime=2006-01-02|datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `query:"to" json:"to" validate:"omitempty,datetime=2006-01-02|datetime=2006-01-02T15:04:05Z07:00"`
+	Sort       string `query:"sort" json:"sort"`
+}
+
+type TermRequest struct {
+	Slug string `json:"slug" validate:"required,slug,max=64"`
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type GetTermRequest struct {
+	Slug string `json:"slug" validate:"required,slug,max=64"`
+}
+
+// Character имеет смысл для актеров; BillingOrder - порядок в титрах, меньше - выше
+type CreditRequest struct {
+	PersonID     string `json:"person_id" validate:"required,uuid"`
+	Role         string `json:"role" validate:"required,oneof=director writer actor producer"`
+	Character    string `json:"character" validate:"max=255"`
+	BillingOrder int    `json:"billing_order" validate:"min=0"`
+}
+
+type PersonRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+	Bio  string `json:"bio" validate:"max=5000"`
+}
+
+type GetPersonRequest struct {
+	UUID string `json:"uuid" validate:"required,uuid"`
+}
+
+type ListPeopleQuery struct {
+	Name string `query:"name" json:"name" validate:"max=255"`
+	Sort string `query:"sort" json:"sort"`
+}
+
+// OwnerID можно не передавать при запросе по API-ключу - владелец берется из ключа
+type CreateSeriesRequest struct {
+	OwnerID     string `json:"owner_id" validate:"omitempty,uuid"`
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=5000"`
+}
+
+type UpdateSeriesRequest struct {
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=5000"`
+}
+
+type SeasonRequest struct {
+	Number  int    `json:"number" validate:"required,gt=0"`
+	Title   string `json:"title" validate:"max=255"`
+	AirDate string `json:"air_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type EpisodeRequest struct {
+	Number         int    `json:"number" validate:"required,gt=0"`
+	Title          string `json:"title" validate:"required,max=255"`
+	Description    string `json:"description" validate:"max=5000"`
+	AirDate        string `json:"air_date" validate:"omitempty,datetime=2006-01-02"`
+	RuntimeMinutes int    `json:"runtime_minutes" validate:"omitempty,gt=0,max=1440"`
+}
+
+// SeriesPathRequest - параметры вложенных маршрутов /series/:id/seasons/:n/episodes/:e
+type SeriesPathRequest struct {
+	SeriesID string `json:"id" validate:"required,uuid"`
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+}
+
+type GetEpisodeRequest struct {
+	UUID string `json:"uuid" validate:"required,uuid"`
+}
+
+type GetTitleRequest struct {
+	UUID string `json:"uuid" validate:"required,uuid"`
+}
+
+type ListSeriesQuery struct {
+	OwnerID string `query:"owner_id" json:"owner_id" validate:"omitempty,uuid"`
+	Sort    string `query:"sort" json:"sort"`
+}
+
+// AssetMetadata - поля Upload-Metadata tus-загрузки; checksum - SHA-256 всего файла в hex
+type AssetMetadata struct {
+	Filename string `json:"filename" validate:"max=255"`
+	FileType string `json:"filetype" validate:"omitempty,startswith=video/,max=100"`
+	Checksum string `json:"checksum" validate:"required,len=64,hexadecimal"`
+}
+
+type CreatePlaybackRequest struct {
+	TitleID string `json:"id" validate:"required,uuid"`
+	// BindIP привязывает ссылку к адресу клиента, запросившего ее
+	BindIP bool `json:"bind_ip"`
+}
+
+type GetJobRequest struct {
+	UUID string `json:"uuid" validate:"required,uuid"`
+}
+
+type GetJobsRequest struct {
+	TitleID string `json:"id" validate:"required,uuid"`
+}
+
+type GetAssetsRequest struct {
+	TitleID string `json:"id" validate:"required,uuid"`
+}
+
+type GetAssetRequest struct {
+	TitleID string `json:"id" validate:"required,uuid"`
+	AssetID string `json:"assetId" validate:"required,uuid"`
 }
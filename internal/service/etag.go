@@ -0,0 +1,56 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"streaming-service/internal/repo"
+)
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// checkIfMatch сверяет If-Match с текущей версией записи и возвращает версию,
+// которую репозиторий должен проверить атомарно при записи (0 - заголовка нет).
+func checkIfMatch(ctx *fiber.Ctx, entity string, current int) (int, error) {
+	header := ctx.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return 0, nil
+	}
+
+	// If-Match использует строгое сравнение, слабые теги не совпадают никогда
+	if strings.TrimSpace(header) == "*" || matchesETag(header, etag(current), false) {
+		return current, nil
+	}
+
+	return 0, repo.PreconditionFailed(entity)
+}
+
+// notModified обрабатывает If-None-Match для GET: при совпадении отвечать нужно 304.
+func notModified(ctx *fiber.Ctx, current int) bool {
+	header := ctx.Get(fiber.HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+
+	return strings.TrimSpace(header) == "*" || matchesETag(header, etag(current), true)
+}
+
+func matchesETag(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/dash"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/hls"
+	"streaming-service/internal/repo"
+	"streaming-service/internal/storage"
+	"streaming-service/internal/worker"
+)
+
+// HLSService отдает пакет основного медиафайла: /hls/master.m3u8 и /dash/manifest.mpd
+// вместе с файлами дорожек относительно них. HLS и DASH используют одни и те же сегменты.
+type HLSService interface {
+	GetMovieHLS(ctx *fiber.Ctx) error
+	GetEpisodeHLS(ctx *fiber.Ctx) error
+	GetMovieDASH(ctx *fiber.Ctx) error
+	GetEpisodeDASH(ctx *fiber.Ctx) error
+	// RunPackageJob - обработчик задач repo.JobPackageAsset
+	RunPackageJob(ctx context.Context, job *repo.Job, progress func(percent int)) error
+}
+
+func (s *service) GetMovieHLS(ctx *fiber.Ctx) error {
+	return s.getPackageFile(ctx, repo.TitleMovie, hls.ContentType)
+}
+func (s *service) GetEpisodeHLS(ctx *fiber.Ctx) error {
+	return s.getPackageFile(ctx, repo.TitleEpisode, hls.ContentType)
+}
+func (s *service) GetMovieDASH(ctx *fiber.Ctx) error {
+	return s.getPackageFile(ctx, repo.TitleMovie, dash.ContentType)
+}
+func (s *service) GetEpisodeDASH(ctx *fiber.Ctx) error {
+	return s.getPackageFile(ctx, repo.TitleEpisode, dash.ContentType)
+}
+
+// getPackageFile отдает файл пакета; contentTypeOf ограничивает, какие файлы доступны по маршруту.
+func (s *service) getPackageFile(ctx *fiber.Ctx, kind string, contentTypeOf func(name string) string) error {
+	req := GetTitleRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// Отдаются только файлы пакета, и без выхода за его каталог
+	name := ctx.Params("*")
+	contentType := contentTypeOf(name)
+	if contentType == "" || path.Clean("/"+name) != "/"+name {
+		return fiber.NewError(fiber.StatusNotFound, "Requested file is not part of the package")
+	}
+
+	if _, err := s.titleFor(ctx, kind, req.UUID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	asset, err := s.assetRepo.GetPrimaryAsset(ctx.UserContext(), kind, req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get primary media asset", zap.Error(err))
+		return err
+	}
+
+	object, err := s.blobs.Get(ctx.UserContext(), path.Join(packagePrefix(asset.UUID), name), 0, -1)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Package is not ready yet")
+		}
+		s.log.Error("Failed to read package file", zap.Error(err))
+		return err
+	}
+
+	// Сегменты после нарезки не меняются, а плейлисты и манифест переписываются при повторной упаковке
+	cacheControl := "private, max-age=86400"
+	if strings.HasSuffix(name, ".m3u8") || strings.HasSuffix(name, ".mpd") {
+		cacheControl = "no-cache"
+	}
+
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderCacheControl, cacheControl)
+	if object.Info.ETag != "" {
+		ctx.Set(fiber.HeaderETag, object.Info.ETag)
+	}
+
+	if ctx.Method() == fiber.MethodHead {
+		object.Close()
+		ctx.Response().Header.SetContentLength(int(object.Info.Size))
+		return nil
+	}
+
+	ctx.Context().SetBodyStream(object, int(object.Info.Size))
+	return nil
+}
+
+// RunPackageJob упаковывает готовый файл для HLS и DASH; вызывается пулом фоновых задач.
+func (s *service) RunPackageJob(ctx context.Context, job *repo.Job, progress func(percent int)) error {
+	if s.packager == nil {
+		return worker.Permanent(errors.New("packaging is disabled"))
+	}
+
+	asset, err := s.assetRepo.GetAsset(ctx, job.TitleKind, job.TitleID, job.AssetID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return worker.Permanent(err)
+		}
+		return err
+	}
+	if asset.Status != repo.AssetReady {
+		return worker.Permanent(errors.Errorf("media asset is %s, not ready", asset.Status))
+	}
+
+	_, err = s.packager.Package(ctx, asset.StorageKey, packagePrefix(asset.UUID), progress)
+	return err
+}
+
+// packagePrefix - каталог пакета медиафайла в хранилище.
+func packagePrefix(assetID string) string {
+	return "packages/" + assetID
+}
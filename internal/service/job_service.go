@@ -0,0 +1,65 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+// JobService показывает состояние фоновых задач: доступ к задаче - как на чтение ее тайтла.
+type JobService interface {
+	GetJob(ctx *fiber.Ctx) error
+	GetMovieJobs(ctx *fiber.Ctx) error
+	GetEpisodeJobs(ctx *fiber.Ctx) error
+}
+
+func (s *service) GetJob(ctx *fiber.Ctx) error {
+	req := GetJobRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	job, err := s.jobRepo.GetJob(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get job", zap.Error(err))
+		return err
+	}
+
+	if _, err := s.titleFor(ctx, job.TitleKind, job.TitleID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   job,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) GetMovieJobs(ctx *fiber.Ctx) error   { return s.getJobs(ctx, repo.TitleMovie) }
+func (s *service) GetEpisodeJobs(ctx *fiber.Ctx) error { return s.getJobs(ctx, repo.TitleEpisode) }
+
+func (s *service) getJobs(ctx *fiber.Ctx, kind string) error {
+	req := GetJobsRequest{TitleID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.titleFor(ctx, kind, req.TitleID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	jobs, err := s.jobRepo.GetTitleJobs(ctx.UserContext(), kind, req.TitleID)
+	if err != nil {
+		s.log.Error("Failed to get jobs", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   jobs,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type MemberService interface {
+	AddOwnerMember(ctx *fiber.Ctx) error
+	GetOwnerMembers(ctx *fiber.Ctx) error
+	RemoveOwnerMember(ctx *fiber.Ctx) error
+	SetUserRole(ctx *fiber.Ctx) error
+}
+
+func (s *service) AddOwnerMember(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	if ownerID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	var req AddOwnerMemberRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.authorize(ctx, ownerID, PermMembersManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	member := repo.OwnerMember{
+		OwnerID: ownerID,
+		UserID:  req.UserID,
+		Role:    req.Role,
+	}
+	if err := s.memberRepo.AddOwnerMember(ctx.UserContext(), &member); err != nil {
+		s.log.Error("Failed to add owner member", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   member,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetOwnerMembers(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	if ownerID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	if err := s.authorize(ctx, ownerID, PermMembersManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	members, err := s.memberRepo.GetOwnerMembers(ctx.UserContext(), ownerID)
+	if err != nil {
+		s.log.Error("Failed to get owner members", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   members,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) RemoveOwnerMember(ctx *fiber.Ctx) error {
+	ownerID := ctx.Params("id")
+	userID := ctx.Params("userId")
+	if ownerID == "" || userID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	if err := s.authorize(ctx, ownerID, PermMembersManage); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.memberRepo.RemoveOwnerMember(ctx.UserContext(), ownerID, userID); err != nil {
+		s.log.Error("Failed to remove owner member", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   userID,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) SetUserRole(ctx *fiber.Ctx) error {
+	userID := ctx.Params("id")
+	if userID == "" {
+		s.log.Error("Missing UUID in URL parameters")
+		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	}
+
+	var req SetUserRoleRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.userRepo.UpdateUserRole(ctx.UserContext(), userID, req.Role); err != nil {
+		s.log.Error("Failed to update user role", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data: map[string]string{
+			"userUUID": userID,
+			"role":     req.Role,
+		},
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
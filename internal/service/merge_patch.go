@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"streaming-service/internal/dto"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// readMergePatch проверяет Content-Type и разбирает тело запроса как документ патча.
+func readMergePatch(ctx *fiber.Ctx) (map[string]json.RawMessage, error) {
+	contentType, _, _ := strings.Cut(ctx.Get(fiber.HeaderContentType), ";")
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	if contentType != mergePatchContentType && contentType != fiber.MIMEApplicationJSON {
+		return nil, fiber.NewError(fiber.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+	}
+
+	patch, err := parseMergePatch(ctx.Body())
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid merge patch document: "+err.Error())
+	}
+
+	return patch, nil
+}
+
+// parseMergePatch разбирает тело запроса RFC 7396. Документ патча обязан быть JSON-объектом.
+func parseMergePatch(body []byte) (map[string]json.RawMessage, error) {
+	var patch map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return nil, fmt.Errorf("merge patch document must be a JSON object")
+	}
+
+	return patch, nil
+}
+
+// applyMergePatch накладывает патч на target (указатель на структуру с json-тегами).
+// null сбрасывает поле в нулевое значение, отсутствующие поля не меняются.
+// Возвращает json-имена измененных полей в порядке объявления в структуре.
+func applyMergePatch(patch map[string]json.RawMessage, target any, immutable ...string) ([]string, []dto.FieldError) {
+	value := reflect.ValueOf(target).Elem()
+	fieldsByName := make(map[string]int, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		fieldsByName[name] = i
+	}
+
+	var errs []dto.FieldError
+	for name := range patch {
+		_, known := fieldsByName[name]
+		if !known || contains(immutable, name) {
+			errs = append(errs, dto.FieldError{
+				Field:   name,
+				Code:    dto.FieldBadFormat,
+				Message: fmt.Sprintf("Field '%s' cannot be patched", name),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var changed []string
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		raw, ok := patch[name]
+		if !ok {
+			continue
+		}
+
+		field := value.Field(i)
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			field.Set(reflect.Zero(field.Type()))
+		} else if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			errs = append(errs, dto.FieldError{
+				Field:   name,
+				Code:    dto.FieldBadFormat,
+				Message: fmt.Sprintf("Field '%s' has invalid type", name),
+			})
+			continue
+		}
+		changed = append(changed, name)
+	}
+
+	return changed, errs
+}
+
+// patchedFields собирает значения измененных полей по их json-именам.
+func patchedFields(target any, changed []string) map[string]any {
+	value := reflect.ValueOf(target).Elem()
+	fields := make(map[string]any, len(changed))
+
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if contains(changed, name) {
+			fields[name] = value.Field(i).Interface()
+		}
+	}
+
+	return fields
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
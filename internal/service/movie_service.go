@@ -3,9 +3,13 @@ package service
 import (
 	"encoding/json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"strconv"
+	"strings"
+	"time"
 
+	"streaming-service/internal/auth"
 	"streaming-service/internal/dto"
 	"streaming-service/internal/repo"
 )
@@ -15,6 +19,7 @@ type MovieService interface {
 	GetMovie(c *fiber.Ctx) error
 	GetAllMovies(c *fiber.Ctx) error
 	UpdateMovie(c *fiber.Ctx) error
+	PatchMovie(c *fiber.Ctx) error
 	DeleteMovie(c *fiber.Ctx) error
 }
 
@@ -26,17 +31,78 @@ func (s *service) CreateMovie(ctx *fiber.Ctx) error {
 		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
 	}
 
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
 	movie := repo.Movie{
 		Title:       req.Title,
-		Author:      req.Author,
 		Description: req.Description,
 		Year:        req.Year,
 	}
-	movieID, err := s.movieRepo.CreateMovie(ctx.Context(), &movie, req.OwnerName)
+
+	// API-ключ уже однозначно задает владельца, owner_name не нужен
+	if identity, ok := auth.FromContext(ctx); ok && identity.APIKeyID != "" {
+		movie.OwnerID = identity.OwnerID
+	} else if req.OwnerName == "" {
+		return dto.BadRequestError(ctx, dto.FieldRequired, "Field 'owner_name' is required")
+	}
+
+	// Владелец ищется или создается в одной транзакции с фильмом, поэтому параллельные
+	// запросы с новым owner_name не плодят дубликатов, а неудачная вставка не оставляет сирот.
+	// Создавать владельцев, как и в CreateOwner, может только администратор
+	var movieID string
+	err := s.txRepo.WithTx(ctx.UserContext(), func(tx repo.Repositories) error {
+		created := false
+		if movie.OwnerID == "" {
+			if s.requireAdmin(ctx) == nil {
+				owner, isNew, err := tx.UpsertOwner(ctx.UserContext(), req.OwnerName)
+				if err != nil {
+					return err
+				}
+				movie.OwnerID, created = owner.UUID, isNew
+			} else {
+				owner, err := tx.GetOwnerByName(ctx.UserContext(), req.OwnerName)
+				if errors.Is(err, repo.ErrNotFound) {
+					return errForbidden
+				}
+				if err != nil {
+					return err
+				}
+				movie.OwnerID = owner.UUID
+			}
+		}
+
+		// Создатель нового владельца становится его участником, в существующего нужны права
+		if created {
+			if err := s.grantCreator(ctx, tx, movie.OwnerID); err != nil {
+				return errors.Wrap(err, "failed to grant owner membership")
+			}
+		} else if err := s.authorize(ctx, movie.OwnerID, PermMoviesWrite); err != nil {
+			return err
+		}
+
+		var err error
+		if movieID, err = tx.CreateMovie(ctx.UserContext(), &movie); err != nil {
+			return err
+		}
+		if err := setMovieTerms(ctx, tx, movieID, req.Genres, req.Tags); err != nil {
+			return err
+		}
+		credits, err := authorCredits(ctx, tx, movieID, optionalString(req.Author), "", req.Credits)
+		if err != nil {
+			return err
+		}
+		return setMovieCredits(ctx, tx, movieID, credits)
+	})
+	if errors.Is(err, errForbidden) {
+		return s.denied(ctx, err)
+	}
 	if err != nil {
 		s.log.Error("Failed to create movie", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
 
 	response := dto.Response{
 		Status: "success",
@@ -47,55 +113,85 @@ func (s *service) CreateMovie(ctx *fiber.Ctx) error {
 }
 
 func (s *service) GetMovie(ctx *fiber.Ctx) error {
-	uuid := ctx.Params("id")
-	if uuid == "" {
-		s.log.Error("Missing UUID in URL parameters")
-		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	req := GetMovieRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	uuid := req.UUID
+
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
 	}
 
-	movie, err := s.movieRepo.GetMovieByID(ctx.Context(), uuid)
+	movie, err := s.movieRepo.GetMovieByID(ctx.UserContext(), uuid)
 	if err != nil {
 		s.log.Error("Failed to get movie", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
+	}
+
+	ctx.Set(fiber.HeaderETag, etag(movie.Version))
+	if notModified(ctx, movie.Version) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	genres, tags, err := s.movieTerms(ctx, uuid)
+	if err != nil {
+		s.log.Error("Failed to get movie genres and tags", zap.Error(err))
+		return err
+	}
+
+	credits, err := s.peopleRepo.GetMovieCredits(ctx.UserContext(), uuid)
+	if err != nil {
+		s.log.Error("Failed to get movie credits", zap.Error(err))
+		return err
 	}
 
 	response := dto.Response{
 		Status: "success",
-		Data: map[string]string{
+		Data: map[string]interface{}{
 			"title":       movie.Title,
 			"author":      movie.Author,
 			"description": movie.Description,
 			"year":        strconv.Itoa(movie.Year),
+			"version":     strconv.Itoa(movie.Version),
+			"genres":      genres,
+			"tags":        tags,
+			"credits":     credits,
 		},
 	}
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
 
 func (s *service) GetAllMovies(ctx *fiber.Ctx) error {
-	limitStr := ctx.Query("limit", "10")
-	offsetStr := ctx.Query("offset", "0")
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		s.log.Error("Invalid limit parameter", zap.Error(err))
-		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid or missing 'limit' parameter")
+	var query ListMoviesQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		s.log.Error("Invalid offset parameter", zap.Error(err))
-		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid or missing 'offset' parameter")
+	errs := validateRequest(&query)
+	page, pageErrs := parsePageRequest(ctx)
+	errs = append(errs, pageErrs...)
+	sorts, sortErrs := parseSort(repo.MovieSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
 	}
-	movies, err := s.movieRepo.GetAllMovies(ctx.Context(), limit, offset)
+
+	spec := movieListSpec(&query)
+	spec.Sorts = sorts
+
+	movies, err := s.movieRepo.GetAllMovies(ctx.UserContext(), spec, page)
 	if err != nil {
 		s.log.Error("Failed to get movies", zap.Error(err))
-		return dto.InternalServerError(ctx)
-	}
-	response := dto.Response{
-		Status: "success",
-		Data:   movies,
+		return err
 	}
-	return ctx.Status(fiber.StatusOK).JSON(response)
+
+	return sendPage(ctx, movies)
 }
 
 func (s *service) UpdateMovie(ctx *fiber.Ctx) error {
@@ -105,17 +201,61 @@ func (s *service) UpdateMovie(ctx *fiber.Ctx) error {
 		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
 	}
 
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	movie, err := s.movieRepo.GetMovieByID(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get movie", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, movie.OwnerID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	version, err := checkIfMatch(ctx, "movie", movie.Version)
+	if err != nil {
+		return err
+	}
+
 	updatedMovie := repo.Movie{
 		Title:       req.Title,
 		Description: req.Description,
-		Author:      req.Author,
 		Year:        req.Year,
+		Version:     version,
 	}
 
-	if err := s.movieRepo.UpdateMovie(ctx.Context(), req.UUID, &updatedMovie); err != nil {
+	err = s.txRepo.WithTx(ctx.UserContext(), func(tx repo.Repositories) error {
+		if err := tx.UpdateMovie(ctx.UserContext(), req.UUID, &updatedMovie); err != nil {
+			return err
+		}
+		if err := setMovieTerms(ctx, tx, req.UUID, req.Genres, req.Tags); err != nil {
+			return err
+		}
+		credits, err := authorCredits(ctx, tx, req.UUID, optionalString(req.Author), movie.Author, req.Credits)
+		if err != nil {
+			return err
+		}
+		if err := setMovieCredits(ctx, tx, req.UUID, credits); err != nil {
+			return err
+		}
+
+		updated, err := tx.GetMovieByID(ctx.UserContext(), req.UUID)
+		if err != nil {
+			return err
+		}
+		updatedMovie.Author = updated.Author
+		return nil
+	})
+	if err != nil {
 		s.log.Error("Failed to update movie", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
+
+	ctx.Set(fiber.HeaderETag, etag(updatedMovie.Version))
 
 	response := dto.Response{
 		Status: "success",
@@ -127,17 +267,169 @@ func (s *service) UpdateMovie(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
 
+func (s *service) PatchMovie(ctx *fiber.Ctx) error {
+	req := GetMovieRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	patch, err := readMergePatch(ctx)
+	if err != nil {
+		s.log.Error("Invalid merge patch", zap.Error(err))
+		return err
+	}
+
+	movie, err := s.movieRepo.GetMovieByID(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get movie", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, movie.OwnerID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	version, err := checkIfMatch(ctx, "movie", movie.Version)
+	if err != nil {
+		return err
+	}
+
+	genres, tags, err := s.movieTerms(ctx, req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get movie genres and tags", zap.Error(err))
+		return err
+	}
+
+	credits, err := s.peopleRepo.GetMovieCredits(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get movie credits", zap.Error(err))
+		return err
+	}
+
+	// Патч накладывается на текущее состояние, и результат проверяется целиком
+	merged := UpdateMovieRequest{
+		UUID:        movie.UUID,
+		Title:       movie.Title,
+		Author:      movie.Author,
+		Description: movie.Description,
+		Year:        movie.Year,
+		Genres:      termSlugs(genres),
+		Tags:        termSlugs(tags),
+		Credits:     creditRequests(credits),
+	}
+	changed, errs := applyMergePatch(patch, &merged, "uuid")
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	if errs := validateRequest(&merged); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// Жанры, теги и титры - не колонки фильма, они заменяются отдельно; null очищает набор.
+	// author тоже не колонка, а режиссер в титрах
+	var columns []string
+	var newGenres, newTags []string
+	var newCredits []CreditRequest
+	var newAuthor *string
+	for _, field := range changed {
+		switch field {
+		case "author":
+			// null и "" убирают режиссеров
+			newAuthor = &merged.Author
+		case "genres":
+			newGenres = append([]string{}, merged.Genres...)
+		case "tags":
+			newTags = append([]string{}, merged.Tags...)
+		case "credits":
+			newCredits = append([]CreditRequest{}, merged.Credits...)
+		default:
+			columns = append(columns, field)
+		}
+	}
+
+	// Пустой патч ничего не меняет и версию не увеличивает
+	patched := movie
+	if len(changed) > 0 {
+		err = s.txRepo.WithTx(ctx.UserContext(), func(tx repo.Repositories) error {
+			var err error
+			if patched, err = tx.PatchMovie(ctx.UserContext(), req.UUID, version, patchedFields(&merged, columns)); err != nil {
+				return err
+			}
+			if err := setMovieTerms(ctx, tx, req.UUID, newGenres, newTags); err != nil {
+				return err
+			}
+			credits, err := authorCredits(ctx, tx, req.UUID, newAuthor, movie.Author, newCredits)
+			if err != nil {
+				return err
+			}
+			if err := setMovieCredits(ctx, tx, req.UUID, credits); err != nil {
+				return err
+			}
+
+			updated, err := tx.GetMovieByID(ctx.UserContext(), req.UUID)
+			if err != nil {
+				return err
+			}
+			patched.Author = updated.Author
+			return nil
+		})
+		if err != nil {
+			s.log.Error("Failed to patch movie", zap.Error(err))
+			return err
+		}
+		s.suggestions.Purge()
+
+		if genres, tags, err = s.movieTerms(ctx, req.UUID); err != nil {
+			s.log.Error("Failed to get movie genres and tags", zap.Error(err))
+			return err
+		}
+		if credits, err = s.peopleRepo.GetMovieCredits(ctx.UserContext(), req.UUID); err != nil {
+			s.log.Error("Failed to get movie credits", zap.Error(err))
+			return err
+		}
+	}
+
+	ctx.Set(fiber.HeaderETag, etag(patched.Version))
+
+	response := dto.Response{
+		Status: "success",
+		Data: movieResponse{
+			Movie:   patched,
+			Genres:  genres,
+			Tags:    tags,
+			Credits: credits,
+		},
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
 func (s *service) DeleteMovie(ctx *fiber.Ctx) error {
-	uuid := ctx.Params("id")
-	if uuid == "" {
-		s.log.Error("Missing UUID in URL parameters")
-		return dto.BadRequestError(ctx, dto.FieldRequired, "UUID is required")
+	req := DeleteMovieRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
 	}
+	uuid := req.UUID
 
-	if err := s.movieRepo.DeleteMovie(ctx.Context(), uuid); err != nil {
+	movie, err := s.movieRepo.GetMovieByID(ctx.UserContext(), uuid)
+	if err != nil {
+		s.log.Error("Failed to get movie", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, movie.OwnerID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	version, err := checkIfMatch(ctx, "movie", movie.Version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.movieRepo.DeleteMovie(ctx.UserContext(), uuid, version); err != nil {
 		s.log.Error("Failed to delete movie", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
 
 	response := dto.Response{
 		Status: "success",
@@ -145,3 +437,60 @@ func (s *service) DeleteMovie(ctx *fiber.Ctx) error {
 	}
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
+
+// movieListSpec переводит провалидированные параметры запроса в фильтры репозитория.
+func movieListSpec(query *ListMoviesQuery) repo.ListSpec {
+	var spec repo.ListSpec
+
+	if query.OwnerID != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "owner_id", Op: repo.OpEq, Value: query.OwnerID})
+	}
+	if query.Author != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "author", Op: repo.OpIEq, Value: query.Author})
+	}
+	if query.YearFrom != 0 {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "year", Op: repo.OpGte, Value: query.YearFrom})
+	}
+	if query.YearTo != 0 {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "year", Op: repo.OpLte, Value: query.YearTo})
+	}
+	if query.CreatedAfter != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "created_at", Op: repo.OpGt, Value: parseDateTime(query.CreatedAfter)})
+	}
+	for _, slug := range splitList(query.Genre) {
+		spec.Where = append(spec.Where, repo.MovieHasTerm(repo.Genres, slug))
+	}
+	for _, slug := range splitList(query.Tag) {
+		spec.Where = append(spec.Where, repo.MovieHasTerm(repo.Tags, slug))
+	}
+
+	return spec
+}
+
+// parseDateTime принимает дату или RFC 3339; формат уже проверен тегом datetime.
+func parseDateTime(value string) time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC()
+	}
+	t, _ := time.Parse(time.DateOnly, value)
+	return t
+}
+
+// movieResponse - фильм вместе с жанрами, тегами и титрами.
+type movieResponse struct {
+	*repo.Movie
+	Genres  []*repo.Term   `json:"genres"`
+	Tags    []*repo.Term   `json:"tags"`
+	Credits []*repo.Credit `json:"credits"`
+}
+
+// splitList разбирает список значений через запятую, пропуская пустые.
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/cache"
+	"streaming-service/internal/repo"
+)
+
+// fakeCatalog - каталог в памяти для проверок сервисного слоя; транзакция - он сам.
+type fakeCatalog struct {
+	repo.Repositories
+	owners  map[string]string // имя -> uuid
+	members map[string]string // владелец -> роль участника
+	upserts []string
+	movies  []*repo.Movie
+	// Фильм для изменения, его титры и люди каталога (имя -> uuid)
+	movie   *repo.Movie
+	credits []*repo.Credit
+	people  map[string]string
+}
+
+func newFakeCatalog() *fakeCatalog {
+	return &fakeCatalog{owners: make(map[string]string), members: make(map[string]string), people: make(map[string]string)}
+}
+
+func (f *fakeCatalog) WithTx(_ context.Context, fn func(tx repo.Repositories) error) error {
+	return fn(f)
+}
+
+func (f *fakeCatalog) UpsertOwner(_ context.Context, name string) (*repo.Owner, bool, error) {
+	f.upserts = append(f.upserts, name)
+	if id, ok := f.owners[name]; ok {
+		return &repo.Owner{UUID: id, Name: name}, false, nil
+	}
+	f.owners[name] = uuid.New().String()
+	return &repo.Owner{UUID: f.owners[name], Name: name}, true, nil
+}
+
+func (f *fakeCatalog) GetOwnerByName(_ context.Context, name string) (*repo.Owner, error) {
+	id, ok := f.owners[name]
+	if !ok {
+		return nil, &repo.Error{Kind: repo.ErrNotFound, Entity: "owner"}
+	}
+	return &repo.Owner{UUID: id, Name: name}, nil
+}
+
+func (f *fakeCatalog) GetOwnerMember(_ context.Context, ownerID, userID string) (*repo.OwnerMember, error) {
+	role, ok := f.members[ownerID]
+	if !ok {
+		return nil, &repo.Error{Kind: repo.ErrNotFound, Entity: "owner member"}
+	}
+	return &repo.OwnerMember{OwnerID: ownerID, UserID: userID, Role: role}, nil
+}
+
+func (f *fakeCatalog) AddOwnerMember(_ context.Context, member *repo.OwnerMember) error {
+	f.members[member.OwnerID] = member.Role
+	return nil
+}
+
+func (f *fakeCatalog) CreateMovie(_ context.Context, movie *repo.Movie) (string, error) {
+	created := *movie
+	created.UUID = uuid.New().String()
+	f.movies = append(f.movies, &created)
+	return created.UUID, nil
+}
+
+func (f *fakeCatalog) GetMovieByID(context.Context, string) (*repo.Movie, error) {
+	movie := *f.movie
+	return &movie, nil
+}
+
+func (f *fakeCatalog) PatchMovie(_ context.Context, _ string, _ int, fields map[string]any) (*repo.Movie, error) {
+	if title, ok := fields["title"]; ok {
+		f.movie.Title = title.(string)
+	}
+	f.movie.Version++
+	movie := *f.movie
+	return &movie, nil
+}
+
+func (f *fakeCatalog) GetMovieTerms(context.Context, repo.Taxonomy, string) ([]*repo.Term, error) {
+	return []*repo.Term{}, nil
+}
+
+func (f *fakeCatalog) GetMovieCredits(context.Context, string) ([]*repo.Credit, error) {
+	return append([]*repo.Credit{}, f.credits...), nil
+}
+
+// SetMovieCredits, как и репозиторий, пересчитывает author по режиссерам.
+func (f *fakeCatalog) SetMovieCredits(_ context.Context, _ string, credits []*repo.Credit) error {
+	var directors []string
+	for _, credit := range credits {
+		for name, id := range f.people {
+			if id == credit.PersonID {
+				credit.PersonName = name
+			}
+		}
+		if credit.Role == repo.CreditDirector {
+			directors = append(directors, credit.PersonName)
+		}
+	}
+	f.credits = credits
+	f.movie.Author = strings.Join(directors, ", ")
+	return nil
+}
+
+func (f *fakeCatalog) FindPersonByName(_ context.Context, name string) (*repo.Person, error) {
+	id, ok := f.people[name]
+	if !ok {
+		return nil, &repo.Error{Kind: repo.ErrNotFound, Entity: "person"}
+	}
+	return &repo.Person{UUID: id, Name: name}, nil
+}
+
+func newCatalogApp(catalog *fakeCatalog, identity *auth.Identity) *fiber.App {
+	s := &service{
+		txRepo:       catalog,
+		movieRepo:    catalog,
+		memberRepo:   catalog,
+		peopleRepo:   catalog,
+		taxonomyRepo: catalog,
+		suggestions:  cache.NewLRU[string, []*repo.Suggestion](8),
+		log:          zap.NewNop().Sugar(),
+	}
+
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		auth.SetIdentity(ctx, identity)
+		return ctx.Next()
+	})
+	app.Post("/movies", s.CreateMovie)
+	app.Put("/movies/:id", s.UpdateMovie)
+	app.Patch("/movies/:id", s.PatchMovie)
+	return app
+}
+
+func sendJSON(t *testing.T, app *fiber.App, method, path, body string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if method == http.MethodPatch {
+		req.Header.Set(fiber.HeaderContentType, mergePatchContentType)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCreateMovieOwner(t *testing.T) {
+	const body = `{"title": "Movie", "year": 2000, "owner_name": "studio"}`
+
+	tests := []struct {
+		name     string
+		role     auth.Role
+		existing string // роль вызывающего в уже существующем владельце studio; "" - владельца нет
+		status   int
+		upserted bool
+	}{
+		{name: "viewer cannot create owner", role: auth.RoleViewer, status: http.StatusForbidden},
+		{name: "editor cannot create owner", role: auth.RoleEditor, status: http.StatusForbidden},
+		{name: "admin creates owner", role: auth.RoleAdmin, status: http.StatusCreated, upserted: true},
+		{name: "member of existing owner", role: auth.RoleViewer, existing: string(auth.RoleEditor), status: http.StatusCreated},
+		{name: "viewer of existing owner", role: auth.RoleViewer, existing: string(auth.RoleViewer), status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := newFakeCatalog()
+			if tt.existing != "" {
+				catalog.owners["studio"] = uuid.New().String()
+				catalog.members[catalog.owners["studio"]] = tt.existing
+			}
+			app := newCatalogApp(catalog, &auth.Identity{UserID: uuid.New().String(), Role: tt.role})
+
+			if status := sendJSON(t, app, http.MethodPost, "/movies", body); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if upserted := len(catalog.upserts) > 0; upserted != tt.upserted {
+				t.Errorf("UpsertOwner called = %v, want %v", upserted, tt.upserted)
+			}
+			if tt.existing == "" && tt.status == http.StatusForbidden && len(catalog.owners) != 0 {
+				t.Errorf("owners = %v, want none created", catalog.owners)
+			}
+			if created := len(catalog.movies) == 1; created != (tt.status == http.StatusCreated) {
+				t.Errorf("movies = %d", len(catalog.movies))
+			}
+		})
+	}
+}
+
+func TestPatchMovieAuthor(t *testing.T) {
+	alice, bob, carol := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	actor := &repo.Credit{PersonID: bob, PersonName: "Bob", Role: repo.CreditActor, Character: "Hero"}
+
+	tests := []struct {
+		name      string
+		patch     string
+		status    int
+		author    string
+		directors []string
+	}{
+		{name: "null removes directors", patch: `{"author": null}`, status: http.StatusOK, author: ""},
+		{name: "empty removes directors", patch: `{"author": "  "}`, status: http.StatusOK, author: ""},
+		{name: "existing person", patch: `{"author": "Carol"}`, status: http.StatusOK, author: "Carol", directors: []string{carol}},
+		{name: "unchanged author", patch: `{"author": "Alice", "title": "Other"}`, status: http.StatusOK, author: "Alice",
+			directors: []string{alice}},
+		{name: "unknown person", patch: `{"author": "Nobody"}`, status: http.StatusUnprocessableEntity, author: "Alice",
+			directors: []string{alice}},
+		{name: "credits directors win", patch: `{"author": "Carol", "credits": [{"person_id": "` + alice + `", "role": "director"}]}`,
+			status: http.StatusOK, author: "Alice", directors: []string{alice}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := newFakeCatalog()
+			catalog.people = map[string]string{"Alice": alice, "Bob": bob, "Carol": carol}
+			catalog.movie = &repo.Movie{UUID: uuid.New().String(), OwnerID: uuid.New().String(), Title: "Movie",
+				Author: "Alice", Year: 2000, Version: 1}
+			catalog.credits = []*repo.Credit{{PersonID: alice, PersonName: "Alice", Role: repo.CreditDirector}, actor}
+			app := newCatalogApp(catalog, &auth.Identity{UserID: uuid.New().String(), Role: auth.RoleAdmin})
+
+			if status := sendJSON(t, app, http.MethodPatch, "/movies/"+catalog.movie.UUID, tt.patch); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if catalog.movie.Author != tt.author {
+				t.Errorf("author = %q, want %q", catalog.movie.Author, tt.author)
+			}
+
+			var directors []string
+			for _, credit := range catalog.credits {
+				if credit.Role == repo.CreditDirector {
+					directors = append(directors, credit.PersonID)
+				}
+			}
+			if strings.Join(directors, ",") != strings.Join(tt.directors, ",") {
+				t.Errorf("directors = %v, want %v", directors, tt.directors)
+			}
+			actors := 0
+			for _, credit := range catalog.credits {
+				if credit.PersonID == bob && credit.Role == repo.CreditActor {
+					actors++
+				}
+			}
+			if !strings.Contains(tt.patch, "credits") && actors != 1 {
+				t.Errorf("credits = %+v, want the actor kept", catalog.credits)
+			}
+		})
+	}
+}
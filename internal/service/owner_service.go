@@ -3,8 +3,8 @@ package service
 import (
 	"encoding/json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"strconv"
 	"streaming-service/internal/dto"
 	"streaming-service/internal/repo"
 )
@@ -15,6 +15,7 @@ type OwnerService interface {
 	GetOwnerByName(ctx *fiber.Ctx) error
 	GetAllOwners(ctx *fiber.Ctx) error
 	UpdateOwner(ctx *fiber.Ctx) error
+	PatchOwner(ctx *fiber.Ctx) error
 	DeleteOwner(ctx *fiber.Ctx) error
 }
 
@@ -26,14 +27,32 @@ func (s *service) CreateOwner(ctx *fiber.Ctx) error {
 		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
 	}
 
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// Глобальная роль пользователя - admin или viewer, а API-ключ действует только внутри своего владельца,
+	// поэтому заводить новых владельцев могут только администраторы
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
 	owner := repo.Owner{
 		Name: req.Name,
 	}
-	ownerID, err := s.ownerRepo.CreateOwner(ctx.Context(), &owner)
+	var ownerID string
+	err := s.txRepo.WithTx(ctx.UserContext(), func(tx repo.Repositories) error {
+		var err error
+		if ownerID, err = tx.CreateOwner(ctx.UserContext(), &owner); err != nil {
+			return err
+		}
+		return errors.Wrap(s.grantCreator(ctx, tx, ownerID), "failed to grant owner membership")
+	})
 	if err != nil {
 		s.log.Error("Failed to create owner", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
 
 	response := dto.Response{
 		Status: "success",
@@ -44,16 +63,21 @@ func (s *service) CreateOwner(ctx *fiber.Ctx) error {
 }
 
 func (s *service) GetOwnerByUUID(ctx *fiber.Ctx) error {
-	uuid := ctx.Params("uuid")
-	if uuid == "" {
-		s.log.Error("Missing UUID in URL parameters", zap.String("uuid", uuid))
-		return dto.BadRequestError(ctx, dto.FieldRequired, "Missing UUID in URL parameters")
+	req := GetOwnerByUUIDRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
 	}
+	uuid := req.UUID
 
-	owner, err := s.ownerRepo.GetOwnerByID(ctx.Context(), uuid)
+	owner, err := s.ownerRepo.GetOwnerByID(ctx.UserContext(), uuid)
 	if err != nil {
 		s.log.Error("Failed to get owner", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
+	}
+
+	ctx.Set(fiber.HeaderETag, etag(owner.Version))
+	if notModified(ctx, owner.Version) {
+		return ctx.SendStatus(fiber.StatusNotModified)
 	}
 
 	response := dto.Response{
@@ -61,6 +85,7 @@ func (s *service) GetOwnerByUUID(ctx *fiber.Ctx) error {
 		Data: map[string]interface{}{
 			"uuid":       owner.UUID,
 			"name":       owner.Name,
+			"version":    owner.Version,
 			"created_at": owner.Created_at,
 		},
 	}
@@ -68,16 +93,21 @@ func (s *service) GetOwnerByUUID(ctx *fiber.Ctx) error {
 }
 
 func (s *service) GetOwnerByName(ctx *fiber.Ctx) error {
-	name := ctx.Params("name")
-	if name == "" {
-		s.log.Error("Missing name in URL parameters", zap.String("name", name))
-		return dto.BadRequestError(ctx, dto.FieldRequired, "Missing name in URL parameters")
+	req := GetOwnerByNameRequest{Name: ctx.Params("name")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
 	}
+	name := req.Name
 
-	owner, err := s.ownerRepo.GetOwnerByName(ctx.Context(), name)
+	owner, err := s.ownerRepo.GetOwnerByName(ctx.UserContext(), name)
 	if err != nil {
 		s.log.Error("Failed to get owner", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
+	}
+
+	ctx.Set(fiber.HeaderETag, etag(owner.Version))
+	if notModified(ctx, owner.Version) {
+		return ctx.SendStatus(fiber.StatusNotModified)
 	}
 
 	response := dto.Response{
@@ -85,6 +115,7 @@ func (s *service) GetOwnerByName(ctx *fiber.Ctx) error {
 		Data: map[string]interface{}{
 			"uuid":       owner.UUID,
 			"name":       owner.Name,
+			"version":    owner.Version,
 			"created_at": owner.Created_at,
 		},
 	}
@@ -92,30 +123,26 @@ func (s *service) GetOwnerByName(ctx *fiber.Ctx) error {
 }
 
 func (s *service) GetAllOwners(ctx *fiber.Ctx) error {
-	limitStr := ctx.Query("limit", "10")
-	offsetStr := ctx.Query("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		s.log.Error("Invalid limit parameter", zap.Error(err))
-		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid or missing 'limit' parameter")
+	var query ListOwnersQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		s.log.Error("Invalid offset parameter", zap.Error(err))
-		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid or missing 'offset' parameter")
+	page, errs := parsePageRequest(ctx)
+	sorts, sortErrs := parseSort(repo.OwnerSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
 	}
-	owners, err := s.ownerRepo.GetAllOwners(ctx.Context(), limit, offset)
+
+	owners, err := s.ownerRepo.GetAllOwners(ctx.UserContext(), repo.ListSpec{Sorts: sorts}, page)
 	if err != nil {
 		s.log.Error("Failed to get owners", zap.Error(err))
-		return dto.InternalServerError(ctx)
-	}
-	response := dto.Response{
-		Status: "success",
-		Data:   owners,
+		return err
 	}
-	return ctx.Status(fiber.StatusOK).JSON(response)
+
+	return sendPage(ctx, owners)
 }
 
 func (s *service) UpdateOwner(ctx *fiber.Ctx) error {
@@ -125,14 +152,31 @@ func (s *service) UpdateOwner(ctx *fiber.Ctx) error {
 		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
 	}
 
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.authorize(ctx, req.UUID, PermOwnersWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	version, err := s.ownerIfMatch(ctx, req.UUID)
+	if err != nil {
+		return err
+	}
+
 	updatedOwner := repo.Owner{
-		Name: req.Name,
+		Name:    req.Name,
+		Version: version,
 	}
 
-	if err := s.ownerRepo.UpdateOwner(ctx.Context(), req.UUID, &updatedOwner); err != nil {
+	if err := s.ownerRepo.UpdateOwner(ctx.UserContext(), req.UUID, &updatedOwner); err != nil {
 		s.log.Error("Failed to update movie", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
+
+	ctx.Set(fiber.HeaderETag, etag(updatedOwner.Version))
 
 	response := dto.Response{
 		Status: "success",
@@ -144,17 +188,82 @@ func (s *service) UpdateOwner(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
 
+func (s *service) PatchOwner(ctx *fiber.Ctx) error {
+	req := GetOwnerByUUIDRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	patch, err := readMergePatch(ctx)
+	if err != nil {
+		s.log.Error("Invalid merge patch", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, req.UUID, PermOwnersWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	owner, err := s.ownerRepo.GetOwnerByID(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get owner", zap.Error(err))
+		return err
+	}
+
+	version, err := checkIfMatch(ctx, "owner", owner.Version)
+	if err != nil {
+		return err
+	}
+
+	merged := UpdateOwnerRequest{
+		UUID: owner.UUID,
+		Name: owner.Name,
+	}
+	changed, errs := applyMergePatch(patch, &merged, "uuid")
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	if errs := validateRequest(&merged); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	patched, err := s.ownerRepo.PatchOwner(ctx.UserContext(), req.UUID, version, patchedFields(&merged, changed))
+	if err != nil {
+		s.log.Error("Failed to patch owner", zap.Error(err))
+		return err
+	}
+	s.suggestions.Purge()
+
+	ctx.Set(fiber.HeaderETag, etag(patched.Version))
+
+	response := dto.Response{
+		Status: "success",
+		Data:   patched,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
 func (s *service) DeleteOwner(ctx *fiber.Ctx) error {
-	uuid := ctx.Params("uuid")
-	if uuid == "" {
-		s.log.Error("Missing UUID in URL parameters", zap.String("uuid", uuid))
-		return dto.BadRequestError(ctx, dto.FieldRequired, "Missing UUID in URL parameters")
+	req := DeleteOwnerRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	uuid := req.UUID
+
+	if err := s.authorize(ctx, uuid, PermOwnersDelete); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	version, err := s.ownerIfMatch(ctx, uuid)
+	if err != nil {
+		return err
 	}
 
-	if err := s.ownerRepo.DeleteOwner(ctx.Context(), uuid); err != nil {
+	if err := s.ownerRepo.DeleteOwner(ctx.UserContext(), uuid, version); err != nil {
 		s.log.Error("Failed to delete owner", zap.Error(err))
-		return dto.InternalServerError(ctx)
+		return err
 	}
+	s.suggestions.Purge()
 
 	response := dto.Response{
 		Status: "success",
@@ -162,3 +271,18 @@ func (s *service) DeleteOwner(ctx *fiber.Ctx) error {
 	}
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
+
+// ownerIfMatch загружает владельца для сверки версии, только если пришел If-Match.
+func (s *service) ownerIfMatch(ctx *fiber.Ctx, uuid string) (int, error) {
+	if ctx.Get(fiber.HeaderIfMatch) == "" {
+		return 0, nil
+	}
+
+	owner, err := s.ownerRepo.GetOwnerByID(ctx.UserContext(), uuid)
+	if err != nil {
+		s.log.Error("Failed to get owner", zap.Error(err))
+		return 0, err
+	}
+
+	return checkIfMatch(ctx, "owner", owner.Version)
+}
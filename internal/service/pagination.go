@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+// parsePageRequest читает limit, cursor и total из query-параметров.
+func parsePageRequest(ctx *fiber.Ctx) (repo.PageRequest, []dto.FieldError) {
+	var errs []dto.FieldError
+	page := repo.PageRequest{Limit: repo.DefaultPageLimit}
+
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > repo.MaxPageLimit {
+			errs = append(errs, dto.FieldError{
+				Field:   "limit",
+				Code:    dto.FieldBadFormat,
+				Message: fmt.Sprintf("Field 'limit' must be an integer between 1 and %d", repo.MaxPageLimit),
+			})
+		}
+		page.Limit = limit
+	}
+
+	if value := ctx.Query("cursor"); value != "" {
+		cursor, err := repo.DecodeCursor(value)
+		if err != nil {
+			errs = append(errs, dto.FieldError{
+				Field:   "cursor",
+				Code:    dto.FieldBadFormat,
+				Message: "Field 'cursor' is not a valid page cursor",
+			})
+		}
+		page.Cursor = cursor
+	}
+
+	if value := ctx.Query("total"); value != "" {
+		withTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, dto.FieldError{
+				Field:   "total",
+				Code:    dto.FieldBadFormat,
+				Message: "Field 'total' must be a boolean",
+			})
+		}
+		page.WithTotal = withTotal
+	}
+
+	return page, errs
+}
+
+// parseSort разбирает параметр sort по белому списку полей схемы.
+func parseSort(schema repo.Schema, value string) ([]repo.Sort, []dto.FieldError) {
+	sorts, err := schema.ParseSort(value)
+	if err != nil {
+		return nil, []dto.FieldError{{
+			Field:   "sort",
+			Code:    dto.FieldBadFormat,
+			Message: "Field 'sort' is invalid: " + err.Error(),
+		}}
+	}
+	return sorts, nil
+}
+
+// sendPage отдает страницу упорядоченным массивом и дублирует курсоры в Link (RFC 8288).
+func sendPage[T any](ctx *fiber.Ctx, page *repo.Page[T]) error {
+	var links []string
+	if page.NextCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(ctx, page.NextCursor)))
+	}
+	if page.PrevCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(ctx, page.PrevCursor)))
+	}
+	if len(links) > 0 {
+		ctx.Set(fiber.HeaderLink, strings.Join(links, ", "))
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data: dto.Page{
+			Items:      page.Items,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+			Total:      page.Total,
+		},
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func pageURL(ctx *fiber.Ctx, cursor string) string {
+	query := url.Values{}
+	for key, value := range ctx.Queries() {
+		query.Set(key, value)
+	}
+	query.Set("cursor", cursor)
+
+	return ctx.BaseURL() + ctx.Path() + "?" + query.Encode()
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type PeopleService interface {
+	CreatePerson(ctx *fiber.Ctx) error
+	GetPeople(ctx *fiber.Ctx) error
+	GetPerson(ctx *fiber.Ctx) error
+	UpdatePerson(ctx *fiber.Ctx) error
+	DeletePerson(ctx *fiber.Ctx) error
+	GetFilmography(ctx *fiber.Ctx) error
+}
+
+// Люди, как и словари, общие для всего каталога: менять их может только администратор.
+func (s *service) CreatePerson(ctx *fiber.Ctx) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var req PersonRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	person, err := s.peopleRepo.CreatePerson(ctx.UserContext(), &repo.Person{Name: req.Name, Bio: req.Bio})
+	if err != nil {
+		s.log.Error("Failed to create person", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   person,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetPeople(ctx *fiber.Ctx) error {
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var query ListPeopleQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+
+	errs := validateRequest(&query)
+	page, pageErrs := parsePageRequest(ctx)
+	errs = append(errs, pageErrs...)
+	sorts, sortErrs := parseSort(repo.PeopleSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	spec := repo.ListSpec{Sorts: sorts}
+	if query.Name != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "name", Op: repo.OpIEq, Value: query.Name})
+	}
+
+	people, err := s.peopleRepo.GetPeople(ctx.UserContext(), spec, page)
+	if err != nil {
+		s.log.Error("Failed to get people", zap.Error(err))
+		return err
+	}
+
+	return sendPage(ctx, people)
+}
+
+func (s *service) GetPerson(ctx *fiber.Ctx) error {
+	req := GetPersonRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	person, err := s.peopleRepo.GetPersonByID(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get person", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   person,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) UpdatePerson(ctx *fiber.Ctx) error {
+	current := GetPersonRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&current); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var req PersonRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	person, err := s.peopleRepo.UpdatePerson(ctx.UserContext(), current.UUID, &repo.Person{Name: req.Name, Bio: req.Bio})
+	if err != nil {
+		s.log.Error("Failed to update person", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   person,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) DeletePerson(ctx *fiber.Ctx) error {
+	req := GetPersonRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.peopleRepo.DeletePerson(ctx.UserContext(), req.UUID); err != nil {
+		s.log.Error("Failed to delete person", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   req.UUID,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) GetFilmography(ctx *fiber.Ctx) error {
+	req := GetPersonRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	entries, err := s.peopleRepo.GetFilmography(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get filmography", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   entries,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// setMovieCredits заменяет титры фильма; nil означает "не менять".
+func setMovieCredits(ctx *fiber.Ctx, tx repo.Repositories, movieID string, credits []CreditRequest) error {
+	if credits == nil {
+		return nil
+	}
+
+	repoCredits := make([]*repo.Credit, 0, len(credits))
+	for _, credit := range credits {
+		repoCredits = append(repoCredits, &repo.Credit{
+			PersonID:     credit.PersonID,
+			Role:         credit.Role,
+			Character:    credit.Character,
+			BillingOrder: credit.BillingOrder,
+		})
+	}
+
+	return tx.SetMovieCredits(ctx.UserContext(), movieID, repoCredits)
+}
+
+// authorCredits сводит поле author к титрам: author фильма - это его режиссеры, и колонку пересчитывает
+// репозиторий. author == nil или совпадающий с текущим currentAuthor ничего не задает. Иначе он заменяет
+// режиссеров, если в новых титрах их нет или титры не меняются (credits == nil): пустой author убирает
+// режиссеров, непустой должен быть именем уже существующего человека - создавать людей может только
+// администратор через /people. Возвращает титры для setMovieCredits: nil - титры не меняются.
+func authorCredits(ctx *fiber.Ctx, tx repo.Repositories, movieID string, author *string, currentAuthor string,
+	credits []CreditRequest) ([]CreditRequest, error) {
+	if author == nil || strings.TrimSpace(*author) == currentAuthor {
+		return credits, nil
+	}
+	name := strings.TrimSpace(*author)
+
+	if credits != nil {
+		if hasDirector(credits) {
+			return credits, nil
+		}
+	} else {
+		current, err := tx.GetMovieCredits(ctx.UserContext(), movieID)
+		if err != nil {
+			return nil, err
+		}
+		credits = make([]CreditRequest, 0, len(current))
+		for _, credit := range creditRequests(current) {
+			if credit.Role != repo.CreditDirector {
+				credits = append(credits, credit)
+			}
+		}
+	}
+
+	if name == "" {
+		return credits, nil
+	}
+
+	person, err := tx.FindPersonByName(ctx.UserContext(), name)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, "Field 'author' must be the name of an existing person")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(credits, CreditRequest{PersonID: person.UUID, Role: repo.CreditDirector}), nil
+}
+
+func hasDirector(credits []CreditRequest) bool {
+	for _, credit := range credits {
+		if credit.Role == repo.CreditDirector {
+			return true
+		}
+	}
+	return false
+}
+
+func creditRequests(credits []*repo.Credit) []CreditRequest {
+	requests := make([]CreditRequest, 0, len(credits))
+	for _, credit := range credits {
+		requests = append(requests, CreditRequest{
+			PersonID:     credit.PersonID,
+			Role:         credit.Role,
+			Character:    credit.Character,
+			BillingOrder: credit.BillingOrder,
+		})
+	}
+	return requests
+}
+
+// optionalString - nil для пустой строки: в POST и PUT пустой author означает, что он не передан.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
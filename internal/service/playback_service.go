@@ -0,0 +1,97 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dash"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/hls"
+	"streaming-service/internal/repo"
+)
+
+// PlaybackService выдает подписанные ссылки воспроизведения /v1/play/<token>/...: плеер ходит по ним
+// без заголовка Authorization, а сама ссылка действует только для одного тайтла и до истечения срока.
+// Токен стоит в пути, поэтому относительные ссылки плейлистов HLS и манифеста DASH его сохраняют.
+type PlaybackService interface {
+	CreateMoviePlayback(ctx *fiber.Ctx) error
+	CreateEpisodePlayback(ctx *fiber.Ctx) error
+	// VerifyPlaybackToken проверяет подпись и срок токена; привязку к тайтлу и адресу проверяет вызывающий
+	VerifyPlaybackToken(token string) (*auth.PlaybackClaims, error)
+}
+
+type playbackResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	StreamURL string    `json:"stream_url"`
+	HLSURL    string    `json:"hls_url,omitempty"`
+	DASHURL   string    `json:"dash_url,omitempty"`
+}
+
+func (s *service) CreateMoviePlayback(ctx *fiber.Ctx) error {
+	return s.createPlayback(ctx, repo.TitleMovie)
+}
+func (s *service) CreateEpisodePlayback(ctx *fiber.Ctx) error {
+	return s.createPlayback(ctx, repo.TitleEpisode)
+}
+
+func (s *service) VerifyPlaybackToken(token string) (*auth.PlaybackClaims, error) {
+	return s.playback.Verify(token)
+}
+
+func (s *service) createPlayback(ctx *fiber.Ctx, kind string) error {
+	var req CreatePlaybackRequest
+	if len(ctx.Body()) > 0 {
+		if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+			s.log.Error("Invalid request body", zap.Error(err))
+			return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+		}
+	}
+	req.TitleID = ctx.Params("id")
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// Анонимное чтение при PUBLIC_READ ссылок не выдает: иначе их мог бы получить кто угодно
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return s.denied(ctx, errForbidden)
+	}
+
+	if _, err := s.titleFor(ctx, kind, req.TitleID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	claims := auth.PlaybackClaims{Kind: kind, TitleID: req.TitleID, Subject: identity.Actor()}
+	if req.BindIP {
+		claims.IP = ctx.IP()
+	}
+
+	token, err := s.playback.Sign(&claims)
+	if err != nil {
+		s.log.Error("Failed to sign playback token", zap.Error(err))
+		return err
+	}
+
+	base := "/v1/play/" + token + "/" + kind + "s/" + req.TitleID
+	data := playbackResponse{
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt,
+		StreamURL: base + "/stream",
+	}
+	if s.packager != nil {
+		data.HLSURL = base + "/hls/" + hls.MasterPlaylist
+		data.DASHURL = base + "/dash/" + dash.ManifestName
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   data,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type Permission string
+
+const (
+	PermMoviesWrite   Permission = "movies:write"
+	PermOwnersWrite   Permission = "owners:write"
+	PermOwnersDelete  Permission = "owners:delete"
+	PermMembersManage Permission = "members:manage"
+	PermAPIKeysManage Permission = "api-keys:manage"
+)
+
+// rolePermissions - права, которые роль участника дает в рамках своего владельца.
+// RoleAdmin не нуждается в членстве и проходит любую проверку.
+var rolePermissions = map[auth.Role]map[Permission]bool{
+	auth.RoleOwnerMember: {
+		PermMoviesWrite:   true,
+		PermOwnersWrite:   true,
+		PermOwnersDelete:  true,
+		PermMembersManage: true,
+		PermAPIKeysManage: true,
+	},
+	auth.RoleEditor: {
+		PermMoviesWrite: true,
+	},
+	auth.RoleViewer: {},
+}
+
+// permissionScopes - scope API-ключа, необходимый для права. Права без scope ключам недоступны.
+var permissionScopes = map[Permission]string{
+	PermMoviesWrite: auth.ScopeMoviesWrite,
+}
+
+var errForbidden = errors.New("forbidden")
+
+// authorize проверяет, что вызывающая сторона имеет право perm в рамках владельца ownerID.
+func (s *service) authorize(ctx *fiber.Ctx, ownerID string, perm Permission) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return errForbidden
+	}
+	if identity.IsAdmin() {
+		return nil
+	}
+
+	if identity.APIKeyID != "" {
+		scope, ok := permissionScopes[perm]
+		if !ok || identity.OwnerID != ownerID || !identity.HasScope(scope) {
+			return errForbidden
+		}
+		return nil
+	}
+
+	member, err := s.memberRepo.GetOwnerMember(ctx.UserContext(), ownerID, identity.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return errForbidden
+		}
+		return errors.Wrap(err, "failed to query owner membership")
+	}
+
+	if !rolePermissions[auth.Role(member.Role)][perm] {
+		return errForbidden
+	}
+
+	return nil
+}
+
+// requireScope ограничивает запросы по API-ключу выданными ему scope.
+func (s *service) requireScope(ctx *fiber.Ctx, scope string) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.APIKeyID == "" || identity.HasScope(scope) {
+		return nil
+	}
+	return errForbidden
+}
+
+func (s *service) requireAdmin(ctx *fiber.Ctx) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || !identity.IsAdmin() {
+		return errForbidden
+	}
+	return nil
+}
+
+// denied формирует ответ на ошибку проверки прав.
+func (s *service) denied(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, errForbidden) {
+		return dto.ForbiddenError(ctx, "Not enough permissions to perform this action")
+	}
+
+	s.log.Error("Failed to check permissions", zap.Error(err))
+	return err
+}
+
+// grantCreator делает создателя владельца его участником с ролью owner-member.
+// members - репозиторий транзакции, в которой создается владелец.
+func (s *service) grantCreator(ctx *fiber.Ctx, members repo.MemberRepository, ownerID string) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.UserID == "" {
+		return nil
+	}
+
+	return members.AddOwnerMember(ctx.UserContext(), &repo.OwnerMember{
+		OwnerID: ownerID,
+		UserID:  identity.UserID,
+		Role:    string(auth.RoleOwnerMember),
+	})
+}
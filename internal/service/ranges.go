@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxRanges ограничивает число диапазонов в одном запросе: иначе мелкие диапазоны
+// позволяют заставить сервер отдать файл многократно
+const maxRanges = 16
+
+// errRangeNotSatisfiable - ни один из запрошенных диапазонов не пересекается с файлом.
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange - length байт файла начиная с start.
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+func (r byteRange) partHeader(contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":  {contentType},
+		"Content-Range": {r.contentRange(size)},
+	}
+}
+
+// parseRange разбирает заголовок Range (RFC 9110, 14.2) для файла размером size.
+// Некорректный заголовок игнорируется (nil, nil) - тогда отдается весь файл, как и разрешает RFC.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	specs, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok {
+		return nil, nil
+	}
+
+	var ranges []byteRange
+	var total int64
+	parsed := false
+
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		first, last, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, nil
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+		parsed = true
+
+		var r byteRange
+		if first == "" {
+			// Суффикс "-N" - последние N байт
+			suffix, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || suffix < 0 {
+				return nil, nil
+			}
+			if suffix == 0 || size == 0 {
+				continue
+			}
+			r.start = max(size-suffix, 0)
+			r.length = size - r.start
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, nil
+				}
+				end = min(end, size-1)
+			}
+
+			if start >= size {
+				continue
+			}
+			r.start = start
+			r.length = end - start + 1
+		}
+
+		ranges = append(ranges, r)
+		total += r.length
+	}
+
+	if !parsed {
+		return nil, nil
+	}
+	if len(ranges) == 0 {
+		return nil, errRangeNotSatisfiable
+	}
+	if len(ranges) > maxRanges || total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches проверяет If-Range: диапазоны отдаются, только если файл не изменился
+// с тех пор, как клиент получил его ETag или дату изменения.
+func ifRangeMatches(header, tag string, modified time.Time) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+
+	// If-Range допускает только строгое сравнение тегов
+	if strings.HasPrefix(header, `"`) || strings.HasPrefix(header, "W/") {
+		return matchesETag(header, tag, false)
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+	return date.Equal(modified.UTC().Truncate(time.Second))
+}
+
+// multipartLength заранее считает размер ответа multipart/byteranges, чтобы отдать Content-Length.
+func multipartLength(ranges []byteRange, boundary, contentType string, size int64) int64 {
+	var counter countingWriter
+
+	writer := multipart.NewWriter(&counter)
+	_ = writer.SetBoundary(boundary)
+	for _, r := range ranges {
+		_, _ = writer.CreatePart(r.partHeader(contentType, size))
+		counter += countingWriter(r.length)
+	}
+	_ = writer.Close()
+
+	return int64(counter)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
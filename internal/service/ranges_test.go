@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		size   int64
+		want   []byteRange
+		err    error
+	}{
+		{name: "first bytes", header: "bytes=0-9", size: 100, want: []byteRange{{0, 10}}},
+		{name: "open end", header: "bytes=90-", size: 100, want: []byteRange{{90, 10}}},
+		{name: "end clipped to size", header: "bytes=95-200", size: 100, want: []byteRange{{95, 5}}},
+		{name: "suffix", header: "bytes=-10", size: 100, want: []byteRange{{90, 10}}},
+		{name: "suffix longer than file", header: "bytes=-200", size: 100, want: []byteRange{{0, 100}}},
+		{name: "spaces", header: " bytes= 0 - 9 , 20-29", size: 100, want: []byteRange{{0, 10}, {20, 10}}},
+		{name: "several", header: "bytes=0-9,-10", size: 100, want: []byteRange{{0, 10}, {90, 10}}},
+		{name: "adjacent up to size", header: "bytes=0-49,50-99", size: 100, want: []byteRange{{0, 50}, {50, 50}}},
+		{name: "unsatisfiable part is skipped", header: "bytes=100-,0-9", size: 100, want: []byteRange{{0, 10}}},
+
+		// Перекрывающиеся диапазоны дороже самого файла - отдается файл целиком
+		{name: "duplicate whole file", header: "bytes=0-,0-", size: 100},
+		{name: "overlap over size", header: "bytes=0-59,40-99", size: 100},
+		{name: "too many ranges", header: "bytes=" + strings.Repeat("0-0,", maxRanges) + "1-1", size: 100},
+
+		{name: "start at size", header: "bytes=100-", size: 100, err: errRangeNotSatisfiable},
+		{name: "all past end", header: "bytes=100-200,300-", size: 100, err: errRangeNotSatisfiable},
+		{name: "empty suffix", header: "bytes=-0", size: 100, err: errRangeNotSatisfiable},
+		{name: "suffix of empty file", header: "bytes=-5", size: 0, err: errRangeNotSatisfiable},
+
+		// Некорректный заголовок игнорируется
+		{name: "other unit", header: "items=0-9", size: 100},
+		{name: "no dash", header: "bytes=10", size: 100},
+		{name: "reversed", header: "bytes=9-5", size: 100},
+		{name: "not a number", header: "bytes=a-b", size: 100},
+		{name: "negative suffix", header: "bytes=--5", size: 100},
+		{name: "no ranges", header: "bytes= , ", size: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRange(tt.header, tt.size)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 678_000_000, time.UTC)
+	tag := `"3f2a"`
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: true},
+		{header: `"3f2a"`, want: true},
+		{header: ` "3f2a" `, want: true},
+		// If-Range требует строгого сравнения
+		{header: `W/"3f2a"`, want: false},
+		{header: `"other"`, want: false},
+		// Дата в заголовке - с точностью до секунды
+		{header: "Tue, 02 Jan 2024 03:04:05 GMT", want: true},
+		{header: "Tue, 02 Jan 2024 03:04:04 GMT", want: false},
+		{header: "Tue, 02 Jan 2024 03:04:06 GMT", want: false},
+		{header: "yesterday", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ifRangeMatches(tt.header, tag, modified); got != tt.want {
+			t.Errorf("ifRangeMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+
+	// Дата изменения в другом часовом поясе сравнивается как тот же момент
+	if !ifRangeMatches("Tue, 02 Jan 2024 03:04:05 GMT", tag, modified.In(time.FixedZone("MSK", 3*60*60))) {
+		t.Error("If-Range date does not match the same instant in another zone")
+	}
+}
+
+func TestMultipartLength(t *testing.T) {
+	const boundary = "3d6b6a416f9b5"
+
+	tests := [][]byteRange{
+		{{0, 1}, {5, 5}},
+		{{0, 10}, {90, 10}, {40, 1}},
+		{{0, 99999}, {99999, 1}},
+	}
+
+	for _, ranges := range tests {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if err := writer.SetBoundary(boundary); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range ranges {
+			part, err := writer.CreatePart(r.partHeader("video/mp4", 100000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write(bytes.Repeat([]byte{'x'}, int(r.length)))
+		}
+		writer.Close()
+
+		if got := multipartLength(ranges, boundary, "video/mp4", 100000); got != int64(body.Len()) {
+			t.Errorf("multipartLength(%v) = %d, body is %d bytes", ranges, got, body.Len())
+		}
+	}
+}
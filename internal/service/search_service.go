@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+)
+
+const (
+	defaultSearchLimit  = 20
+	defaultSuggestLimit = 10
+)
+
+type SearchService interface {
+	Search(ctx *fiber.Ctx) error
+	Suggest(ctx *fiber.Ctx) error
+}
+
+func (s *service) Search(ctx *fiber.Ctx) error {
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var query SearchQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+	query.Q = strings.TrimSpace(query.Q)
+
+	if errs := validateRequest(&query); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultSearchLimit
+	}
+
+	results, err := s.searchRepo.SearchMovies(ctx.UserContext(), query.Q, query.Limit)
+	if err != nil {
+		s.log.Error("Failed to search movies", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   results,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) Suggest(ctx *fiber.Ctx) error {
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var query SuggestQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+	query.Prefix = strings.TrimSpace(query.Prefix)
+
+	if errs := validateRequest(&query); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultSuggestLimit
+	}
+
+	// Поиск нечувствителен к регистру, поэтому и ключ кэша тоже
+	key := fmt.Sprintf("%d:%s", query.Limit, strings.ToLower(query.Prefix))
+	// Поколение запоминается до запроса: если Purge пройдет, пока идет запрос, устаревший ответ не закэшируется
+	generation := s.suggestions.Generation()
+	suggestions, ok := s.suggestions.Get(key)
+	if !ok {
+		var err error
+		suggestions, err = s.suggestRepo.Suggest(ctx.UserContext(), query.Prefix, query.Limit)
+		if err != nil {
+			s.log.Error("Failed to get suggestions", zap.Error(err))
+			return err
+		}
+		s.suggestions.AddIfGeneration(key, suggestions, generation)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   suggestions,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,585 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type SeriesService interface {
+	CreateSeries(ctx *fiber.Ctx) error
+	GetAllSeries(ctx *fiber.Ctx) error
+	GetSeries(ctx *fiber.Ctx) error
+	UpdateSeries(ctx *fiber.Ctx) error
+	DeleteSeries(ctx *fiber.Ctx) error
+
+	CreateSeason(ctx *fiber.Ctx) error
+	GetSeasons(ctx *fiber.Ctx) error
+	GetSeason(ctx *fiber.Ctx) error
+	UpdateSeason(ctx *fiber.Ctx) error
+	DeleteSeason(ctx *fiber.Ctx) error
+
+	CreateEpisode(ctx *fiber.Ctx) error
+	GetEpisodes(ctx *fiber.Ctx) error
+	GetEpisode(ctx *fiber.Ctx) error
+	GetEpisodeByID(ctx *fiber.Ctx) error
+	UpdateEpisode(ctx *fiber.Ctx) error
+	DeleteEpisode(ctx *fiber.Ctx) error
+}
+
+func (s *service) CreateSeries(ctx *fiber.Ctx) error {
+	var req CreateSeriesRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	// API-ключ уже однозначно задает владельца
+	if identity, ok := auth.FromContext(ctx); ok && identity.APIKeyID != "" {
+		req.OwnerID = identity.OwnerID
+	} else if req.OwnerID == "" {
+		return dto.BadRequestError(ctx, dto.FieldRequired, "Field 'owner_id' is required")
+	}
+
+	if _, err := s.ownerRepo.GetOwnerByID(ctx.UserContext(), req.OwnerID); err != nil {
+		s.log.Error("Failed to get owner", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, req.OwnerID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	series, err := s.seriesRepo.CreateSeries(ctx.UserContext(), &repo.Series{
+		OwnerID:     req.OwnerID,
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		s.log.Error("Failed to create series", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   series,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetAllSeries(ctx *fiber.Ctx) error {
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var query ListSeriesQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+
+	errs := validateRequest(&query)
+	page, pageErrs := parsePageRequest(ctx)
+	errs = append(errs, pageErrs...)
+	sorts, sortErrs := parseSort(repo.SeriesSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	spec := repo.ListSpec{Sorts: sorts}
+	if query.OwnerID != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "owner_id", Op: repo.OpEq, Value: query.OwnerID})
+	}
+
+	series, err := s.seriesRepo.GetAllSeries(ctx.UserContext(), spec, page)
+	if err != nil {
+		s.log.Error("Failed to get series", zap.Error(err))
+		return err
+	}
+
+	return sendPage(ctx, series)
+}
+
+func (s *service) GetSeries(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	series, err := s.seriesFor(ctx, path.SeriesID, "")
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   series,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) UpdateSeries(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	var req UpdateSeriesRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	series, err := s.seriesRepo.UpdateSeries(ctx.UserContext(), path.SeriesID, &repo.Series{
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		s.log.Error("Failed to update series", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   series,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) DeleteSeries(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	assets, err := s.seriesRepo.DeleteSeries(ctx.UserContext(), path.SeriesID)
+	if err != nil {
+		s.log.Error("Failed to delete series", zap.Error(err))
+		return err
+	}
+	for _, asset := range assets {
+		s.removeAssetFiles(ctx.UserContext(), asset)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   path.SeriesID,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) CreateSeason(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	var req SeasonRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	season, err := s.seriesRepo.CreateSeason(ctx.UserContext(), &repo.Season{
+		SeriesID: path.SeriesID,
+		Number:   req.Number,
+		Title:    req.Title,
+		AirDate:  parseDate(req.AirDate),
+	})
+	if err != nil {
+		s.log.Error("Failed to create season", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   season,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetSeasons(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	seasons, err := s.seriesRepo.GetSeasons(ctx.UserContext(), path.SeriesID)
+	if err != nil {
+		s.log.Error("Failed to get seasons", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   seasons,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) GetSeason(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	season, err := s.seriesRepo.GetSeason(ctx.UserContext(), path.SeriesID, path.Season)
+	if err != nil {
+		s.log.Error("Failed to get season", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   season,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) UpdateSeason(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	var req SeasonRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	season, err := s.seriesRepo.UpdateSeason(ctx.UserContext(), path.SeriesID, path.Season, &repo.Season{
+		Number:  req.Number,
+		Title:   req.Title,
+		AirDate: parseDate(req.AirDate),
+	})
+	if err != nil {
+		s.log.Error("Failed to update season", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   season,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) DeleteSeason(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	assets, err := s.seriesRepo.DeleteSeason(ctx.UserContext(), path.SeriesID, path.Season)
+	if err != nil {
+		s.log.Error("Failed to delete season", zap.Error(err))
+		return err
+	}
+	for _, asset := range assets {
+		s.removeAssetFiles(ctx.UserContext(), asset)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   path.Season,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) CreateEpisode(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	var req EpisodeRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	episode, err := s.seriesRepo.CreateEpisode(ctx.UserContext(), path.SeriesID, path.Season, episodeFromRequest(&req))
+	if err != nil {
+		s.log.Error("Failed to create episode", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   episode,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) GetEpisodes(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	episodes, err := s.seriesRepo.GetEpisodes(ctx.UserContext(), path.SeriesID, path.Season)
+	if err != nil {
+		s.log.Error("Failed to get episodes", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   episodes,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) GetEpisode(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, ""); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	episode, err := s.seriesRepo.GetEpisode(ctx.UserContext(), path.SeriesID, path.Season, path.Episode)
+	if err != nil {
+		s.log.Error("Failed to get episode", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   episode,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetEpisodeByID отдает эпизод по uuid - так на него ссылаются плееры и общий каталог титулов.
+func (s *service) GetEpisodeByID(ctx *fiber.Ctx) error {
+	req := GetEpisodeRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	episode, err := s.seriesRepo.GetEpisodeByID(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get episode", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   episode,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) UpdateEpisode(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	var req EpisodeRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	episode, err := s.seriesRepo.UpdateEpisode(ctx.UserContext(), path.SeriesID, path.Season, path.Episode, episodeFromRequest(&req))
+	if err != nil {
+		s.log.Error("Failed to update episode", zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   episode,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) DeleteEpisode(ctx *fiber.Ctx) error {
+	path, errs := seriesPath(ctx)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.seriesFor(ctx, path.SeriesID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	assets, err := s.seriesRepo.DeleteEpisode(ctx.UserContext(), path.SeriesID, path.Season, path.Episode)
+	if err != nil {
+		s.log.Error("Failed to delete episode", zap.Error(err))
+		return err
+	}
+	for _, asset := range assets {
+		s.removeAssetFiles(ctx.UserContext(), asset)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   path.Episode,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// seriesFor загружает сериал и проверяет право perm в рамках его владельца.
+// Пустое perm - только чтение, для него проверяется scope movies:read.
+// Ошибку нужно передать в denied: запрет превращается в 403, остальное уходит в обработчик ошибок.
+func (s *service) seriesFor(ctx *fiber.Ctx, seriesID string, perm Permission) (*repo.Series, error) {
+	if perm == "" {
+		if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+			return nil, err
+		}
+	}
+
+	series, err := s.seriesRepo.GetSeriesByID(ctx.UserContext(), seriesID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get series")
+	}
+
+	if perm != "" {
+		if err := s.authorize(ctx, series.OwnerID, perm); err != nil {
+			return nil, err
+		}
+	}
+
+	return series, nil
+}
+
+// seriesPath разбирает параметры вложенных маршрутов: :id сериала, :n сезона и :e эпизода.
+// Номера, которых нет в маршруте, остаются нулевыми.
+func seriesPath(ctx *fiber.Ctx) (SeriesPathRequest, []dto.FieldError) {
+	path := SeriesPathRequest{SeriesID: ctx.Params("id")}
+
+	errs := validateRequest(&path)
+	if err := pathNumber(ctx, "n", "season", &path.Season); err != nil {
+		errs = append(errs, *err)
+	}
+	if err := pathNumber(ctx, "e", "episode", &path.Episode); err != nil {
+		errs = append(errs, *err)
+	}
+
+	return path, errs
+}
+
+func pathNumber(ctx *fiber.Ctx, param, field string, target *int) *dto.FieldError {
+	value := ctx.Params(param)
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return &dto.FieldError{
+			Field:   field,
+			Code:    dto.FieldBadFormat,
+			Message: fmt.Sprintf("Field '%s' must be a positive integer", field),
+		}
+	}
+
+	*target = number
+	return nil
+}
+
+func episodeFromRequest(req *EpisodeRequest) *repo.Episode {
+	episode := &repo.Episode{
+		Number:      req.Number,
+		Title:       req.Title,
+		Description: req.Description,
+		AirDate:     parseDate(req.AirDate),
+	}
+	if req.RuntimeMinutes > 0 {
+		episode.RuntimeMinutes = &req.RuntimeMinutes
+	}
+	return episode
+}
+
+// parseDate разбирает дату выхода; пустая строка - дата неизвестна. Формат уже проверен тегом datetime.
+func parseDate(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, _ := time.Parse(time.DateOnly, value)
+	return &date
+}
@@ -2,24 +2,117 @@ package service
 
 import (
 	"go.uber.org/zap"
+	"streaming-service/internal/auth"
+	"streaming-service/internal/cache"
+	"streaming-service/internal/hls"
 	"streaming-service/internal/repo"
+	"streaming-service/internal/storage"
+	"streaming-service/internal/upload"
 )
 
 type service struct {
-	movieRepo repo.MovieRepository
-	ownerRepo repo.OwnerRepository
-	log       *zap.SugaredLogger
+	txRepo       repo.Transactor
+	movieRepo    repo.MovieRepository
+	ownerRepo    repo.OwnerRepository
+	userRepo     repo.UserRepository
+	memberRepo   repo.MemberRepository
+	apiKeyRepo   repo.APIKeyRepository
+	searchRepo   repo.SearchRepository
+	suggestRepo  repo.SuggestRepository
+	trashRepo    repo.TrashRepository
+	auditRepo    repo.AuditRepository
+	taxonomyRepo repo.TaxonomyRepository
+	peopleRepo   repo.PeopleRepository
+	seriesRepo   repo.SeriesRepository
+	titleRepo    repo.TitleRepository
+	assetRepo    repo.MediaAssetRepository
+	jobRepo      repo.JobRepository
+	// suggestions - подсказки по префиксу; сбрасывается при изменении фильмов и владельцев
+	suggestions *cache.LRU[string, []*repo.Suggestion]
+	// uploads - незавершенные tus-загрузки на локальном диске
+	uploads *upload.Disk
+	// blobs - хранилище готовых медиафайлов
+	blobs storage.BlobStore
+	// packager нарезает готовые файлы для HLS и DASH; nil - упаковка выключена
+	packager *hls.Packager
+	// jobAttempts - число попыток для новых фоновых задач
+	jobAttempts int
+	tokens      *auth.TokenManager
+	// playback подписывает ссылки воспроизведения
+	playback *auth.PlaybackSigner
+	log      *zap.SugaredLogger
 }
 
 type Service interface {
 	MovieService
 	OwnerService
+	AuthService
+	MemberService
+	APIKeyService
+	SearchService
+	TrashService
+	AuditService
+	TaxonomyService
+	PeopleService
+	SeriesService
+	TitleService
+	AssetService
+	StreamService
+	HLSService
+	JobService
+	PlaybackService
 }
 
-func NewService(movieRepo repo.MovieRepository, ownerRepo repo.OwnerRepository, logger *zap.SugaredLogger) Service {
+func NewService(
+	txRepo repo.Transactor,
+	movieRepo repo.MovieRepository,
+	ownerRepo repo.OwnerRepository,
+	userRepo repo.UserRepository,
+	memberRepo repo.MemberRepository,
+	apiKeyRepo repo.APIKeyRepository,
+	searchRepo repo.SearchRepository,
+	suggestRepo repo.SuggestRepository,
+	trashRepo repo.TrashRepository,
+	auditRepo repo.AuditRepository,
+	taxonomyRepo repo.TaxonomyRepository,
+	peopleRepo repo.PeopleRepository,
+	seriesRepo repo.SeriesRepository,
+	titleRepo repo.TitleRepository,
+	assetRepo repo.MediaAssetRepository,
+	jobRepo repo.JobRepository,
+	suggestions *cache.LRU[string, []*repo.Suggestion],
+	uploads *upload.Disk,
+	blobs storage.BlobStore,
+	packager *hls.Packager,
+	jobAttempts int,
+	tokens *auth.TokenManager,
+	playback *auth.PlaybackSigner,
+	logger *zap.SugaredLogger,
+) Service {
 	return &service{
-		movieRepo: movieRepo,
-		ownerRepo: ownerRepo,
-		log:       logger,
+		txRepo:       txRepo,
+		movieRepo:    movieRepo,
+		ownerRepo:    ownerRepo,
+		userRepo:     userRepo,
+		memberRepo:   memberRepo,
+		apiKeyRepo:   apiKeyRepo,
+		searchRepo:   searchRepo,
+		suggestRepo:  suggestRepo,
+		trashRepo:    trashRepo,
+		auditRepo:    auditRepo,
+		taxonomyRepo: taxonomyRepo,
+		peopleRepo:   peopleRepo,
+		seriesRepo:   seriesRepo,
+		titleRepo:    titleRepo,
+		assetRepo:    assetRepo,
+		jobRepo:      jobRepo,
+		suggestions:  suggestions,
+		uploads:      uploads,
+		blobs:        blobs,
+		packager:     packager,
+		jobAttempts:  jobAttempts,
+		tokens:       tokens,
+		playback:     playback,
+		log:          logger,
 	}
 }
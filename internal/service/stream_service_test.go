@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/repo"
+	"streaming-service/internal/storage"
+)
+
+type fakeTitles struct {
+	repo.TitleRepository
+}
+
+func (fakeTitles) GetTitle(_ context.Context, kind, uuid string) (*repo.Title, error) {
+	return &repo.Title{Kind: kind, UUID: uuid}, nil
+}
+
+type fakeAssets struct {
+	repo.MediaAssetRepository
+	asset *repo.MediaAsset
+}
+
+func (f fakeAssets) GetPrimaryAsset(context.Context, string, string) (*repo.MediaAsset, error) {
+	return f.asset, nil
+}
+
+const streamAssetID = "6f1c2b8e-3d4a-4f5b-9c6d-7e8f9a0b1c2d"
+
+// newStreamApp отдает файл content как основной медиафайл любого фильма.
+func newStreamApp(t *testing.T, content []byte) (*fiber.App, *repo.MediaAsset) {
+	t.Helper()
+
+	blobs, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := &repo.MediaAsset{
+		UUID:       streamAssetID,
+		MimeType:   "video/mp4",
+		Size:       int64(len(content)),
+		Status:     repo.AssetReady,
+		StorageKey: assetStorageKey(streamAssetID),
+	}
+	completed := time.Date(2024, 1, 2, 3, 4, 5, 678_000_000, time.UTC)
+	asset.CompletedAt = &completed
+	if err := blobs.Put(context.Background(), asset.StorageKey, bytes.NewReader(content), asset.Size, asset.MimeType); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &service{titleRepo: fakeTitles{}, assetRepo: fakeAssets{asset: asset}, blobs: blobs, log: zap.NewNop().Sugar()}
+	app := fiber.New()
+	app.Get("/movies/:id/stream", s.StreamMovie)
+	return app, asset
+}
+
+func streamRequest(t *testing.T, app *fiber.App, method string, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/movies/0b7e4c1a-2f3d-4e5a-8b9c-0d1e2f3a4b5c/stream", nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func TestStream(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ+/")
+	app, _ := newStreamApp(t, content)
+	size := strconv.Itoa(len(content))
+
+	tests := []struct {
+		name         string
+		header       map[string]string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{name: "whole file", status: http.StatusOK, body: string(content)},
+		{name: "range", header: map[string]string{"Range": "bytes=10-19"}, status: http.StatusPartialContent,
+			contentRange: "bytes 10-19/" + size, body: "abcdefghij"},
+		{name: "suffix", header: map[string]string{"Range": "bytes=-2"}, status: http.StatusPartialContent,
+			contentRange: "bytes 62-63/" + size, body: "+/"},
+		{name: "open end", header: map[string]string{"Range": "bytes=60-"}, status: http.StatusPartialContent,
+			contentRange: "bytes 60-63/" + size, body: "YZ+/"},
+		{name: "unsatisfiable", header: map[string]string{"Range": "bytes=64-"}, status: http.StatusRequestedRangeNotSatisfiable,
+			contentRange: "bytes */" + size},
+		{name: "overlap over size", header: map[string]string{"Range": "bytes=0-,0-"}, status: http.StatusOK, body: string(content)},
+		{name: "malformed range", header: map[string]string{"Range": "bytes=x-y"}, status: http.StatusOK, body: string(content)},
+		{name: "if-range strong tag", header: map[string]string{"Range": "bytes=0-0", "If-Range": `"` + streamAssetID + `"`},
+			status: http.StatusPartialContent, contentRange: "bytes 0-0/" + size, body: "0"},
+		{name: "if-range weak tag", header: map[string]string{"Range": "bytes=0-0", "If-Range": `W/"` + streamAssetID + `"`},
+			status: http.StatusOK, body: string(content)},
+		{name: "if-range stale tag", header: map[string]string{"Range": "bytes=0-0", "If-Range": `"stale"`},
+			status: http.StatusOK, body: string(content)},
+		{name: "if-range date", header: map[string]string{"Range": "bytes=1-1", "If-Range": "Tue, 02 Jan 2024 03:04:05 GMT"},
+			status: http.StatusPartialContent, contentRange: "bytes 1-1/" + size, body: "1"},
+		{name: "if-range old date", header: map[string]string{"Range": "bytes=1-1", "If-Range": "Mon, 01 Jan 2024 00:00:00 GMT"},
+			status: http.StatusOK, body: string(content)},
+		{name: "if-none-match", header: map[string]string{"If-None-Match": `W/"` + streamAssetID + `"`}, status: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := streamRequest(t, app, http.MethodGet, tt.header)
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+			if tt.status == http.StatusRequestedRangeNotSatisfiable || tt.status == http.StatusNotModified {
+				return
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if resp.Header.Get("Accept-Ranges") != "bytes" || resp.Header.Get("ETag") != `"`+streamAssetID+`"` {
+				t.Errorf("headers = %v", resp.Header)
+			}
+		})
+	}
+}
+
+func TestStreamMultipleRanges(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	app, asset := newStreamApp(t, content)
+	ranges := []byteRange{{0, 5}, {9990, 10}, {4000, 3000}}
+	header := map[string]string{"Range": "bytes=0-4,-10,4000-6999"}
+
+	resp, body := streamRequest(t, app, http.MethodGet, header)
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", resp.StatusCode)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	// Тело совпадает байт в байт с тем, что собрал бы multipart.Writer, и с заранее объявленной длиной
+	var want bytes.Buffer
+	writer := multipart.NewWriter(&want)
+	if err := writer.SetBoundary(params["boundary"]); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ranges {
+		part, _ := writer.CreatePart(r.partHeader(asset.MimeType, asset.Size))
+		part.Write(content[r.start : r.start+r.length])
+	}
+	writer.Close()
+
+	if !bytes.Equal(body, want.Bytes()) {
+		t.Errorf("multipart body differs from the expected one:\n%q\nwant\n%q", body[:min(len(body), 300)], want.Bytes()[:300])
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Errorf("Content-Length = %d, body is %d bytes", resp.ContentLength, len(body))
+	}
+	if got := multipartLength(ranges, params["boundary"], asset.MimeType, asset.Size); got != int64(len(body)) {
+		t.Errorf("multipartLength = %d, body is %d bytes", got, len(body))
+	}
+
+	// HEAD объявляет ту же длину, не читая файл
+	head, _ := streamRequest(t, app, http.MethodHead, header)
+	if head.StatusCode != http.StatusPartialContent || head.ContentLength != int64(len(body)) {
+		t.Errorf("HEAD = %d with Content-Length %d, want 206 with %d", head.StatusCode, head.ContentLength, len(body))
+	}
+}
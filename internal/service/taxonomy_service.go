@@ -0,0 +1,197 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type TaxonomyService interface {
+	CreateGenre(ctx *fiber.Ctx) error
+	GetGenres(ctx *fiber.Ctx) error
+	GetGenre(ctx *fiber.Ctx) error
+	UpdateGenre(ctx *fiber.Ctx) error
+	DeleteGenre(ctx *fiber.Ctx) error
+
+	CreateTag(ctx *fiber.Ctx) error
+	GetTags(ctx *fiber.Ctx) error
+	GetTag(ctx *fiber.Ctx) error
+	UpdateTag(ctx *fiber.Ctx) error
+	DeleteTag(ctx *fiber.Ctx) error
+}
+
+func (s *service) CreateGenre(ctx *fiber.Ctx) error { return s.createTerm(ctx, repo.Genres) }
+func (s *service) GetGenres(ctx *fiber.Ctx) error   { return s.getTerms(ctx, repo.Genres) }
+func (s *service) GetGenre(ctx *fiber.Ctx) error    { return s.getTerm(ctx, repo.Genres) }
+func (s *service) UpdateGenre(ctx *fiber.Ctx) error { return s.updateTerm(ctx, repo.Genres) }
+func (s *service) DeleteGenre(ctx *fiber.Ctx) error { return s.deleteTerm(ctx, repo.Genres) }
+
+func (s *service) CreateTag(ctx *fiber.Ctx) error { return s.createTerm(ctx, repo.Tags) }
+func (s *service) GetTags(ctx *fiber.Ctx) error   { return s.getTerms(ctx, repo.Tags) }
+func (s *service) GetTag(ctx *fiber.Ctx) error    { return s.getTerm(ctx, repo.Tags) }
+func (s *service) UpdateTag(ctx *fiber.Ctx) error { return s.updateTerm(ctx, repo.Tags) }
+func (s *service) DeleteTag(ctx *fiber.Ctx) error { return s.deleteTerm(ctx, repo.Tags) }
+
+// Словари общие для всего каталога, поэтому менять их может только администратор.
+func (s *service) createTerm(ctx *fiber.Ctx, taxonomy repo.Taxonomy) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var req TermRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	term, err := s.taxonomyRepo.CreateTerm(ctx.UserContext(), taxonomy, &repo.Term{Slug: req.Slug, Name: req.Name})
+	if err != nil {
+		s.log.Error("Failed to create "+taxonomy.Entity, zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   term,
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(response)
+}
+
+func (s *service) getTerms(ctx *fiber.Ctx, taxonomy repo.Taxonomy) error {
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	terms, err := s.taxonomyRepo.GetTerms(ctx.UserContext(), taxonomy)
+	if err != nil {
+		s.log.Error("Failed to get "+taxonomy.Table, zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   terms,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) getTerm(ctx *fiber.Ctx, taxonomy repo.Taxonomy) error {
+	req := GetTermRequest{Slug: ctx.Params("slug")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	term, err := s.taxonomyRepo.GetTerm(ctx.UserContext(), taxonomy, req.Slug)
+	if err != nil {
+		s.log.Error("Failed to get "+taxonomy.Entity, zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   term,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) updateTerm(ctx *fiber.Ctx, taxonomy repo.Taxonomy) error {
+	current := GetTermRequest{Slug: ctx.Params("slug")}
+	if errs := validateRequest(&current); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	var req TermRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		s.log.Error("Invalid request body", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid request body")
+	}
+
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	term, err := s.taxonomyRepo.UpdateTerm(ctx.UserContext(), taxonomy, current.Slug, &repo.Term{Slug: req.Slug, Name: req.Name})
+	if err != nil {
+		s.log.Error("Failed to update "+taxonomy.Entity, zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   term,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) deleteTerm(ctx *fiber.Ctx, taxonomy repo.Taxonomy) error {
+	req := GetTermRequest{Slug: ctx.Params("slug")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if err := s.requireAdmin(ctx); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	if err := s.taxonomyRepo.DeleteTerm(ctx.UserContext(), taxonomy, req.Slug); err != nil {
+		s.log.Error("Failed to delete "+taxonomy.Entity, zap.Error(err))
+		return err
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   req.Slug,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// setMovieTerms заменяет жанры и теги фильма; nil означает "не менять".
+func setMovieTerms(ctx *fiber.Ctx, tx repo.Repositories, movieID string, genres, tags []string) error {
+	if genres != nil {
+		if err := tx.SetMovieTerms(ctx.UserContext(), repo.Genres, movieID, genres); err != nil {
+			return err
+		}
+	}
+	if tags != nil {
+		if err := tx.SetMovieTerms(ctx.UserContext(), repo.Tags, movieID, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// movieTerms загружает жанры и теги фильма для ответа.
+func (s *service) movieTerms(ctx *fiber.Ctx, movieID string) (genres, tags []*repo.Term, err error) {
+	if genres, err = s.taxonomyRepo.GetMovieTerms(ctx.UserContext(), repo.Genres, movieID); err != nil {
+		return nil, nil, err
+	}
+	if tags, err = s.taxonomyRepo.GetMovieTerms(ctx.UserContext(), repo.Tags, movieID); err != nil {
+		return nil, nil, err
+	}
+	return genres, tags, nil
+}
+
+func termSlugs(terms []*repo.Term) []string {
+	slugs := make([]string, 0, len(terms))
+	for _, term := range terms {
+		slugs = append(slugs, term.Slug)
+	}
+	return slugs
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type TitleService interface {
+	GetTitle(ctx *fiber.Ctx) error
+}
+
+// GetTitle определяет по uuid, фильм это или эпизод, и к какому владельцу он относится.
+func (s *service) GetTitle(ctx *fiber.Ctx) error {
+	req := GetTitleRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	title, err := s.titleFor(ctx, "", req.UUID, "")
+	if err != nil {
+		return s.denied(ctx, err)
+	}
+
+	response := dto.Response{
+		Status: "success",
+		Data:   title,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+// titleFor загружает фильм или эпизод (пустой kind - любой) и проверяет право perm в рамках его владельца.
+// Через него фильмы и эпизоды одинаково обслуживают воспроизведение и медиафайлы.
+// Пустое perm - только чтение; ошибку нужно передать в denied.
+func (s *service) titleFor(ctx *fiber.Ctx, kind, titleID string, perm Permission) (*repo.Title, error) {
+	if perm == "" {
+		if err := s.requireScope(ctx, auth.ScopeMoviesRead); err != nil {
+			return nil, err
+		}
+	}
+
+	title, err := s.titleRepo.GetTitle(ctx.UserContext(), kind, titleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get title")
+	}
+
+	if perm != "" {
+		if err := s.authorize(ctx, title.OwnerID, perm); err != nil {
+			return nil, err
+		}
+	}
+
+	return title, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/auth"
+	"streaming-service/internal/dto"
+	"streaming-service/internal/repo"
+)
+
+type TrashService interface {
+	GetTrash(ctx *fiber.Ctx) error
+	RestoreMovie(ctx *fiber.Ctx) error
+	RestoreOwner(ctx *fiber.Ctx) error
+	// RunTrashPurge раз в interval окончательно удаляет записи старше retention; блокируется до отмены ctx
+	RunTrashPurge(ctx context.Context, retention, interval time.Duration)
+}
+
+func (s *service) GetTrash(ctx *fiber.Ctx) error {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return s.denied(ctx, errForbidden)
+	}
+
+	var query ListTrashQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		s.log.Error("Invalid query parameters", zap.Error(err))
+		return dto.BadRequestError(ctx, dto.FieldBadFormat, "Invalid query parameters")
+	}
+
+	errs := validateRequest(&query)
+	page, pageErrs := parsePageRequest(ctx)
+	errs = append(errs, pageErrs...)
+	sorts, sortErrs := parseSort(repo.TrashSchema(), query.Sort)
+	errs = append(errs, sortErrs...)
+	if len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	spec := repo.ListSpec{Sorts: sorts}
+	if query.Type != "" {
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "type", Op: repo.OpEq, Value: query.Type})
+	}
+
+	// Администратор видит всю корзину, остальные - только корзину своих владельцев
+	switch {
+	case identity.IsAdmin():
+	case identity.APIKeyID != "":
+		spec.Filters = append(spec.Filters, repo.Filter{Field: "owner_id", Op: repo.OpEq, Value: identity.OwnerID})
+	default:
+		spec.Where = append(spec.Where, repo.MemberOwners(identity.UserID))
+	}
+
+	items, err := s.trashRepo.GetTrash(ctx.UserContext(), spec, page)
+	if err != nil {
+		s.log.Error("Failed to get trash", zap.Error(err))
+		return err
+	}
+
+	return sendPage(ctx, items)
+}
+
+func (s *service) RestoreMovie(ctx *fiber.Ctx) error {
+	req := GetMovieRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	movie, err := s.trashRepo.GetDeletedMovie(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to get deleted movie", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, movie.OwnerID, PermMoviesWrite); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	restored, err := s.trashRepo.RestoreMovie(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to restore movie", zap.Error(err))
+		return err
+	}
+	s.suggestions.Purge()
+
+	ctx.Set(fiber.HeaderETag, etag(restored.Version))
+
+	response := dto.Response{
+		Status: "success",
+		Data:   restored,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) RestoreOwner(ctx *fiber.Ctx) error {
+	req := GetOwnerByUUIDRequest{UUID: ctx.Params("id")}
+	if errs := validateRequest(&req); len(errs) > 0 {
+		return dto.ValidationError(ctx, errs)
+	}
+
+	if _, err := s.trashRepo.GetDeletedOwner(ctx.UserContext(), req.UUID); err != nil {
+		s.log.Error("Failed to get deleted owner", zap.Error(err))
+		return err
+	}
+
+	if err := s.authorize(ctx, req.UUID, PermOwnersDelete); err != nil {
+		return s.denied(ctx, err)
+	}
+
+	restored, err := s.trashRepo.RestoreOwner(ctx.UserContext(), req.UUID)
+	if err != nil {
+		s.log.Error("Failed to restore owner", zap.Error(err))
+		return err
+	}
+	s.suggestions.Purge()
+
+	ctx.Set(fiber.HeaderETag, etag(restored.Version))
+
+	response := dto.Response{
+		Status: "success",
+		Data:   restored,
+	}
+	return ctx.Status(fiber.StatusOK).JSON(response)
+}
+
+func (s *service) RunTrashPurge(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purge, err := s.trashRepo.PurgeTrash(ctx, retention)
+		if err != nil {
+			s.log.Error("Failed to purge trash", zap.Error(err))
+		} else {
+			for _, asset := range purge.Assets {
+				s.removeAssetFiles(ctx, asset)
+			}
+			if purge.Movies > 0 || purge.Owners > 0 {
+				s.log.Infof("Purged %d movies and %d owners from trash", purge.Movies, purge.Owners)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// Протокол возобновляемой загрузки tus 1.0.0 (https://tus.io/protocols/resumable-upload).
+// Поддерживаются расширения creation, checksum и termination.
+const (
+	tusVersion            = "1.0.0"
+	tusExtensions         = "creation,checksum,termination"
+	tusChecksumAlgorithms = "sha256"
+	tusContentType        = "application/offset+octet-stream"
+
+	headerTusResumable         = "Tus-Resumable"
+	headerTusVersion           = "Tus-Version"
+	headerTusExtension         = "Tus-Extension"
+	headerTusMaxSize           = "Tus-Max-Size"
+	headerTusChecksumAlgorithm = "Tus-Checksum-Algorithm"
+	headerUploadLength         = "Upload-Length"
+	headerUploadOffset         = "Upload-Offset"
+	headerUploadMetadata       = "Upload-Metadata"
+	headerUploadChecksum       = "Upload-Checksum"
+)
+
+// StatusChecksumMismatch - ответ tus на несовпадение контрольной суммы.
+const StatusChecksumMismatch = 460
+
+// checkTusResumable отклоняет запросы клиентов другой версии протокола.
+func checkTusResumable(ctx *fiber.Ctx) error {
+	ctx.Set(headerTusResumable, tusVersion)
+
+	if ctx.Get(headerTusResumable) != tusVersion {
+		ctx.Set(headerTusVersion, tusVersion)
+		return fiber.NewError(fiber.StatusPreconditionFailed, "Unsupported tus protocol version, expected "+tusVersion)
+	}
+	return nil
+}
+
+// parseUploadMetadata разбирает Upload-Metadata: пары "ключ base64(значение)" через запятую.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		if _, duplicate := metadata[key]; duplicate {
+			return nil, errors.Errorf("metadata key %q is listed twice", key)
+		}
+
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, errors.Wrapf(err, "metadata value of %q is not valid base64", key)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// parseUploadLength читает обязательный Upload-Length; отложенная длина не поддерживается.
+func parseUploadLength(ctx *fiber.Ctx) (int64, error) {
+	length, err := strconv.ParseInt(ctx.Get(headerUploadLength), 10, 64)
+	if err != nil || length <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Upload-Length must be a positive integer")
+	}
+	return length, nil
+}
+
+func parseUploadOffset(ctx *fiber.Ctx) (int64, error) {
+	offset, err := strconv.ParseInt(ctx.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Upload-Offset must be a non-negative integer")
+	}
+	return offset, nil
+}
+
+// verifyChunkChecksum проверяет Upload-Checksum фрагмента ("sha256 base64(digest)"), если он передан.
+func verifyChunkChecksum(ctx *fiber.Ctx, chunk []byte) error {
+	header := ctx.Get(headerUploadChecksum)
+	if header == "" {
+		return nil
+	}
+
+	algorithm, encoded, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if algorithm != tusChecksumAlgorithms {
+		return fiber.NewError(fiber.StatusBadRequest, "Unsupported checksum algorithm, expected "+tusChecksumAlgorithms)
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Upload-Checksum digest is not valid base64")
+	}
+
+	actual := sha256.Sum256(chunk)
+	if subtle.ConstantTimeCompare(expected, actual[:]) != 1 {
+		return fiber.NewError(StatusChecksumMismatch, "Checksum Mismatch")
+	}
+	return nil
+}
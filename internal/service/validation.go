@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"streaming-service/internal/dto"
+)
+
+var (
+	validate    = newValidator()
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// В ошибках используются имена полей из json-тегов, а не из Go-структур
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// slug - машинное имя термина словаря: строчные латинские буквы и цифры через дефис
+	_ = v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugPattern.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+// validateRequest проверяет запрос по тегам validate и возвращает все ошибки полей сразу.
+func validateRequest(req any) []dto.FieldError {
+	err := validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []dto.FieldError{{Code: dto.FieldBadFormat, Message: err.Error()}}
+	}
+
+	fields := make([]dto.FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		fields = append(fields, toFieldError(fieldErr))
+	}
+
+	return fields
+}
+
+func toFieldError(fieldErr validator.FieldError) dto.FieldError {
+	field := fieldPath(fieldErr)
+
+	if fieldErr.Tag() == "required" {
+		return dto.FieldError{
+			Field:   field,
+			Code:    dto.FieldRequired,
+			Message: fmt.Sprintf("Field '%s' is required", field),
+		}
+	}
+
+	var message string
+	switch fieldErr.Tag() {
+	case "uuid", "uuid4":
+		message = "must be a valid UUID"
+	case "email":
+		message = "must be a valid email address"
+	case "min", "gte":
+		message = fmt.Sprintf("must be at least %s%s", fieldErr.Param(), sizeUnit(fieldErr))
+	case "max", "lte":
+		message = fmt.Sprintf("must be at most %s%s", fieldErr.Param(), sizeUnit(fieldErr))
+	case "gt":
+		message = fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "slug":
+		message = "must contain only lowercase letters, digits and single hyphens"
+	case "oneof":
+		message = fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	default:
+		message = fmt.Sprintf("failed '%s' check", fieldErr.Tag())
+	}
+
+	return dto.FieldError{
+		Field:   field,
+		Code:    dto.FieldBadFormat,
+		Message: fmt.Sprintf("Field '%s' %s", field, message),
+	}
+}
+
+// fieldPath возвращает путь к полю без имени корневой структуры, например scopes[0].
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return fieldErr.Field()
+}
+
+func sizeUnit(fieldErr validator.FieldError) string {
+	switch fieldErr.Kind() {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FS хранит объекты файлами в каталоге: ключ "a/b" лежит в <dir>/a/b.
+// Тип содержимого не сохраняется и определяется по расширению или первым байтам файла.
+type FS struct {
+	dir string
+}
+
+func NewFS(dir string) (*FS, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create storage directory")
+	}
+	return &FS{dir: dir}, nil
+}
+
+func (s *FS) Put(_ context.Context, key string, body io.Reader, size int64, _ string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return errors.Wrap(err, "failed to create object directory")
+	}
+
+	// Пишем во временный файл рядом и переименовываем: читатель не увидит недописанный объект
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create object file")
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, body)
+	if err == nil && written != size {
+		err = errors.Errorf("object body has %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write object file")
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return errors.Wrap(err, "failed to store object file")
+	}
+	return nil
+}
+
+func (s *FS) Get(_ context.Context, key string, offset, length int64) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fsError(err, "failed to open object file")
+	}
+
+	info, err := s.stat(key, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if offset < 0 || offset > info.Size || (offset == info.Size && length != 0 && info.Size > 0) {
+		file.Close()
+		return nil, ErrInvalidRange
+	}
+	if length < 0 || offset+length > info.Size {
+		length = info.Size - offset
+	}
+
+	return &Object{
+		ReadCloser: struct {
+			io.Reader
+			io.Closer
+		}{io.NewSectionReader(file, offset, length), file},
+		Info: *info,
+	}, nil
+}
+
+func (s *FS) Delete(_ context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to delete object file")
+	}
+	return nil
+}
+
+func (s *FS) Stat(_ context.Context, key string) (*ObjectInfo, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fsError(err, "failed to open object file")
+	}
+	defer file.Close()
+
+	return s.stat(key, file)
+}
+
+func (s *FS) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+
+	// Обходим только каталог, в котором могут лежать ключи с этим префиксом
+	root := s.dir
+	if dir := path.Dir(prefix); strings.Contains(prefix, "/") {
+		if err := checkKey(dir); err != nil {
+			return nil, err
+		}
+		root = filepath.Join(s.dir, filepath.FromSlash(dir))
+	}
+
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".put-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  mime.TypeByExtension(path.Ext(key)),
+			ETag:         fsETag(info),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list objects")
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// PresignGet не поддерживается: файлы локального диска отдает только сам сервис.
+func (s *FS) PresignGet(context.Context, string, time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+func (s *FS) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func (s *FS) stat(key string, file *os.File) (*ObjectInfo, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat object file")
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, err := file.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, "failed to read object file")
+		}
+		contentType = http.DetectContentType(head[:n])
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  contentType,
+		ETag:         fsETag(info),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// fsETag строится из времени изменения и размера: объекты перезаписываются только целиком.
+func fsETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+func fsError(err error, message string) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return errors.Wrap(err, message)
+}
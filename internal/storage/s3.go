@@ -0,0 +1,534 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat  = "20060102T150405Z"
+	sigV4DateFormat  = "20060102"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	maxPresignExpiry = 7 * 24 * time.Hour
+
+	// Одним PUT S3 принимает не больше 5 ГиБ; объекты крупнее partSize загружаются по частям.
+	// Частей не больше maxParts, поэтому для очень больших объектов часть увеличивается
+	defaultPartSize = 64 << 20
+	maxParts        = 10000
+)
+
+type S3Options struct {
+	// Endpoint - адрес сервиса со схемой, например https://s3.eu-central-1.amazonaws.com или http://localhost:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle адресует бакет путем (endpoint/bucket/key), а не поддоменом - так работают MinIO и похожие
+	PathStyle bool
+}
+
+// S3 - хранилище поверх S3-совместимого API. Запросы подписываются AWS Signature Version 4.
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	partSize int64
+	now      func() time.Time
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, errors.Errorf("invalid s3 endpoint %q", opts.Endpoint)
+	}
+	if opts.Bucket == "" || opts.Region == "" || opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, errors.New("s3 bucket, region and credentials are required")
+	}
+
+	return &S3{opts: opts, endpoint: endpoint, client: &http.Client{}, partSize: defaultPartSize, now: time.Now}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if size > s.partSize {
+		return s.putMultipart(ctx, key, body, size, contentType)
+	}
+
+	req, err := s.request(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to put object")
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string, offset, length int64) (*Object, error) {
+	if offset < 0 {
+		return nil, ErrInvalidRange
+	}
+
+	// Пустой диапазон в заголовке Range не выразить: как и FS, отдаем пустое тело, если offset в пределах объекта
+	if length == 0 {
+		info, err := s.Stat(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if offset > info.Size {
+			return nil, ErrInvalidRange
+		}
+		return &Object{ReadCloser: io.NopCloser(strings.NewReader("")), Info: *info}, nil
+	}
+
+	req, err := s.request(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case length > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get object")
+	}
+
+	info := objectInfo(key, resp)
+	if resp.StatusCode == http.StatusPartialContent {
+		// Полный размер объекта в ответе на диапазон есть только в Content-Range: bytes a-b/size
+		_, total, _ := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		if info.Size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			resp.Body.Close()
+			return nil, errors.Wrap(err, "invalid Content-Range in s3 response")
+		}
+	}
+
+	return &Object{ReadCloser: resp.Body, Info: info}, nil
+}
+
+// putMultipart загружает объект частями (CreateMultipartUpload, UploadPart, CompleteMultipartUpload).
+// При ошибке незавершенная загрузка отменяется, чтобы ее части не занимали место в бакете.
+func (s *S3) putMultipart(ctx context.Context, key string, body io.Reader, size int64, contentType string) (err error) {
+	partSize := max(s.partSize, (size+maxParts-1)/maxParts)
+
+	req, err := s.request(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	var initiate struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.doXML(req, &initiate); err != nil {
+		return errors.Wrap(err, "failed to start multipart upload")
+	}
+	if initiate.UploadID == "" {
+		return errors.New("s3 returned empty multipart upload id")
+	}
+
+	defer func() {
+		if err != nil {
+			s.abortMultipart(context.WithoutCancel(ctx), key, initiate.UploadID)
+		}
+	}()
+
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+partSize, number+1 {
+		length := min(partSize, size-offset)
+
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiate.UploadID}}
+		req, err := s.request(ctx, http.MethodPut, key, query, io.LimitReader(body, length))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = length
+
+		resp, err := s.do(req)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload part %d", number)
+		}
+		resp.Body.Close()
+
+		complete.Parts = append(complete.Parts, part{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+
+	payload, err := xml.Marshal(complete)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode multipart upload parts")
+	}
+
+	req, err = s.request(ctx, http.MethodPost, key, url.Values{"uploadId": {initiate.UploadID}}, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+
+	// CompleteMultipartUpload может ответить 200 с ошибкой в теле
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := s.doXML(req, &result); err != nil {
+		return errors.Wrap(err, "failed to complete multipart upload")
+	}
+	if result.XMLName.Local == "Error" {
+		return errors.Errorf("failed to complete multipart upload: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+func (s *S3) abortMultipart(ctx context.Context, key, uploadID string) {
+	req, err := s.request(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return
+	}
+	if resp, err := s.do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.Wrap(err, "failed to delete object")
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	req, err := s.request(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat object")
+	}
+	resp.Body.Close()
+
+	info := objectInfo(key, resp)
+	return &info, nil
+}
+
+// List читает ListObjectsV2 постранично, пока сервис не вернет последнюю страницу.
+func (s *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	token := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list objects")
+		}
+
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				ETag         string    `xml:"ETag"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode object list")
+		}
+
+		for _, item := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          item.Key,
+				Size:         item.Size,
+				ETag:         item.ETag,
+				LastModified: item.LastModified,
+			})
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// PresignGet подписывает GET параметрами запроса, поэтому ссылку можно отдать клиенту как есть.
+func (s *S3) PresignGet(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > maxPresignExpiry {
+		return "", errors.Errorf("presign ttl must be within (0, %s]", maxPresignExpiry)
+	}
+
+	now := s.now().UTC()
+	target := s.objectURL(key)
+
+	query := url.Values{
+		"X-Amz-Algorithm":     {sigV4Algorithm},
+		"X-Amz-Credential":    {s.opts.AccessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(sigV4TimeFormat)},
+		"X-Amz-Expires":       {strconv.FormatInt(int64(ttl/time.Second), 10)},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	target.RawQuery = canonicalQuery(query)
+
+	header := http.Header{}
+	header.Set("Host", target.Host)
+	signature := s.signature(http.MethodGet, target, header, unsignedPayload, now)
+
+	target.RawQuery += "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+// request собирает подписанный запрос; пустой key адресует сам бакет.
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	if key != "" {
+		if err := checkKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	target := s.objectURL(key)
+	target.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build s3 request")
+	}
+
+	// Тело не хэшируется: загрузки бывают в гигабайты, а целостность проверяется по контрольной сумме до Put
+	now := s.now().UTC()
+	req.Header.Set("Host", target.Host)
+	req.Header.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signature := s.signature(method, target, req.Header, unsignedPayload, now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.opts.AccessKey, s.scope(now), signedHeaders(req.Header), signature))
+
+	return req, nil
+}
+
+// do выполняет запрос и превращает ответы с ошибкой в error, закрывая их тело.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, ErrInvalidRange
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&s3Err); err != nil || s3Err.Code == "" {
+		return nil, errors.Errorf("s3 responded with status %d", resp.StatusCode)
+	}
+	return nil, errors.Errorf("s3 responded with status %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+}
+
+// doXML выполняет запрос и разбирает XML-ответ в result.
+func (s *S3) doXML(req *http.Request, result any) error {
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "failed to decode s3 response")
+	}
+	return nil
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	target := *s.endpoint
+	target.RawQuery = ""
+
+	base := strings.TrimSuffix(target.Path, "/")
+	if s.opts.PathStyle {
+		base += "/" + s.opts.Bucket
+	} else {
+		target.Host = s.opts.Bucket + "." + target.Host
+	}
+
+	target.Path = base + "/" + key
+	target.RawPath = uriEncode(base, false) + "/" + uriEncode(key, false)
+	return &target
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format(sigV4DateFormat) + "/" + s.opts.Region + "/s3/aws4_request"
+}
+
+// signature считает подпись SigV4 по каноническому запросу из заголовков header.
+func (s *S3) signature(method string, target *url.URL, header http.Header, payloadHash string, now time.Time) string {
+	names := signedHeaderNames(header)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		target.EscapedPath(),
+		target.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(names, ";"),
+		payloadHash,
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func objectInfo(key string, resp *http.Response) ObjectInfo {
+	info := ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	return info
+}
+
+func signedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func signedHeaders(header http.Header) string {
+	return strings.Join(signedHeaderNames(header), ";")
+}
+
+// canonicalQuery кодирует параметры так, как их кодирует SigV4: отсортированы, пробел - %20.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode экранирует все, кроме незарезервированных символов RFC 3986; слэш - только при encodeSlash.
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"streaming-service/internal/config"
+)
+
+// Драйверы хранилища, которые выбираются через STORAGE_DRIVER
+const (
+	DriverFS = "fs"
+	DriverS3 = "s3"
+)
+
+var (
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidRange - запрошенный диапазон начинается за концом объекта
+	ErrInvalidRange = errors.New("requested range is not satisfiable")
+	// ErrPresignUnsupported - хранилище не умеет выдавать ссылки для прямого скачивания
+	ErrPresignUnsupported = errors.New("presigned urls are not supported by this storage")
+)
+
+// ObjectInfo - метаданные объекта. Size - полный размер объекта, даже если прочитан только диапазон.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Object - содержимое объекта или его диапазона; вызывающий обязан закрыть его.
+type Object struct {
+	io.ReadCloser
+	Info ObjectInfo
+}
+
+// BlobStore хранит медиафайлы по ключам вида "assets/<uuid>".
+type BlobStore interface {
+	// Put записывает объект целиком; size - точный размер body
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get читает length байт с позиции offset; length < 0 - до конца объекта
+	Get(ctx context.Context, key string, offset, length int64) (*Object, error)
+	// Delete удаляет объект; отсутствие объекта ошибкой не считается
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List возвращает объекты, ключи которых начинаются с prefix, в порядке ключей
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// PresignGet выдает ссылку, по которой объект можно скачать без авторизации в течение ttl
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// New создает хранилище, выбранное в конфигурации.
+func New(cfg config.Storage) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverFS:
+		return NewFS(cfg.Dir)
+	case DriverS3:
+		return NewS3(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, errors.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// checkKey отклоняет ключи, которые в файловом хранилище вышли бы за пределы корня.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return errors.Errorf("invalid object key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return errors.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
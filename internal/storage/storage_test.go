@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// forEachStore прогоняет тест на обоих хранилищах: поведение BlobStore не должно зависеть от драйвера.
+func forEachStore(t *testing.T, test func(t *testing.T, store BlobStore)) {
+	t.Run("fs", func(t *testing.T) {
+		store, err := NewFS(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		test(t, store)
+	})
+	t.Run("s3", func(t *testing.T) {
+		_, store := newFakeS3(t)
+		test(t, store)
+	})
+}
+
+func read(t *testing.T, store BlobStore, key string, offset, length int64) (string, *Object, error) {
+	t.Helper()
+
+	object, err := store.Get(context.Background(), key, offset, length)
+	if err != nil {
+		return "", nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), object, nil
+}
+
+func TestGetRanges(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		if err := store.Put(context.Background(), "assets/a", strings.NewReader("0123456789"), 10, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name           string
+			offset, length int64
+			want           string
+			err            error
+		}{
+			{name: "whole", offset: 0, length: -1, want: "0123456789"},
+			{name: "range", offset: 2, length: 3, want: "234"},
+			{name: "to end", offset: 7, length: -1, want: "789"},
+			{name: "past end is clipped", offset: 8, length: 5, want: "89"},
+			{name: "empty inside", offset: 3, length: 0, want: ""},
+			{name: "empty at end", offset: 10, length: 0, want: ""},
+			{name: "start at end", offset: 10, length: -1, err: ErrInvalidRange},
+			{name: "start past end", offset: 11, length: 1, err: ErrInvalidRange},
+			{name: "empty past end", offset: 11, length: 0, err: ErrInvalidRange},
+			{name: "negative offset", offset: -1, length: 1, err: ErrInvalidRange},
+		}
+
+		for _, tt := range tests {
+			got, object, err := read(t, store, "assets/a", tt.offset, tt.length)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			if got != tt.want || object.Info.Size != 10 {
+				t.Errorf("%s: got %q (size %d), want %q (size 10)", tt.name, got, object.Info.Size, tt.want)
+			}
+		}
+	})
+}
+
+func TestNotFound(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		ctx := context.Background()
+
+		if _, err := store.Get(ctx, "assets/missing", 0, -1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get = %v, want ErrNotFound", err)
+		}
+		if _, err := store.Get(ctx, "assets/missing", 0, 0); !errors.Is(err, ErrNotFound) {
+			t.Errorf("empty Get = %v, want ErrNotFound", err)
+		}
+		if _, err := store.Stat(ctx, "assets/missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Stat = %v, want ErrNotFound", err)
+		}
+		if err := store.Delete(ctx, "assets/missing"); err != nil {
+			t.Errorf("Delete of missing object = %v, want nil", err)
+		}
+	})
+}
+
+func TestPutListDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		ctx := context.Background()
+
+		for _, key := range []string{"packages/a/720p/index.m3u8", "packages/a/master.m3u8", "packages/ab/master.m3u8"} {
+			if err := store.Put(ctx, key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Перезапись заменяет объект целиком
+		if err := store.Put(ctx, "packages/a/master.m3u8", strings.NewReader("new"), 3, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		objects, err := store.List(ctx, "packages/a/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 2 || objects[0].Key != "packages/a/720p/index.m3u8" ||
+			objects[1].Key != "packages/a/master.m3u8" || objects[1].Size != 3 {
+			t.Errorf("List = %+v", objects)
+		}
+
+		if err := store.Delete(ctx, "packages/a/master.m3u8"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Stat(ctx, "packages/a/master.m3u8"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+		}
+
+		if objects, err := store.List(ctx, "missing/"); err != nil || len(objects) != 0 {
+			t.Errorf("List of missing prefix = %v, %v", objects, err)
+		}
+	})
+}
+
+func TestInvalidKeys(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store BlobStore) {
+		for _, key := range []string{"", "/abs", "dir/", "a//b", "../escape", "a/../../b", "a/./b"} {
+			if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+				t.Errorf("Put(%q) succeeded, want error", key)
+			}
+			if _, err := store.Get(context.Background(), key, 0, -1); err == nil {
+				t.Errorf("Get(%q) succeeded, want error", key)
+			}
+		}
+	})
+}
+
+func TestFSPutSizeMismatch(t *testing.T) {
+	store, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put(context.Background(), "assets/a", strings.NewReader("short"), 10, ""); err == nil {
+		t.Fatal("Put with wrong size succeeded")
+	}
+	if _, err := store.Stat(context.Background(), "assets/a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("partially written object is visible: %v", err)
+	}
+	if _, err := store.PresignGet(context.Background(), "assets/a", 0); !errors.Is(err, ErrPresignUnsupported) {
+		t.Errorf("PresignGet = %v, want ErrPresignUnsupported", err)
+	}
+}
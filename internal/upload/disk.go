@@ -0,0 +1,181 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrOffsetMismatch - фрагмент пришел не с того смещения, на котором остановилась загрузка.
+var ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+// Disk хранит незавершенные загрузки на локальном диске: по файлу <id>.part на загрузку.
+// Фрагменты дописываются в конец файла, поэтому загрузку можно продолжить после обрыва.
+type Disk struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewDisk(dir string, maxSize int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create upload directory")
+	}
+	return &Disk{dir: dir, maxSize: maxSize, locks: make(map[string]*sync.Mutex)}, nil
+}
+
+// MaxSize - наибольший допустимый размер загрузки в байтах.
+func (d *Disk) MaxSize() int64 {
+	return d.maxSize
+}
+
+// Create заводит пустой файл для новой загрузки.
+func (d *Disk) Create(id string) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create upload file")
+	}
+	return file.Close()
+}
+
+// Append дописывает фрагмент с позиции offset и возвращает новый размер.
+// Хвост за offset (остаток неподтвержденной попытки) отбрасывается, поэтому повтор фрагмента безопасен.
+func (d *Disk) Append(id string, offset int64, data io.Reader) (int64, error) {
+	path, err := d.path(id)
+	if err != nil {
+		return 0, err
+	}
+
+	unlock := d.lock(id)
+	defer unlock()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to open upload file")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to stat upload file")
+	}
+	if info.Size() < offset {
+		return 0, errors.Wrapf(ErrOffsetMismatch, "upload file has %d bytes, chunk starts at %d", info.Size(), offset)
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		return 0, errors.Wrap(err, "failed to truncate upload file")
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "failed to seek upload file")
+	}
+
+	written, err := io.Copy(file, data)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to write upload chunk")
+	}
+	if err := file.Sync(); err != nil {
+		return 0, errors.Wrap(err, "failed to sync upload file")
+	}
+
+	return offset + written, nil
+}
+
+// Checksum считает SHA-256 загруженного файла (hex).
+func (d *Disk) Checksum(id string) (string, error) {
+	file, err := d.Open(id)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", errors.Wrap(err, "failed to read upload file")
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DetectContentType определяет MIME-тип по первым байтам файла.
+func (d *Disk) DetectContentType(id string) (string, error) {
+	file, err := d.Open(id)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", errors.Wrap(err, "failed to read upload file")
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// Open открывает загруженный файл на чтение.
+func (d *Disk) Open(id string) (*os.File, error) {
+	path, err := d.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open upload file")
+	}
+	return file, nil
+}
+
+// Remove удаляет файл загрузки; отсутствие файла ошибкой не считается.
+// Завершенная загрузка к этому моменту уже перенесена в хранилище медиафайлов.
+func (d *Disk) Remove(id string) error {
+	path, err := d.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove upload file")
+	}
+
+	d.mu.Lock()
+	delete(d.locks, id)
+	d.mu.Unlock()
+	return nil
+}
+
+// path строит путь к файлу загрузки; id - uuid, иначе он мог бы выйти за пределы каталога.
+func (d *Disk) path(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", errors.Wrap(err, "invalid upload id")
+	}
+	return filepath.Join(d.dir, id+".part"), nil
+}
+
+// lock не дает двум запросам одновременно писать в одну загрузку.
+func (d *Disk) lock(id string) func() {
+	d.mu.Lock()
+	lock, ok := d.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.locks[id] = lock
+	}
+	d.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/config"
+	"streaming-service/internal/repo"
+)
+
+// Handler выполняет задачу. progress сообщает готовность в процентах; ctx отменяется,
+// если пул останавливается или аренда задачи перешла к другому обработчику.
+type Handler func(ctx context.Context, job *repo.Job, progress func(percent int)) error
+
+// permanentError - ошибка, повтор после которой ничего не изменит.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку обработчика как окончательную: задача сразу уходит в dead, без повторов.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Pool разбирает очередь задач из Postgres несколькими обработчиками.
+// Задача арендуется на cfg.Lease и продлевается, пока выполняется; упавший процесс
+// аренду не продлит, и задачу подхватит другой обработчик.
+type Pool struct {
+	jobs     repo.JobRepository
+	cfg      config.Jobs
+	log      *zap.SugaredLogger
+	id       string
+	handlers map[string]Handler
+}
+
+func NewPool(jobs repo.JobRepository, cfg config.Jobs, log *zap.SugaredLogger) *Pool {
+	host, _ := os.Hostname()
+	return &Pool{
+		jobs:     jobs,
+		cfg:      cfg,
+		log:      log,
+		id:       fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.New().String()[:8]),
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle регистрирует обработчик задач типа kind; вызывается до Run.
+func (p *Pool) Handle(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// Run запускает обработчики и блокируется до отмены ctx и завершения выполняемых задач.
+// Прерванные остановкой задачи возвращаются в очередь.
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			p.work(ctx, worker)
+		}(fmt.Sprintf("%s/%d", p.id, i))
+	}
+	wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context, worker string) {
+	for {
+		job, err := p.jobs.ClaimJob(ctx, worker, p.cfg.Lease)
+		if err != nil && ctx.Err() == nil {
+			p.log.Error("Failed to claim job", zap.Error(err))
+		}
+
+		if job != nil {
+			p.process(ctx, worker, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.cfg.PollInterval):
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, worker string, job *repo.Job) {
+	// Итог задачи записывается и после остановки пула, поэтому не в ctx
+	finish := context.WithoutCancel(ctx)
+
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		p.bury(finish, worker, job, errors.Errorf("no handler for job kind %q", job.Kind))
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+		p.heartbeat(jobCtx, cancel, worker, job)
+	}()
+
+	progress := func(percent int) {
+		if err := p.jobs.SetJobProgress(jobCtx, job.UUID, worker, min(max(percent, 0), 99)); err != nil && jobCtx.Err() == nil {
+			p.log.Error("Failed to update job progress", zap.String("job", job.UUID), zap.Error(err))
+		}
+	}
+
+	err := handler(jobCtx, job, progress)
+	lost := jobCtx.Err() != nil && ctx.Err() == nil
+	cancel()
+	<-heartbeat
+
+	var permanent *permanentError
+	switch {
+	case lost:
+		// Аренду перехватил другой обработчик: итог запишет он
+		p.log.Warn("Job lease is lost", zap.String("job", job.UUID))
+	case err == nil:
+		if err := p.jobs.CompleteJob(finish, job.UUID, worker); err != nil {
+			p.log.Error("Failed to complete job", zap.String("job", job.UUID), zap.Error(err))
+		}
+	case ctx.Err() != nil:
+		if err := p.jobs.ReleaseJob(finish, job.UUID, worker); err != nil {
+			p.log.Error("Failed to release job", zap.String("job", job.UUID), zap.Error(err))
+		}
+	case errors.As(err, &permanent) || job.Attempts >= job.MaxAttempts:
+		p.bury(finish, worker, job, err)
+	default:
+		delay := p.backoff(job.Attempts)
+		p.log.Warn("Job failed, will retry", zap.String("job", job.UUID), zap.Int("attempt", job.Attempts),
+			zap.Duration("delay", delay), zap.Error(err))
+		if err := p.jobs.RetryJob(finish, job.UUID, worker, delay, err.Error()); err != nil {
+			p.log.Error("Failed to reschedule job", zap.String("job", job.UUID), zap.Error(err))
+		}
+	}
+}
+
+// heartbeat продлевает аренду, пока задача выполняется, и отменяет ее, если аренда потеряна.
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelFunc, worker string, job *repo.Job) {
+	ticker := time.NewTicker(p.cfg.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := p.jobs.ExtendJobLease(ctx, job.UUID, worker, p.cfg.Lease)
+			if errors.Is(err, repo.ErrConflict) {
+				cancel()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				p.log.Error("Failed to extend job lease", zap.String("job", job.UUID), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (p *Pool) bury(ctx context.Context, worker string, job *repo.Job, cause error) {
+	p.log.Error("Job is dead-lettered", zap.String("job", job.UUID), zap.Int("attempts", job.Attempts), zap.Error(cause))
+	if err := p.jobs.BuryJob(ctx, job.UUID, worker, cause.Error()); err != nil {
+		p.log.Error("Failed to bury job", zap.String("job", job.UUID), zap.Error(err))
+	}
+}
+
+// backoff - задержка перед попыткой attempt+1: удваивается с каждой неудачей, не больше BackoffMax.
+// Случайная добавка до 20% разводит по времени задачи, упавшие одновременно.
+func (p *Pool) backoff(attempt int) time.Duration {
+	// Удвоение в цикле, а не сдвигом: большой BackoffBase при сдвиге переполнялся бы в отрицательную задержку
+	delay := p.cfg.BackoffBase
+	for i := 1; i < attempt && delay < p.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.cfg.BackoffMax)
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,278 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"streaming-service/internal/config"
+	"streaming-service/internal/repo"
+)
+
+// fakeJobs записывает, чем закончилась задача, вместо очереди в Postgres.
+type fakeJobs struct {
+	repo.JobRepository
+
+	mu       sync.Mutex
+	queue    []*repo.Job
+	outcome  string // complete, retry, bury, release
+	delay    time.Duration
+	lastErr  string
+	extends  int
+	progress []int
+	// lostAfter - после скольких продлений аренда считается перехваченной; 0 - никогда
+	lostAfter int
+}
+
+func (f *fakeJobs) ClaimJob(context.Context, string, time.Duration) (*repo.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.queue) == 0 {
+		return nil, nil
+	}
+	job := f.queue[0]
+	f.queue = f.queue[1:]
+	job.Attempts++
+	return job, nil
+}
+
+func (f *fakeJobs) ExtendJobLease(context.Context, string, string, time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.extends++
+	if f.lostAfter > 0 && f.extends >= f.lostAfter {
+		return &repo.Error{Kind: repo.ErrConflict, Entity: "job"}
+	}
+	return nil
+}
+
+func (f *fakeJobs) SetJobProgress(_ context.Context, _, _ string, progress int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.progress = append(f.progress, progress)
+	return nil
+}
+
+func (f *fakeJobs) finish(outcome string, delay time.Duration, lastErr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.outcome != "" {
+		return errors.Errorf("job already finished as %s", f.outcome)
+	}
+	f.outcome, f.delay, f.lastErr = outcome, delay, lastErr
+	return nil
+}
+
+func (f *fakeJobs) CompleteJob(context.Context, string, string) error {
+	return f.finish("complete", 0, "")
+}
+
+func (f *fakeJobs) RetryJob(_ context.Context, _, _ string, delay time.Duration, lastError string) error {
+	return f.finish("retry", delay, lastError)
+}
+
+func (f *fakeJobs) BuryJob(_ context.Context, _, _, lastError string) error {
+	return f.finish("bury", 0, lastError)
+}
+
+func (f *fakeJobs) ReleaseJob(context.Context, string, string) error {
+	return f.finish("release", 0, "")
+}
+
+func testConfig() config.Jobs {
+	return config.Jobs{
+		Workers:      1,
+		PollInterval: 5 * time.Millisecond,
+		Lease:        30 * time.Millisecond,
+		MaxAttempts:  3,
+		BackoffBase:  time.Second,
+		BackoffMax:   time.Minute,
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		attempt  int
+		expected time.Duration
+	}{
+		{name: "first retry", base: time.Second, max: time.Minute, attempt: 1, expected: time.Second},
+		{name: "doubles", base: time.Second, max: time.Minute, attempt: 2, expected: 2 * time.Second},
+		{name: "doubles again", base: time.Second, max: time.Minute, attempt: 4, expected: 8 * time.Second},
+		{name: "capped", base: time.Second, max: time.Minute, attempt: 7, expected: time.Minute},
+		{name: "many attempts", base: time.Second, max: time.Minute, attempt: 100, expected: time.Minute},
+		{name: "large base does not overflow", base: time.Hour, max: 24 * time.Hour, attempt: 31, expected: 24 * time.Hour},
+		{name: "base above max", base: time.Hour, max: time.Minute, attempt: 1, expected: time.Minute},
+		{name: "no attempts yet", base: time.Second, max: time.Minute, attempt: 0, expected: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.BackoffBase, cfg.BackoffMax = tt.base, tt.max
+			pool := NewPool(&fakeJobs{}, cfg, zap.NewNop().Sugar())
+
+			// Случайная добавка - не больше 20%
+			for i := 0; i < 100; i++ {
+				if delay := pool.backoff(tt.attempt); delay < tt.expected || delay > tt.expected+tt.expected/5 {
+					t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, delay, tt.expected, tt.expected+tt.expected/5)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	failure := errors.New("transcoder crashed")
+
+	tests := []struct {
+		name     string
+		kind     string
+		attempts int
+		handler  Handler
+		outcome  string
+		lastErr  string
+	}{
+		{name: "success", handler: func(context.Context, *repo.Job, func(int)) error { return nil }, outcome: "complete"},
+		{name: "failure is retried", attempts: 1, handler: func(context.Context, *repo.Job, func(int)) error { return failure },
+			outcome: "retry", lastErr: failure.Error()},
+		{name: "failure before the last attempt", attempts: 2, handler: func(context.Context, *repo.Job, func(int)) error {
+			return failure
+		}, outcome: "retry", lastErr: failure.Error()},
+		{name: "last attempt is buried", attempts: 3, handler: func(context.Context, *repo.Job, func(int)) error { return failure },
+			outcome: "bury", lastErr: failure.Error()},
+		{name: "permanent error is buried at once", attempts: 1, handler: func(context.Context, *repo.Job, func(int)) error {
+			return Permanent(errors.Wrap(failure, "checksum mismatch"))
+		}, outcome: "bury", lastErr: "checksum mismatch: " + failure.Error()},
+		{name: "wrapped permanent error", attempts: 1, handler: func(context.Context, *repo.Job, func(int)) error {
+			return errors.Wrap(Permanent(failure), "package")
+		}, outcome: "bury", lastErr: "package: " + failure.Error()},
+		{name: "unknown kind", kind: "unknown", outcome: "bury", lastErr: `no handler for job kind "unknown"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeJobs{}
+			pool := NewPool(jobs, testConfig(), zap.NewNop().Sugar())
+			if tt.handler != nil {
+				pool.Handle("package", tt.handler)
+			}
+
+			kind := "package"
+			if tt.kind != "" {
+				kind = tt.kind
+			}
+			pool.process(context.Background(), "worker", &repo.Job{UUID: "job", Kind: kind, Attempts: tt.attempts, MaxAttempts: 3})
+
+			if jobs.outcome != tt.outcome || jobs.lastErr != tt.lastErr {
+				t.Fatalf("outcome = %s (%q), want %s (%q)", jobs.outcome, jobs.lastErr, tt.outcome, tt.lastErr)
+			}
+			if tt.outcome == "retry" {
+				if expected := pool.cfg.BackoffBase << (tt.attempts - 1); jobs.delay < expected || jobs.delay > expected+expected/5 {
+					t.Errorf("retry delay = %v, want about %v", jobs.delay, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessHeartbeat(t *testing.T) {
+	jobs := &fakeJobs{}
+	pool := NewPool(jobs, testConfig(), zap.NewNop().Sugar())
+	pool.Handle("package", func(ctx context.Context, _ *repo.Job, progress func(int)) error {
+		progress(-5)
+		progress(50)
+		progress(100)
+
+		// Задача дольше аренды: ее должны продлевать, а не отменять
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(4 * pool.cfg.Lease):
+			return nil
+		}
+	})
+
+	pool.process(context.Background(), "worker", &repo.Job{UUID: "job", Kind: "package", Attempts: 1, MaxAttempts: 3})
+
+	if jobs.outcome != "complete" {
+		t.Fatalf("outcome = %s, want complete", jobs.outcome)
+	}
+	if jobs.extends < 3 {
+		t.Errorf("lease extended %d times, want at least 3", jobs.extends)
+	}
+	// 100% выставляет только завершение задачи
+	if len(jobs.progress) != 3 || jobs.progress[0] != 0 || jobs.progress[1] != 50 || jobs.progress[2] != 99 {
+		t.Errorf("progress = %v, want [0 50 99]", jobs.progress)
+	}
+}
+
+func TestProcessLostLease(t *testing.T) {
+	jobs := &fakeJobs{lostAfter: 2}
+	pool := NewPool(jobs, testConfig(), zap.NewNop().Sugar())
+
+	cancelled := make(chan struct{})
+	pool.Handle("package", func(ctx context.Context, _ *repo.Job, _ func(int)) error {
+		select {
+		case <-ctx.Done():
+			close(cancelled)
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+
+	pool.process(context.Background(), "worker", &repo.Job{UUID: "job", Kind: "package", Attempts: 1, MaxAttempts: 3})
+
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("handler was not cancelled after the lease was lost")
+	}
+	// Итог пишет обработчик, перехвативший аренду
+	if jobs.outcome != "" {
+		t.Errorf("outcome = %s, want none", jobs.outcome)
+	}
+}
+
+func TestRunStopReleasesJob(t *testing.T) {
+	jobs := &fakeJobs{queue: []*repo.Job{{UUID: "job", Kind: "package", MaxAttempts: 3}}}
+	pool := NewPool(jobs, testConfig(), zap.NewNop().Sugar())
+
+	ctx, stop := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	pool.Handle("package", func(ctx context.Context, _ *repo.Job, _ func(int)) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(ctx)
+	}()
+
+	<-started
+	stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after stop")
+	}
+
+	// Прерванная остановкой задача возвращается в очередь без попытки, а не повторяется и не хоронится
+	if jobs.outcome != "release" {
+		t.Errorf("outcome = %s, want release", jobs.outcome)
+	}
+}
@@ -0,0 +1,5 @@
+-- Удаление таблицы sessions
+DROP TABLE IF EXISTS sessions;
+
+-- Удаление таблицы users
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,21 @@
+-- Создание таблицы users
+CREATE TABLE users (
+                       uuid UUID PRIMARY KEY, -- Уникальный идентификатор пользователя
+                       email TEXT NOT NULL, -- Email, используется как логин
+                       password_hash TEXT NOT NULL, -- bcrypt-хэш пароля
+                       created_at TIMESTAMP DEFAULT now() -- Время создания записи
+);
+
+-- Email уникален без учета регистра
+CREATE UNIQUE INDEX idx_users_email ON users(lower(email));
+
+-- Создание таблицы sessions (одна сессия = один refresh-токен)
+CREATE TABLE sessions (
+                          uuid UUID PRIMARY KEY, -- Идентификатор сессии, попадает в claim sid
+                          user_id UUID NOT NULL REFERENCES users(uuid) ON DELETE CASCADE, -- Владелец сессии
+                          expires_at TIMESTAMP NOT NULL, -- Время истечения refresh-токена
+                          revoked_at TIMESTAMP, -- Время отзыва (logout/refresh)
+                          created_at TIMESTAMP DEFAULT now() -- Время создания записи
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
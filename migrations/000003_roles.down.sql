@@ -0,0 +1,5 @@
+-- Удаление таблицы owner_members
+DROP TABLE IF EXISTS owner_members;
+
+-- Удаление роли пользователя
+ALTER TABLE users DROP COLUMN IF EXISTS role;
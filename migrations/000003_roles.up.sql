@@ -0,0 +1,13 @@
+-- Глобальная роль пользователя
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'viewer' CHECK (role IN ('admin', 'viewer'));
+
+-- Создание таблицы owner_members (участники владельца и их роль в нем)
+CREATE TABLE owner_members (
+                               owner_id UUID NOT NULL REFERENCES owners(uuid) ON DELETE CASCADE, -- Владелец
+                               user_id UUID NOT NULL REFERENCES users(uuid) ON DELETE CASCADE, -- Участник
+                               role TEXT NOT NULL CHECK (role IN ('owner-member', 'editor', 'viewer')), -- Роль в рамках владельца
+                               created_at TIMESTAMP DEFAULT now(), -- Время создания записи
+                               PRIMARY KEY (owner_id, user_id)
+);
+
+CREATE INDEX idx_owner_members_user_id ON owner_members(user_id);
@@ -0,0 +1,2 @@
+-- Удаление таблицы api_keys
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,15 @@
+-- Создание таблицы api_keys (машинный доступ владельцев)
+CREATE TABLE api_keys (
+                          uuid UUID PRIMARY KEY, -- Идентификатор ключа
+                          owner_id UUID NOT NULL REFERENCES owners(uuid) ON DELETE CASCADE, -- Владелец, от имени которого действует ключ
+                          name TEXT NOT NULL DEFAULT '', -- Человекочитаемое название
+                          prefix TEXT NOT NULL, -- Начало ключа для отображения в списке
+                          key_hash TEXT NOT NULL UNIQUE, -- SHA-256 от ключа, сам ключ не хранится
+                          scopes TEXT[] NOT NULL DEFAULT '{}', -- Разрешения ключа (movies:read, movies:write)
+                          created_at TIMESTAMP DEFAULT now(), -- Время создания записи
+                          rotated_at TIMESTAMP, -- Время последней ротации
+                          last_used_at TIMESTAMP, -- Время последнего использования
+                          revoked_at TIMESTAMP -- Время отзыва
+);
+
+CREATE INDEX idx_api_keys_owner_id ON api_keys(owner_id);
@@ -0,0 +1,4 @@
+-- Удаление версий записей
+ALTER TABLE movies DROP COLUMN IF EXISTS version;
+
+ALTER TABLE owners DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Версия записи для оптимистичной блокировки (ETag / If-Match)
+ALTER TABLE movies ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE owners ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+-- Удаление индексов пагинации
+DROP INDEX IF EXISTS idx_movies_created_at_uuid;
+
+DROP INDEX IF EXISTS idx_owners_created_at_uuid;
+
+ALTER TABLE movies ALTER COLUMN created_at DROP NOT NULL;
+
+ALTER TABLE owners ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,11 @@
+-- Ключ пагинации (created_at, uuid) не должен содержать NULL
+UPDATE owners SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE owners ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE movies SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE movies ALTER COLUMN created_at SET NOT NULL;
+
+-- Индексы под keyset-пагинацию
+CREATE INDEX idx_owners_created_at_uuid ON owners(created_at, uuid);
+
+CREATE INDEX idx_movies_created_at_uuid ON movies(created_at, uuid);
@@ -0,0 +1,6 @@
+-- Удаление поисковых индексов
+DROP INDEX IF EXISTS idx_movies_title_trgm;
+
+DROP INDEX IF EXISTS idx_movies_search_vector;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS search_vector;
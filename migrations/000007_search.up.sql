@@ -0,0 +1,13 @@
+-- Триграммы для нечеткого поиска по названию
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Поисковый вектор: название важнее автора, автор важнее описания
+ALTER TABLE movies ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(author, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+) STORED;
+
+CREATE INDEX idx_movies_search_vector ON movies USING GIN (search_vector);
+
+CREATE INDEX idx_movies_title_trgm ON movies USING GIN (title gin_trgm_ops);
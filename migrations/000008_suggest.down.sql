@@ -0,0 +1,2 @@
+-- Удаление индекса подсказок
+DROP INDEX IF EXISTS idx_owners_name_trgm;
@@ -0,0 +1,2 @@
+-- Триграммный индекс по имени владельца для подсказок (обслуживает и ILIKE 'prefix%')
+CREATE INDEX idx_owners_name_trgm ON owners USING GIN (name gin_trgm_ops);
@@ -0,0 +1,4 @@
+-- Снятие уникальности имени владельца
+ALTER TABLE owners DROP CONSTRAINT IF EXISTS owners_name_key;
+
+CREATE INDEX idx_owners_name ON owners(name);
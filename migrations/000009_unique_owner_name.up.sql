@@ -0,0 +1,22 @@
+-- Сливаем владельцев-дубликатов в самого раннего, чтобы можно было добавить уникальность
+CREATE TEMPORARY TABLE owner_duplicates AS
+SELECT uuid, keeper FROM (
+    SELECT uuid, first_value(uuid) OVER (PARTITION BY name ORDER BY created_at, uuid) AS keeper FROM owners
+) ranked
+WHERE uuid <> keeper;
+
+UPDATE movies m SET owner_id = d.keeper FROM owner_duplicates d WHERE m.owner_id = d.uuid;
+
+UPDATE api_keys k SET owner_id = d.keeper FROM owner_duplicates d WHERE k.owner_id = d.uuid;
+
+INSERT INTO owner_members (owner_id, user_id, role, created_at)
+SELECT d.keeper, om.user_id, om.role, om.created_at FROM owner_members om JOIN owner_duplicates d ON om.owner_id = d.uuid
+ON CONFLICT (owner_id, user_id) DO NOTHING;
+
+DELETE FROM owners WHERE uuid IN (SELECT uuid FROM owner_duplicates);
+
+DROP TABLE owner_duplicates;
+
+-- Имя владельца уникально: автосоздание в CreateMovie опирается на INSERT ... ON CONFLICT (name)
+DROP INDEX IF EXISTS idx_owners_name;
+ALTER TABLE owners ADD CONSTRAINT owners_name_key UNIQUE (name);
@@ -0,0 +1,15 @@
+-- Содержимое корзины удаляется окончательно
+DELETE FROM movies WHERE deleted_at IS NOT NULL;
+
+DELETE FROM owners WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_owners_deleted_at;
+
+DROP INDEX IF EXISTS idx_movies_deleted_at;
+
+DROP INDEX IF EXISTS idx_owners_name_alive;
+ALTER TABLE owners ADD CONSTRAINT owners_name_key UNIQUE (name);
+
+ALTER TABLE owners DROP COLUMN deleted_at;
+
+ALTER TABLE movies DROP COLUMN deleted_at;
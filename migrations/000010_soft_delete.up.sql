@@ -0,0 +1,13 @@
+-- Мягкое удаление: строка остается в корзине до очистки по сроку хранения
+ALTER TABLE movies ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE owners ADD COLUMN deleted_at TIMESTAMP;
+
+-- Имя уникально только среди неудаленных владельцев
+ALTER TABLE owners DROP CONSTRAINT owners_name_key;
+CREATE UNIQUE INDEX idx_owners_name_alive ON owners(name) WHERE deleted_at IS NULL;
+
+-- Индексы под корзину и очистку
+CREATE INDEX idx_movies_deleted_at ON movies(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_owners_deleted_at ON owners(deleted_at) WHERE deleted_at IS NOT NULL;
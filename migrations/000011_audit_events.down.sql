@@ -0,0 +1,2 @@
+-- Удаление журнала изменений
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,18 @@
+-- Создание таблицы audit_events (журнал изменений)
+CREATE TABLE audit_events (
+                              uuid UUID PRIMARY KEY, -- Идентификатор события
+                              actor TEXT NOT NULL, -- Кто изменил: user:<uuid>, api-key:<uuid>, static-token или system
+                              action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')), -- Вид изменения
+                              entity_type TEXT NOT NULL, -- Тип записи (movie, owner)
+                              entity_id UUID NOT NULL, -- Идентификатор записи
+                              before JSONB, -- Измененные поля до (NULL при создании)
+                              after JSONB, -- Измененные поля после
+                              request_id TEXT NOT NULL DEFAULT '', -- X-Request-ID запроса
+                              created_at TIMESTAMP NOT NULL DEFAULT now() -- Время изменения
+);
+
+CREATE INDEX idx_audit_events_entity ON audit_events(entity_type, entity_id);
+
+CREATE INDEX idx_audit_events_actor ON audit_events(actor);
+
+CREATE INDEX idx_audit_events_created_at_uuid ON audit_events(created_at, uuid);
@@ -0,0 +1,8 @@
+-- Удаление жанров и тегов
+DROP TABLE IF EXISTS movie_tags;
+
+DROP TABLE IF EXISTS movie_genres;
+
+DROP TABLE IF EXISTS tags;
+
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,33 @@
+-- Создание таблицы genres (жанры)
+CREATE TABLE genres (
+                        uuid UUID PRIMARY KEY, -- Идентификатор жанра
+                        slug TEXT NOT NULL UNIQUE, -- Машинное имя, используется в API
+                        name TEXT NOT NULL, -- Отображаемое название
+                        created_at TIMESTAMP NOT NULL DEFAULT now() -- Время создания записи
+);
+
+-- Создание таблицы tags (теги)
+CREATE TABLE tags (
+                      uuid UUID PRIMARY KEY, -- Идентификатор тега
+                      slug TEXT NOT NULL UNIQUE, -- Машинное имя, используется в API
+                      name TEXT NOT NULL, -- Отображаемое название
+                      created_at TIMESTAMP NOT NULL DEFAULT now() -- Время создания записи
+);
+
+-- Связь фильмов с жанрами
+CREATE TABLE movie_genres (
+                              movie_id UUID NOT NULL REFERENCES movies(uuid) ON DELETE CASCADE, -- Фильм
+                              genre_id UUID NOT NULL REFERENCES genres(uuid) ON DELETE CASCADE, -- Жанр
+                              PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE INDEX idx_movie_genres_genre_id ON movie_genres(genre_id);
+
+-- Связь фильмов с тегами
+CREATE TABLE movie_tags (
+                            movie_id UUID NOT NULL REFERENCES movies(uuid) ON DELETE CASCADE, -- Фильм
+                            tag_id UUID NOT NULL REFERENCES tags(uuid) ON DELETE CASCADE, -- Тег
+                            PRIMARY KEY (movie_id, tag_id)
+);
+
+CREATE INDEX idx_movie_tags_tag_id ON movie_tags(tag_id);
@@ -0,0 +1,4 @@
+-- Удаление людей и титров; колонка author не затрагивалась, данные в ней сохранены
+DROP TABLE IF EXISTS movie_credits;
+
+DROP TABLE IF EXISTS people;
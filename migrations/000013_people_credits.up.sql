@@ -0,0 +1,29 @@
+-- Создание таблицы people (люди: режиссеры, сценаристы, актеры, продюсеры)
+CREATE TABLE people (
+                        uuid UUID PRIMARY KEY, -- Идентификатор человека
+                        name TEXT NOT NULL, -- Имя
+                        bio TEXT NOT NULL DEFAULT '', -- Краткая биография
+                        created_at TIMESTAMP NOT NULL DEFAULT now() -- Время создания записи
+);
+
+CREATE INDEX idx_people_name ON people(name);
+
+-- Участие людей в фильмах
+CREATE TABLE movie_credits (
+                               movie_id UUID NOT NULL REFERENCES movies(uuid) ON DELETE CASCADE, -- Фильм
+                               person_id UUID NOT NULL REFERENCES people(uuid), -- Человек; удалить человека с титрами нельзя
+                               role TEXT NOT NULL CHECK (role IN ('director', 'writer', 'actor', 'producer')), -- Роль в фильме
+                               character_name TEXT NOT NULL DEFAULT '', -- Имя персонажа (для актеров)
+                               billing_order INT NOT NULL DEFAULT 0 CHECK (billing_order >= 0), -- Порядок в титрах
+                               PRIMARY KEY (movie_id, person_id, role, character_name)
+);
+
+CREATE INDEX idx_movie_credits_person_id ON movie_credits(person_id);
+
+-- Переносим авторов в режиссеры: одно имя - один человек.
+-- Колонка author остается для совместимости API, фильтра и полнотекстового поиска
+INSERT INTO people (uuid, name)
+SELECT gen_random_uuid(), name FROM (SELECT DISTINCT btrim(author) AS name FROM movies WHERE btrim(author) <> '') authors;
+
+INSERT INTO movie_credits (movie_id, person_id, role)
+SELECT m.uuid, p.uuid, 'director' FROM movies m JOIN people p ON p.name = btrim(m.author);
@@ -0,0 +1,8 @@
+-- Удаление сериалов, сезонов и эпизодов
+DROP VIEW IF EXISTS titles;
+
+DROP TABLE IF EXISTS episodes;
+
+DROP TABLE IF EXISTS seasons;
+
+DROP TABLE IF EXISTS series;
@@ -0,0 +1,51 @@
+-- Создание таблицы series (сериалы)
+CREATE TABLE series (
+                        uuid UUID PRIMARY KEY, -- Идентификатор сериала
+                        owner_id UUID NOT NULL REFERENCES owners(uuid) ON DELETE CASCADE, -- Владелец
+                        title TEXT NOT NULL, -- Название
+                        description TEXT NOT NULL DEFAULT '', -- Описание
+                        created_at TIMESTAMP NOT NULL DEFAULT now() -- Время создания записи
+);
+
+CREATE INDEX idx_series_owner_id ON series(owner_id);
+
+CREATE INDEX idx_series_created_at_uuid ON series(created_at, uuid);
+
+-- Создание таблицы seasons (сезоны)
+CREATE TABLE seasons (
+                         uuid UUID PRIMARY KEY, -- Идентификатор сезона
+                         series_id UUID NOT NULL REFERENCES series(uuid) ON DELETE CASCADE, -- Сериал
+                         number INT NOT NULL CHECK (number > 0), -- Номер сезона
+                         title TEXT NOT NULL DEFAULT '', -- Название сезона
+                         air_date DATE, -- Дата выхода
+                         created_at TIMESTAMP NOT NULL DEFAULT now(), -- Время создания записи
+                         UNIQUE (series_id, number)
+);
+
+-- Создание таблицы episodes (эпизоды)
+CREATE TABLE episodes (
+                          uuid UUID PRIMARY KEY, -- Идентификатор эпизода
+                          season_id UUID NOT NULL REFERENCES seasons(uuid) ON DELETE CASCADE, -- Сезон
+                          number INT NOT NULL CHECK (number > 0), -- Номер эпизода в сезоне
+                          title TEXT NOT NULL, -- Название эпизода
+                          description TEXT NOT NULL DEFAULT '', -- Описание
+                          air_date DATE, -- Дата выхода
+                          runtime_minutes INT CHECK (runtime_minutes > 0), -- Продолжительность в минутах
+                          created_at TIMESTAMP NOT NULL DEFAULT now(), -- Время создания записи
+                          UNIQUE (season_id, number)
+);
+
+-- Единый список воспроизводимых единиц каталога: фильмы и эпизоды.
+-- Удаленные фильмы и содержимое удаленных владельцев сюда не попадают
+CREATE VIEW titles AS
+SELECT 'movie' AS kind, m.uuid, m.owner_id, m.title AS label
+FROM movies m
+WHERE m.deleted_at IS NULL
+UNION ALL
+SELECT 'episode' AS kind, e.uuid, sr.owner_id,
+       format('%s S%sE%s %s', sr.title, lpad(sn.number::text, 2, '0'), lpad(e.number::text, 2, '0'), e.title) AS label
+FROM episodes e
+         JOIN seasons sn ON sn.uuid = e.season_id
+         JOIN series sr ON sr.uuid = sn.series_id
+         JOIN owners o ON o.uuid = sr.owner_id
+WHERE o.deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- Удаление медиафайлов; сами файлы на диске не затрагиваются
+DROP TABLE IF EXISTS media_assets;
@@ -0,0 +1,20 @@
+-- Создание таблицы media_assets (медиафайлы фильмов и эпизодов)
+CREATE TABLE media_assets (
+                              uuid UUID PRIMARY KEY, -- Идентификатор файла, он же идентификатор tus-загрузки
+                              movie_id UUID REFERENCES movies(uuid) ON DELETE CASCADE, -- Фильм
+                              episode_id UUID REFERENCES episodes(uuid) ON DELETE CASCADE, -- Эпизод
+                              filename TEXT NOT NULL DEFAULT '', -- Исходное имя файла
+                              mime_type TEXT NOT NULL DEFAULT '', -- MIME-тип, известен после завершения загрузки
+                              size BIGINT NOT NULL CHECK (size > 0), -- Полный размер в байтах (Upload-Length)
+                              upload_offset BIGINT NOT NULL DEFAULT 0 CHECK (upload_offset >= 0 AND upload_offset <= size), -- Сколько байт уже принято
+                              checksum TEXT NOT NULL, -- Ожидаемый SHA-256 всего файла (hex)
+                              status TEXT NOT NULL DEFAULT 'uploading' CHECK (status IN ('uploading', 'ready', 'failed')), -- Состояние загрузки
+                              storage_key TEXT NOT NULL DEFAULT '', -- Ключ готового файла в хранилище
+                              created_at TIMESTAMP NOT NULL DEFAULT now(), -- Время начала загрузки
+                              completed_at TIMESTAMP, -- Время завершения загрузки
+                              CHECK (num_nonnulls(movie_id, episode_id) = 1)
+);
+
+CREATE INDEX idx_media_assets_movie_id ON media_assets(movie_id) WHERE movie_id IS NOT NULL;
+
+CREATE INDEX idx_media_assets_episode_id ON media_assets(episode_id) WHERE episode_id IS NOT NULL;
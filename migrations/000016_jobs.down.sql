@@ -0,0 +1,2 @@
+-- Удаление очереди фоновых задач вместе с историей их выполнения
+DROP TABLE IF EXISTS jobs;
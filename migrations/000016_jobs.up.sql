@@ -0,0 +1,24 @@
+-- Создание таблицы jobs (очередь фоновых задач, например упаковки медиафайлов)
+CREATE TABLE jobs (
+                      uuid UUID PRIMARY KEY, -- Идентификатор задачи
+                      kind TEXT NOT NULL, -- Тип задачи, по нему выбирается обработчик
+                      asset_id UUID NOT NULL REFERENCES media_assets(uuid) ON DELETE CASCADE, -- Медиафайл, над которым работает задача
+                      status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'dead')), -- dead - попытки исчерпаны
+                      progress SMALLINT NOT NULL DEFAULT 0 CHECK (progress BETWEEN 0 AND 100), -- Готовность в процентах
+                      attempts INT NOT NULL DEFAULT 0 CHECK (attempts >= 0), -- Сколько раз задача уже запускалась
+                      max_attempts INT NOT NULL CHECK (max_attempts > 0), -- После стольких неудач задача уходит в dead
+                      last_error TEXT NOT NULL DEFAULT '', -- Ошибка последней неудачной попытки
+                      run_at TIMESTAMP NOT NULL DEFAULT now(), -- Раньше этого времени задача не запускается (задержка перед повтором)
+                      locked_by TEXT, -- Обработчик, который выполняет задачу
+                      locked_until TIMESTAMP, -- Аренда: если обработчик не продлил ее, задачу подхватит другой
+                      created_at TIMESTAMP NOT NULL DEFAULT now(), -- Время постановки в очередь
+                      updated_at TIMESTAMP NOT NULL DEFAULT now(), -- Время последнего изменения
+                      finished_at TIMESTAMP -- Время успешного завершения или ухода в dead
+);
+
+-- Выборка следующей задачи: готовые к запуску и зависшие с истекшей арендой
+CREATE INDEX idx_jobs_queued ON jobs(run_at) WHERE status = 'queued';
+
+CREATE INDEX idx_jobs_running ON jobs(locked_until) WHERE status = 'running';
+
+CREATE INDEX idx_jobs_asset_id ON jobs(asset_id);
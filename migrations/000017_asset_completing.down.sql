@@ -0,0 +1,6 @@
+-- Незавершенные загрузки без фоновой задачи уже не завершатся
+UPDATE media_assets SET status = 'failed', completed_at = now() WHERE status = 'completing';
+
+ALTER TABLE media_assets DROP CONSTRAINT media_assets_status_check;
+ALTER TABLE media_assets ADD CONSTRAINT media_assets_status_check
+    CHECK (status IN ('uploading', 'ready', 'failed'));
@@ -0,0 +1,4 @@
+-- Состояние completing: файл принят целиком, проверка контрольной суммы и перенос в хранилище идут в фоновой задаче
+ALTER TABLE media_assets DROP CONSTRAINT media_assets_status_check;
+ALTER TABLE media_assets ADD CONSTRAINT media_assets_status_check
+    CHECK (status IN ('uploading', 'completing', 'ready', 'failed'));